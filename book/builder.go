@@ -0,0 +1,244 @@
+// --- book/builder.go ---
+
+// Package book builds Polyglot opening books from PGN game collections.
+package book
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/pgn"
+	"github.com/mesb/mchess/polyglot"
+	"github.com/mesb/mchess/socrates"
+)
+
+// Options controls which games and moves make it into the book.
+type Options struct {
+	MaxPly   int     // plies replayed per game (0 = whole game)
+	MinGames int     // games a move needs before it is kept
+	MinScore float64 // score (0..1, for the side playing the move) a move needs
+}
+
+// Stats counts game results after a move, from the mover's point of view.
+type Stats struct {
+	Wins, Draws, Losses int
+}
+
+// Games returns the number of games the move was played in.
+func (s Stats) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Score returns the mover's average result, 1 for a win and ½ for a draw.
+func (s Stats) Score() float64 {
+	if s.Games() == 0 {
+		return 0
+	}
+	return (float64(s.Wins) + float64(s.Draws)/2) / float64(s.Games())
+}
+
+// Weight turns the statistics into a Polyglot weight: two points for a
+// win, one for a draw.
+func (s Stats) Weight() int {
+	return 2*s.Wins + s.Draws
+}
+
+// position gathers the moves played from one position.
+type position struct {
+	fen   string // first occurrence, for the report
+	moves map[uint16]*Stats
+	order []uint16 // moves in the order first seen
+	names map[uint16]string
+}
+
+// Builder aggregates games into book statistics.
+type Builder struct {
+	opts      Options
+	positions map[uint64]*position
+	engine    *socrates.RuleEngine
+
+	// Games counts games that contributed; Skipped those without a result
+	// or with moves that could not be replayed.
+	Games, Skipped int
+}
+
+// NewBuilder returns an empty builder.
+func NewBuilder(opts Options) *Builder {
+	return &Builder{
+		opts:      opts,
+		positions: map[uint64]*position{},
+		engine:    socrates.New(board.InitStandard()),
+	}
+}
+
+// ReadPGN streams a PGN database game by game into the builder. Games
+// with malformed movetext or illegal moves are skipped.
+func (b *Builder) ReadPGN(r io.Reader) error {
+	rd := pgn.NewReader(r)
+	for {
+		g, err := rd.Read()
+		var syntax *pgn.SyntaxError
+		switch {
+		case err == io.EOF:
+			return nil
+		case errors.As(err, &syntax):
+			b.Skipped++
+		case err != nil:
+			return err
+		default:
+			b.addGame(g)
+		}
+	}
+}
+
+// addGame replays one game and credits its result to every book move.
+func (b *Builder) addGame(g *pgn.Game) {
+	var white Stats
+	switch g.Result {
+	case "1-0":
+		white.Wins = 1
+	case "0-1":
+		white.Losses = 1
+	case "1/2-1/2":
+		white.Draws = 1
+	default:
+		b.Skipped++
+		return
+	}
+
+	e := b.engine
+	bd, st, err := board.FromFEN(g.FEN())
+	if err != nil {
+		b.Skipped++
+		return
+	}
+	e.SetPosition(bd, st)
+
+	type bookMove struct {
+		key  uint64
+		fen  string
+		move uint16
+		name string
+		turn int
+	}
+	var played []bookMove
+	for _, gm := range g.Moves {
+		if b.opts.MaxPly > 0 && len(played) >= b.opts.MaxPly {
+			break
+		}
+		m := polyglot.Move{From: gm.From, To: gm.To, Promo: gm.Promo}
+		played = append(played, bookMove{
+			key:  polyglot.Key(e.Board, e.State),
+			fen:  e.Board.ToFEN(e.State),
+			move: polyglot.EncodeMove(e.Board, m),
+			name: m.String(),
+			turn: e.Turn,
+		})
+		e.MakeMove(m.From, m.To, m.Promo)
+	}
+
+	b.Games++
+	for _, p := range played {
+		pos := b.positions[p.key]
+		if pos == nil {
+			pos = &position{fen: p.fen, moves: map[uint16]*Stats{}, names: map[uint16]string{}}
+			b.positions[p.key] = pos
+		}
+		st := pos.moves[p.move]
+		if st == nil {
+			st = &Stats{}
+			pos.moves[p.move] = st
+			pos.order = append(pos.order, p.move)
+			pos.names[p.move] = p.name
+		}
+		if p.turn == 0 {
+			st.Wins += white.Wins
+			st.Losses += white.Losses
+		} else {
+			st.Wins += white.Losses
+			st.Losses += white.Wins
+		}
+		st.Draws += white.Draws
+	}
+}
+
+// keep applies the move filters.
+func (b *Builder) keep(s *Stats) bool {
+	return s.Games() >= b.opts.MinGames && s.Score() >= b.opts.MinScore
+}
+
+// Book returns the moves that pass the filters as a Polyglot book. Weights
+// are scaled down per position when they would overflow 16 bits.
+func (b *Builder) Book() *polyglot.Book {
+	var entries []polyglot.Entry
+	for key, pos := range b.positions {
+		top := 0
+		for _, s := range pos.moves {
+			if b.keep(s) {
+				top = max(top, s.Weight())
+			}
+		}
+		for _, mv := range pos.order {
+			s := pos.moves[mv]
+			if !b.keep(s) {
+				continue
+			}
+			w := s.Weight()
+			if top > 0xffff {
+				w = w * 0xffff / top
+			}
+			entries = append(entries, polyglot.Entry{Key: key, Move: mv, Weight: uint16(w)})
+		}
+	}
+	return polyglot.New(entries)
+}
+
+// WriteReport prints every kept position, most frequent first, with the
+// statistics of its moves.
+func (b *Builder) WriteReport(w io.Writer) error {
+	type row struct {
+		pos   *position
+		games int
+	}
+	var rows []row
+	for _, pos := range b.positions {
+		n := 0
+		for _, s := range pos.moves {
+			if b.keep(s) {
+				n += s.Games()
+			}
+		}
+		if n > 0 {
+			rows = append(rows, row{pos, n})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].games != rows[j].games {
+			return rows[i].games > rows[j].games
+		}
+		return rows[i].pos.fen < rows[j].pos.fen
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "games %d, skipped %d, positions %d\n", b.Games, b.Skipped, len(rows))
+	for _, r := range rows {
+		fmt.Fprintf(bw, "\n%s (%d games)\n", r.pos.fen, r.games)
+		moves := append([]uint16(nil), r.pos.order...)
+		sort.SliceStable(moves, func(i, j int) bool {
+			return r.pos.moves[moves[i]].Games() > r.pos.moves[moves[j]].Games()
+		})
+		for _, mv := range moves {
+			s := r.pos.moves[mv]
+			if !b.keep(s) {
+				continue
+			}
+			fmt.Fprintf(bw, "  %-6s %5d games  +%d =%d -%d  %5.1f%%\n",
+				r.pos.names[mv], s.Games(), s.Wins, s.Draws, s.Losses, 100*s.Score())
+		}
+	}
+	return bw.Flush()
+}
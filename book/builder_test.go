@@ -0,0 +1,134 @@
+package book
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/polyglot"
+)
+
+const games = `[Event "A"]
+[Result "1-0"]
+
+1. e2e4 e7e5 2. g1f3 {main line} b8c6 1-0
+
+[Event "B"]
+[Result "0-1"]
+
+1. e2e4 c7c5 (1... e7e5 2. f1c4) 2. g1f3 d7d6 0-1
+
+[Event "C"]
+[Result "1/2-1/2"]
+
+1. d2d4 d7d5 ; the Queen's Gambit
+2. c2c4 1/2-1/2
+
+[Event "D"]
+[Result "*"]
+
+1. e2e4 e7e5 *
+
+[Event "E"]
+[Result "1-0"]
+
+1. e2e5 1-0
+`
+
+func sq(s string) address.Addr {
+	return address.MakeAddr(address.Rank(s[1]-'1'), address.File(s[0]-'a'))
+}
+
+func build(t *testing.T, opts Options) *Builder {
+	t.Helper()
+	b := NewBuilder(opts)
+	if err := b.ReadPGN(strings.NewReader(games)); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBuilderAggregatesResults(t *testing.T) {
+	b := build(t, Options{MaxPly: 2})
+	if b.Games != 3 || b.Skipped != 2 {
+		t.Fatalf("games %d skipped %d, want 3 and 2", b.Games, b.Skipped)
+	}
+
+	bd, s := board.InitStandard(), board.NewGameState()
+	moves, weights := b.Book().Probe(bd, s)
+	got := map[string]uint16{}
+	for i, m := range moves {
+		got[m.String()] = weights[i]
+	}
+	// e4: one win, one loss; d4: one draw.
+	if len(got) != 2 || got["e2e4"] != 2 || got["d2d4"] != 1 {
+		t.Fatalf("start position moves %v", got)
+	}
+
+	// Only two plies were replayed, so g1f3 is not in the book.
+	bd, s, _ = board.FromFEN("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2")
+	if moves, _ := b.Book().Probe(bd, s); len(moves) != 0 {
+		t.Fatalf("moves beyond the ply limit: %v", moves)
+	}
+}
+
+func TestBuilderFilters(t *testing.T) {
+	b := build(t, Options{MinGames: 2})
+	bd, s := board.InitStandard(), board.NewGameState()
+	moves, _ := b.Book().Probe(bd, s)
+	if len(moves) != 1 || moves[0] != (polyglot.Move{From: sq("e2"), To: sq("e4")}) {
+		t.Fatalf("min-games filter kept %v", moves)
+	}
+
+	// After 1. e4 Black scored a win with c5 and a loss with e5.
+	b = build(t, Options{MinScore: 0.5})
+	bd, s, _ = board.FromFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	moves, _ = b.Book().Probe(bd, s)
+	if len(moves) != 1 || moves[0].String() != "c7c5" {
+		t.Fatalf("min-score filter kept %v", moves)
+	}
+}
+
+func TestBuilderReadsSAN(t *testing.T) {
+	b := NewBuilder(Options{})
+	sanGames := `[Event "SAN"]
+[Result "0-1"]
+
+1. Nf3 d5 2. g3 {a King's Indian Attack} Nf6 3. Bg2 c6 4. O-O Bg4 0-1
+
+[Event "From a position"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"]
+
+1. e4 Kd7 1-0
+`
+	if err := b.ReadPGN(strings.NewReader(sanGames)); err != nil {
+		t.Fatal(err)
+	}
+	if b.Games != 2 || b.Skipped != 0 {
+		t.Fatalf("games %d skipped %d, want 2 and 0", b.Games, b.Skipped)
+	}
+	bd, s, _ := board.FromFEN("rnbqkb1r/pp2pppp/2p2n2/3p4/8/5NP1/PPPPPPBP/RNBQK2R w KQkq - 0 4")
+	moves, _ := b.Book().Probe(bd, s)
+	if len(moves) != 1 || moves[0] != (polyglot.Move{From: sq("e1"), To: sq("g1")}) {
+		t.Fatalf("castling not in the book: %v", moves)
+	}
+	bd, s, _ = board.FromFEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	if moves, _ := b.Book().Probe(bd, s); len(moves) != 1 || moves[0].String() != "e2e4" {
+		t.Fatalf("game from a FEN: %v", moves)
+	}
+}
+
+func TestBuilderReport(t *testing.T) {
+	var out strings.Builder
+	if err := build(t, Options{MaxPly: 1}).WriteReport(&out); err != nil {
+		t.Fatal(err)
+	}
+	report := out.String()
+	for _, want := range []string{"games 3, skipped 2, positions 1", "e2e4       2 games  +1 =0 -1   50.0%", "d2d4"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// --- cmd/book/main.go ---
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/mesb/mchess/book"
+)
+
+// main builds a Polyglot opening book from PGN files given as arguments and
+// writes it, with a report of the statistics behind every move.
+func main() {
+	out := flag.String("out", "book.bin", "where to write the Polyglot book")
+	report := flag.String("report", "", "optional text report of positions and move statistics")
+	depth := flag.Int("depth", 20, "plies of every game to include (0 = whole game)")
+	minGames := flag.Int("min-games", 1, "games a move must appear in to be kept")
+	minScore := flag.Float64("min-score", 0, "score (0-1) a move must reach for the side playing it")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("usage: book [flags] games.pgn ...")
+	}
+	b := book.NewBuilder(book.Options{MaxPly: *depth, MinGames: *minGames, MinScore: *minScore})
+	for _, path := range flag.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = b.ReadPGN(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+	}
+
+	bk := b.Book()
+	if err := bk.Save(*out); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d games (%d skipped), %d entries written to %s\n", b.Games, b.Skipped, bk.Len(), *out)
+
+	if *report != "" {
+		f, err := os.Create(*report)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := b.WriteReport(f); err != nil {
+			log.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
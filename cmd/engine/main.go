@@ -2,8 +2,52 @@
 
 package main
 
-import "github.com/mesb/mchess/uci"
+import (
+	"bufio"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/mesb/mchess/search"
+	"github.com/mesb/mchess/socrates"
+	"github.com/mesb/mchess/uci"
+	"github.com/mesb/mchess/xboard"
+)
 
 func main() {
-	uci.Run()
+	params := flag.String("evalparams", "", "JSON evaluation parameters (e.g. from cmd/tune) replacing the compiled-in defaults")
+	bench := flag.Bool("bench", false, "search the bench positions, print the node count signature and speed, and exit")
+	benchDepth := flag.Int("bench-depth", search.BenchDepth, "depth of -bench")
+	flag.Parse()
+
+	if *params != "" {
+		if err := socrates.LoadEvalParams(*params); err != nil {
+			log.Fatalf("evalparams: %v", err)
+		}
+	}
+
+	if *bench {
+		search.Bench(os.Stdout, *benchDepth)
+		return
+	}
+
+	// The GUI's first command picks the protocol: XBoard opens with
+	// "xboard" (or straight away with "protover"), anything else is UCI.
+	in := bufio.NewReader(os.Stdin)
+	first, err := in.ReadString('\n')
+	if err != nil && first == "" {
+		return
+	}
+	stdin := io.MultiReader(strings.NewReader(first), in)
+	switch cmd, _, _ := strings.Cut(strings.TrimSpace(first), " "); cmd {
+	case "xboard", "protover":
+		err = xboard.New(stdin, os.Stdout).Run()
+	default:
+		err = uci.New(stdin, os.Stdout).Run()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,148 @@
+// --- cmd/epd/main.go ---
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/epd"
+	"github.com/mesb/mchess/search"
+	"github.com/mesb/mchess/socrates"
+)
+
+// main runs the engine on every position of EPD test suites (WAC, STS and
+// the like) and reports, position by position, whether it found the bm
+// move, avoided the am moves and saw the dm mate, then sums up.
+func main() {
+	depth := flag.Int("depth", 0, "search depth per position")
+	moveTime := flag.Duration("movetime", time.Second, "search time per position, unless -depth is given")
+	hash := flag.Int("hash", socrates.DefaultHashMB, "hash table size in MB, cleared for every position")
+	concurrency := flag.Int("concurrency", 1, "positions searched at once")
+	times := flag.Bool("times", false, "add nodes and time to the report lines (which then vary between runs)")
+	params := flag.String("evalparams", "", "JSON evaluation parameters replacing the compiled-in defaults")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("usage: epd [flags] suite.epd ...")
+	}
+	if *params != "" {
+		if err := socrates.LoadEvalParams(*params); err != nil {
+			log.Fatalf("evalparams: %v", err)
+		}
+	}
+	l := search.Limits{Depth: *depth}
+	if *depth == 0 {
+		l.MoveTime = *moveTime
+	}
+
+	type entry struct {
+		pos epd.Position
+		id  string
+	}
+	var suite []entry
+	for _, path := range flag.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ps, err := epd.Read(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		for i, p := range ps {
+			id := p.ID()
+			if id == "" {
+				id = fmt.Sprintf("%s#%d", strings.TrimSuffix(path, ".epd"), i+1)
+			}
+			suite = append(suite, entry{p, id})
+		}
+	}
+
+	// Workers take positions in turn; the report keeps the suite's order.
+	outcomes := make([]chan Outcome, len(suite))
+	for i := range outcomes {
+		outcomes[i] = make(chan Outcome, 1)
+	}
+	next := make(chan int)
+	go func() {
+		for i := range suite {
+			next <- i
+		}
+		close(next)
+	}()
+	for range max(*concurrency, 1) {
+		go func() {
+			eng := socrates.New(board.InitStandard())
+			eng.SetHashSize(*hash)
+			bo := eng.BookOptions()
+			bo.Disabled = true
+			eng.SetBookOptions(bo)
+			for i := range next {
+				outcomes[i] <- solve(eng, suite[i].pos, suite[i].id, l)
+			}
+		}()
+	}
+
+	var sum summary
+	start := time.Now()
+	for _, ch := range outcomes {
+		o := <-ch
+		line := o.String()
+		if *times && o.Status != Invalid {
+			line += fmt.Sprintf("  nodes %d time %dms", o.Nodes, o.Time.Milliseconds())
+		}
+		fmt.Println(line)
+		sum.add(o)
+	}
+	sum.print(time.Since(start))
+}
+
+// summary totals a run.
+type summary struct {
+	positions, solved, failed, unscored, invalid int
+	points, maxPoints                            int
+	nodes                                        int
+	failures                                     []string
+}
+
+func (s *summary) add(o Outcome) {
+	s.positions++
+	s.nodes += o.Nodes
+	s.points += o.Points
+	s.maxPoints += o.MaxPoints
+	switch o.Status {
+	case Solved:
+		s.solved++
+	case Failed:
+		s.failed++
+		s.failures = append(s.failures, o.ID)
+	case Unscored:
+		s.unscored++
+	case Invalid:
+		s.invalid++
+	}
+}
+
+func (s *summary) print(elapsed time.Duration) {
+	scored := s.solved + s.failed
+	fmt.Printf("\nsolved %d of %d (%.1f%%)", s.solved, scored, 100*float64(s.solved)/float64(max(scored, 1)))
+	if s.unscored > 0 || s.invalid > 0 {
+		fmt.Printf(", %d unscored, %d invalid", s.unscored, s.invalid)
+	}
+	fmt.Println()
+	if s.maxPoints > 0 {
+		fmt.Printf("points %d of %d (%.1f%%)\n", s.points, s.maxPoints, 100*float64(s.points)/float64(s.maxPoints))
+	}
+	if len(s.failures) > 0 {
+		fmt.Printf("failed: %s\n", strings.Join(s.failures, " "))
+	}
+	fmt.Printf("%d positions, %d nodes in %v (%d nps)\n", s.positions, s.nodes, elapsed.Round(time.Millisecond),
+		int64(s.nodes)*1000/max(elapsed.Milliseconds(), 1))
+}
@@ -0,0 +1,243 @@
+// --- cmd/epd/suite.go ---
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/epd"
+	"github.com/mesb/mchess/notation"
+	"github.com/mesb/mchess/search"
+	"github.com/mesb/mchess/socrates"
+)
+
+// Status is how the engine fared on one position.
+type Status string
+
+const (
+	Solved   Status = "solved"
+	Failed   Status = "failed"
+	Unscored Status = "unscored" // nothing to check the move against
+	Invalid  Status = "invalid"  // the record or its operands are broken
+)
+
+// Outcome is the engine's answer to one position.
+type Outcome struct {
+	ID     string
+	Status Status
+	Want   string // the operations the move was checked against
+	Move   socrates.SimpleMove
+	Score  int
+	Depth  int
+	Nodes  int
+	Time   time.Duration
+	// Points and MaxPoints score STS-style records, which rate several
+	// moves through c8 (points) and c9 (moves).
+	Points, MaxPoints int
+	Err               error
+}
+
+// solve searches p on eng within l and checks the move against the bm,
+// am and dm operations and the STS points.
+func solve(eng *socrates.RuleEngine, p epd.Position, id string, l search.Limits) Outcome {
+	o := Outcome{ID: id}
+	b, s, err := board.FromFEN(p.FEN)
+	if err != nil {
+		o.Status, o.Err = Invalid, err
+		return o
+	}
+	eng.SetPosition(b, s)
+	eng.ClearHash()
+
+	bm, errBM := moves(eng, p, "bm")
+	am, errAM := moves(eng, p, "am")
+	if err := firstError(errBM, errAM); err != nil {
+		o.Status, o.Err = Invalid, err
+		return o
+	}
+	dm := -1
+	if v, ok := p.Op("dm"); ok {
+		if len(v) != 1 {
+			o.Status, o.Err = Invalid, fmt.Errorf("dm needs one number")
+			return o
+		}
+		if dm, err = strconv.Atoi(v[0]); err != nil || dm <= 0 {
+			o.Status, o.Err = Invalid, fmt.Errorf("dm %q is not a move count", v[0])
+			return o
+		}
+	}
+	sts, err := stsPoints(eng, p)
+	if err != nil {
+		o.Status, o.Err = Invalid, err
+		return o
+	}
+	o.Want = want(p)
+
+	if len(eng.GenerateLegalMoves()) == 0 {
+		o.Status, o.Err = Invalid, fmt.Errorf("no legal moves")
+		return o
+	}
+	start := time.Now()
+	res, info := think(eng, l)
+	o.Time = time.Since(start)
+	o.Move, _ = res.Move()
+	o.Score, o.Depth, o.Nodes = info.Score, info.Depth, res.Nodes
+
+	o.Status = Unscored
+	checks := []bool{}
+	if len(bm) > 0 {
+		checks = append(checks, contains(bm, o.Move))
+	}
+	if len(am) > 0 {
+		checks = append(checks, !contains(am, o.Move))
+	}
+	if dm > 0 {
+		checks = append(checks, o.Score >= socrates.MateScore-(2*dm-1))
+	}
+	if sts != nil {
+		o.MaxPoints = 0
+		for m, pts := range sts {
+			o.MaxPoints = max(o.MaxPoints, pts)
+			if m == o.Move {
+				o.Points = pts
+			}
+		}
+		if len(checks) == 0 {
+			checks = append(checks, o.Points == o.MaxPoints)
+		}
+	}
+	if len(checks) > 0 {
+		o.Status = Solved
+		for _, ok := range checks {
+			if !ok {
+				o.Status = Failed
+			}
+		}
+	}
+	return o
+}
+
+// think runs one search to completion and returns it with its last
+// complete iteration.
+func think(eng *socrates.RuleEngine, l search.Limits) (search.Result, search.Info) {
+	r := &collector{done: make(chan search.Result, 1)}
+	var s search.Searcher
+	s.Start(eng, l, r)
+	res := <-r.done
+	return res, r.last
+}
+
+// collector keeps the last exact principal variation of a search.
+type collector struct {
+	last search.Info
+	done chan search.Result
+}
+
+func (c *collector) Iteration(i search.Info) {
+	if i.MultiPV == 1 && i.Bound == socrates.BoundExact {
+		c.last = i
+	}
+}
+func (c *collector) CurrMove(int, socrates.SimpleMove, int) {}
+func (c *collector) BookMove(socrates.SimpleMove)           {}
+func (c *collector) BestMove(r search.Result)               { c.done <- r }
+
+// moves reads the SAN operands of op.
+func moves(eng *socrates.RuleEngine, p epd.Position, op string) ([]socrates.SimpleMove, error) {
+	operands, _ := p.Op(op)
+	var out []socrates.SimpleMove
+	for _, san := range operands {
+		m, err := notation.ParseSAN(eng, san)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// stsPoints reads the Strategic Test Suite scoring: c9 lists moves in
+// coordinate notation and c8 the points each earns.
+func stsPoints(eng *socrates.RuleEngine, p epd.Position) (map[socrates.SimpleMove]int, error) {
+	c8, ok8 := p.Op("c8")
+	c9, ok9 := p.Op("c9")
+	if !ok8 || !ok9 {
+		return nil, nil
+	}
+	// The operands are single strings of space-separated fields.
+	pts, mvs := strings.Fields(strings.Join(c8, " ")), strings.Fields(strings.Join(c9, " "))
+	if len(pts) != len(mvs) {
+		return nil, fmt.Errorf("c8 has %d points for %d c9 moves", len(pts), len(mvs))
+	}
+	out := map[socrates.SimpleMove]int{}
+	for i, mv := range mvs {
+		m, err := notation.ParseSAN(eng, mv)
+		if err != nil {
+			return nil, fmt.Errorf("c9: %v", err)
+		}
+		n, err := strconv.Atoi(pts[i])
+		if err != nil {
+			return nil, fmt.Errorf("c8: %q is not a number", pts[i])
+		}
+		out[m] = n
+	}
+	return out, nil
+}
+
+// want names what the move is checked against, e.g. "bm Qg6 am Qxb2".
+func want(p epd.Position) string {
+	var parts []string
+	for _, op := range p.Ops {
+		switch op.Code {
+		case "bm", "am", "dm", "ce":
+			parts = append(parts, op.Code+" "+strings.Join(op.Operands, " "))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func contains(ms []socrates.SimpleMove, m socrates.SimpleMove) bool {
+	for _, x := range ms {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scoreString shows a score as "cp 35" or "mate -3".
+func scoreString(score int) string {
+	if moves, ok := socrates.MateIn(score); ok {
+		return fmt.Sprintf("mate %d", moves)
+	}
+	return fmt.Sprintf("cp %d", score)
+}
+
+// String is the report line: stable across runs at a fixed depth, so two
+// versions' reports can be diffed.
+func (o Outcome) String() string {
+	if o.Status == Invalid {
+		return fmt.Sprintf("%-12s %-8s %v", o.ID, o.Status, o.Err)
+	}
+	s := fmt.Sprintf("%-12s %-8s %-5s %-9s depth %-3d", o.ID, o.Status, o.Move.String(), scoreString(o.Score), o.Depth)
+	if o.MaxPoints > 0 {
+		s += fmt.Sprintf(" points %d/%d", o.Points, o.MaxPoints)
+	}
+	if o.Want != "" {
+		s += "  " + o.Want
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/epd"
+	"github.com/mesb/mchess/search"
+	"github.com/mesb/mchess/socrates"
+)
+
+func TestSolve(t *testing.T) {
+	eng := socrates.New(board.InitStandard())
+	eng.SetHashSize(1)
+	bo := eng.BookOptions()
+	bo.Disabled = true
+	eng.SetBookOptions(bo)
+
+	tests := []struct {
+		record string
+		status Status
+		points int
+	}{
+		{`6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Ra8#; id "mate";`, Solved, 0},
+		{`6k1/5ppp/8/8/8/8/8/R5K1 w - - dm 1;`, Solved, 0},
+		{`6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Kf1;`, Failed, 0},
+		{`6k1/5ppp/8/8/8/8/8/R5K1 w - - am Ra8;`, Failed, 0},
+		{`6k1/5ppp/8/8/8/8/8/R5K1 w - - c8 "10 4"; c9 "a1a8 g1f2";`, Solved, 10},
+		{`6k1/5ppp/8/8/8/8/8/R5K1 w - - ce 30000;`, Unscored, 0},
+		{`6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Qa8;`, Invalid, 0},
+	}
+	for _, tt := range tests {
+		p, err := epd.Parse(tt.record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o := solve(eng, p, "test", search.Limits{Depth: 2})
+		if o.Status != tt.status || o.Points != tt.points {
+			t.Errorf("%s: %s with %d points (%v), want %s with %d", tt.record, o.Status, o.Points, o.Err, tt.status, tt.points)
+		}
+	}
+}
+
+func TestReportLineIsStable(t *testing.T) {
+	o := Outcome{ID: "WAC.001", Status: Solved, Want: "bm Qg6", Score: socrates.MateScore - 3, Depth: 9}
+	line := o.String()
+	for _, want := range []string{"WAC.001", "solved", "mate 2", "depth 9", "bm Qg6"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("%q lacks %q", line, want)
+		}
+	}
+}
@@ -3,11 +3,20 @@
 package main
 
 import (
+	"flag"
+	"log"
+
 	"github.com/mesb/mchess/shell"
 )
 
 // main boots the mchess terminal engine using the GameSession + TerminalRenderer.
 func main() {
+	book := flag.String("book", "", "Polyglot opening book (.bin) replacing the built-in book")
+	flag.Parse()
+
 	session := shell.NewSession(shell.TerminalRenderer{})
+	if err := session.Engine.LoadBook(*book); err != nil {
+		log.Fatalf("book: %v", err)
+	}
 	shell.RunInteractive(session)
 }
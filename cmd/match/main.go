@@ -0,0 +1,265 @@
+// --- cmd/match/main.go ---
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mesb/mchess/match"
+	"github.com/mesb/mchess/socrates"
+	"github.com/mesb/mchess/uci"
+)
+
+// engineFlags collects the repeated -engine flag.
+type engineFlags []string
+
+func (e *engineFlags) String() string     { return strings.Join(*e, "; ") }
+func (e *engineFlags) Set(v string) error { *e = append(*e, v); return nil }
+
+// main plays a match between engines and reports the score, the Elo
+// difference and, with -sprt, when the result is significant. cmd=builtin
+// stands for this repository's engine, run as a child process of the
+// match so that option sets which change process-wide search parameters
+// stay apart.
+func main() {
+	var engines engineFlags
+	flag.Var(&engines, "engine", `an engine: "name=dev cmd=builtin option.Hash=64" (repeat for every engine)`)
+	each := flag.String("each", "", "fields added to every engine, e.g. \"option.Hash=16\"")
+	tcFlag := flag.String("tc", "10+0.1", "time control moves/base+inc in seconds, or inf")
+	moveTime := flag.Duration("movetime", 0, "fixed time per move instead of a clock")
+	depth := flag.Int("depth", 0, "fixed search depth per move")
+	nodes := flag.Int("nodes", 0, "fixed node count per move")
+	margin := flag.Duration("timemargin", 50*time.Millisecond, "time an engine may overrun its clock")
+	games := flag.Int("games", 100, "games per pairing, played as colour-swapped pairs")
+	concurrency := flag.Int("concurrency", 1, "games played at once")
+	openings := flag.String("openings", "", "opening suite (.epd or .pgn)")
+	plies := flag.Int("plies", 0, "plies of each PGN opening to play (0 = all)")
+	order := flag.String("order", "sequential", "opening order: sequential or random")
+	seed := flag.Int64("seed", 0, "random seed for -order random (0 = from the clock)")
+	pgnOut := flag.String("pgnout", "", "file the games are appended to")
+	event := flag.String("event", "MCHESS match", "PGN Event tag")
+	resign := flag.String("resign", "", `resign adjudication "moves=3 score=600"`)
+	draw := flag.String("draw", "", `draw adjudication "movenumber=40 moves=8 score=10"`)
+	maxMoves := flag.Int("maxmoves", 0, "draw games reaching this many moves (0 = never)")
+	sprtFlag := flag.String("sprt", "", `stop once "elo0=0 elo1=5 alpha=0.05 beta=0.05" is decided`)
+	ratingInterval := flag.Int("ratinginterval", 10, "games between Elo reports")
+	uciMode := flag.Bool("uci", false, "run the built-in engine over UCI (used by cmd=builtin)")
+	params := flag.String("evalparams", "", "with -uci: JSON evaluation parameters for the built-in engine")
+	flag.Parse()
+
+	if *uciMode {
+		if *params != "" {
+			if err := socrates.LoadEvalParams(*params); err != nil {
+				log.Fatalf("evalparams: %v", err)
+			}
+		}
+		uci.Run()
+		return
+	}
+
+	m := &match.Match{
+		Rounds:      (*games + 1) / 2,
+		Concurrency: *concurrency,
+		TimeMargin:  *margin,
+	}
+	var err error
+	if m.TimeControl, err = match.ParseTimeControl(*tcFlag); err != nil {
+		log.Fatal(err)
+	}
+	if *moveTime > 0 || *depth > 0 || *nodes > 0 {
+		m.TimeControl = match.TimeControl{MoveTime: *moveTime, Depth: *depth, Nodes: *nodes}
+	}
+	if len(engines) < 2 {
+		log.Fatal("usage: match -engine SPEC -engine SPEC [flags]")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, spec := range engines {
+		c, err := match.ParseEngineConfig(spec + " " + *each)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if c.Cmd == "builtin" {
+			c.Cmd, c.Args = self, append([]string{"-uci"}, c.Args...)
+		}
+		if c.Cmd == "" {
+			log.Fatalf("engine %q: missing cmd=", spec)
+		}
+		m.Engines = append(m.Engines, c)
+	}
+	if m.Adjudication, err = adjudication(*resign, *draw, *maxMoves); err != nil {
+		log.Fatal(err)
+	}
+	if *sprtFlag != "" {
+		s, err := parseSPRT(*sprtFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		m.SPRT = &s
+	}
+	if *openings != "" {
+		if m.Openings, err = match.ReadOpenings(*openings, *plies); err != nil {
+			log.Fatal(err)
+		}
+		if *order == "random" {
+			if *seed == 0 {
+				*seed = time.Now().UnixNano()
+			}
+			r := rand.New(rand.NewSource(*seed))
+			r.Shuffle(len(m.Openings), func(i, j int) { m.Openings[i], m.Openings[j] = m.Openings[j], m.Openings[i] })
+		}
+	}
+
+	var pgn *os.File
+	if *pgnOut != "" {
+		if pgn, err = os.OpenFile(*pgnOut, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+			log.Fatal(err)
+		}
+		defer pgn.Close()
+	}
+	var mu sync.Mutex
+	m.OnGame = func(g match.Game, st *match.Standings) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Printf("Finished game %d (%s vs %s): %s {%s}\n", g.Round, g.White, g.Black, g.Result, g.Reason)
+		if pgn != nil {
+			if err := match.WritePGN(pgn, g, *event, m.TimeControl); err != nil {
+				log.Printf("pgnout: %v", err)
+			}
+		}
+		report(st, m.SPRT, st.HeadToHead.Games()%max(*ratingInterval, 1) == 0)
+	}
+
+	// Ctrl-C ends the match early with the standings so far.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	st, err := m.Run(ctx)
+	if st != nil {
+		fmt.Println()
+		report(st, m.SPRT, true)
+		if len(st.Names) > 2 {
+			table(st)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// report prints the head-to-head score, and with full its Elo and SPRT.
+func report(st *match.Standings, sprt *match.SPRT, full bool) {
+	s := st.HeadToHead
+	fmt.Printf("Score of %s vs %s: %s\n", st.Names[0], st.Names[1], s)
+	if !full {
+		return
+	}
+	elo, margin := s.Elo()
+	fmt.Printf("Elo difference: %s +/- %s, LOS: %.1f %%, DrawRatio: %.1f %%\n",
+		eloString(elo), eloString(margin), 100*s.LOS(), 100*float64(s.Draws)/float64(max(s.Games(), 1)))
+	if sprt != nil {
+		lower, upper := sprt.Bounds()
+		llr := sprt.LLR(s)
+		fmt.Printf("SPRT: llr %.3g (%.1f%%), lbound %.3g, ubound %.3g - %s\n",
+			llr, 100*llr/upper, lower, upper, st.Verdict)
+	}
+}
+
+// table lists every engine's score in a tournament of more than two,
+// best first.
+func table(st *match.Standings) {
+	rank := make([]int, len(st.Scores))
+	for i := range rank {
+		rank[i] = i
+	}
+	sort.SliceStable(rank, func(a, b int) bool { return st.Scores[rank[a]].Ratio() > st.Scores[rank[b]].Ratio() })
+	fmt.Printf("%-4s %-24s %7s %7s %7s\n", "Rank", "Name", "Elo", "+/-", "Games")
+	for n, i := range rank {
+		elo, margin := st.Scores[i].Elo()
+		fmt.Printf("%-4d %-24s %7s %7s %7d\n", n+1, st.Names[i], eloString(elo), eloString(margin), st.Scores[i].Games())
+	}
+}
+
+func eloString(elo float64) string {
+	if math.IsInf(elo, 0) || math.IsNaN(elo) {
+		return "inf"
+	}
+	return strconv.FormatFloat(elo+0, 'f', 1, 64) // +0 turns -0 into 0
+}
+
+// keyValues reads "a=1 b=2" into a map of numbers.
+func keyValues(s string, keys ...string) (map[string]float64, error) {
+	out := map[string]float64{}
+	for _, f := range strings.Fields(s) {
+		k, v, ok := strings.Cut(f, "=")
+		n, err := strconv.ParseFloat(v, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("%q: %q is not key=number", s, f)
+		}
+		known := false
+		for _, key := range keys {
+			known = known || key == k
+		}
+		if !known {
+			return nil, fmt.Errorf("%q: unknown key %q (want %s)", s, k, strings.Join(keys, ", "))
+		}
+		out[k] = n
+	}
+	return out, nil
+}
+
+func adjudication(resign, draw string, maxMoves int) (match.Adjudication, error) {
+	a := match.Adjudication{MaxMoves: maxMoves}
+	r, err := keyValues(resign, "moves", "score")
+	if err != nil {
+		return a, fmt.Errorf("resign %v", err)
+	}
+	d, err := keyValues(draw, "movenumber", "moves", "score")
+	if err != nil {
+		return a, fmt.Errorf("draw %v", err)
+	}
+	if resign != "" {
+		a.ResignMoves, a.ResignScore = int(r["moves"]), int(r["score"])
+		if a.ResignMoves <= 0 || a.ResignScore <= 0 {
+			return a, fmt.Errorf("resign %q: moves and score must be positive", resign)
+		}
+	}
+	if draw != "" {
+		a.DrawMoveNumber, a.DrawMoves, a.DrawScore = int(d["movenumber"]), int(d["moves"]), int(d["score"])
+		if a.DrawMoves <= 0 {
+			return a, fmt.Errorf("draw %q: moves must be positive", draw)
+		}
+	}
+	return a, nil
+}
+
+func parseSPRT(s string) (match.SPRT, error) {
+	v, err := keyValues(s, "elo0", "elo1", "alpha", "beta")
+	if err != nil {
+		return match.SPRT{}, fmt.Errorf("sprt %v", err)
+	}
+	t := match.SPRT{Elo0: v["elo0"], Elo1: v["elo1"], Alpha: 0.05, Beta: 0.05}
+	if a, ok := v["alpha"]; ok {
+		t.Alpha = a
+	}
+	if b, ok := v["beta"]; ok {
+		t.Beta = b
+	}
+	if t.Elo1 <= t.Elo0 || t.Alpha <= 0 || t.Alpha >= 1 || t.Beta <= 0 || t.Beta >= 1 {
+		return t, fmt.Errorf("sprt %q: need elo0 < elo1 and alpha, beta in (0, 1)", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,110 @@
+// --- cmd/server/book.go ---
+
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mesb/mchess/polyglot"
+	"github.com/mesb/mchess/socrates"
+)
+
+// serverBook shares one opening book and its learning record between all
+// games, following the book moves the engine played in each game.
+type serverBook struct {
+	mu        sync.Mutex
+	book      *polyglot.Book
+	learn     *polyglot.Learning
+	learnPath string
+	lines     map[string]*bookLine // by game id
+}
+
+// bookLine is the book moves the engine played in one game.
+type bookLine struct {
+	steps []polyglot.Step
+	moved time.Time // when the game last had a move
+}
+
+// abandonAfter is how long a game can go without a move before its book
+// line is dropped: an abandoned game never finishes to be credited.
+const abandonAfter = 24 * time.Hour
+
+// openingBook is configured from BOOK_FILE and BOOK_LEARN_FILE at startup;
+// by default games use the engine's built-in book without learning.
+var openingBook = &serverBook{lines: map[string]*bookLine{}}
+
+// load opens the book and learning files; empty paths are skipped.
+func (b *serverBook) load(bookPath, learnPath string) error {
+	if bookPath != "" {
+		bk, err := polyglot.Open(bookPath)
+		if err != nil {
+			return err
+		}
+		b.book = bk
+	}
+	if learnPath != "" {
+		l, err := polyglot.OpenLearning(learnPath)
+		if err != nil {
+			return err
+		}
+		b.learn, b.learnPath = l, learnPath
+	}
+	return nil
+}
+
+// install puts the shared book on a game's engine.
+func (b *serverBook) install(eng *socrates.RuleEngine) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	eng.UseBook(b.book)
+	eng.UseLearning(b.learn)
+}
+
+// played notes an engine book move before it is made.
+func (b *serverBook) played(id string, eng *socrates.RuleEngine, res socrates.SearchResult) {
+	if !res.Book {
+		return
+	}
+	step := eng.BookStep(socrates.SimpleMove{From: res.From, To: res.To, Promo: res.Promo})
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.learn == nil {
+		return
+	}
+	line := b.lines[id]
+	if line == nil {
+		line = &bookLine{}
+		b.lines[id] = line
+	}
+	line.steps = append(line.steps, step)
+	line.moved = time.Now()
+}
+
+// finish follows a move in game id: a finished game is credited to its
+// book moves and the record saved. Lines of abandoned games are dropped.
+func (b *serverBook) finish(id string, eng *socrates.RuleEngine) {
+	score, over := eng.GameResult()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	for other, line := range b.lines {
+		if now.Sub(line.moved) > abandonAfter {
+			delete(b.lines, other)
+		}
+	}
+	line := b.lines[id]
+	if line == nil {
+		return
+	}
+	if !over {
+		line.moved = now
+		return
+	}
+	delete(b.lines, id)
+	b.learn.Record(line.steps, score)
+	if err := b.learn.Save(b.learnPath); err != nil {
+		log.Printf("book learning: %v", err)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -17,6 +19,7 @@ import (
 
 	"github.com/mesb/mchess/address"
 	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/eco"
 	"github.com/mesb/mchess/pgn"
 	"github.com/mesb/mchess/pieces"
 	ws "github.com/mesb/mchess/server"
@@ -26,6 +29,27 @@ import (
 
 // --- Interfaces & DTOs ---
 
+// GameSettings are the engine settings a game keeps from move to move. They
+// are given when the game is created and may be changed by engine-move
+// requests that name them.
+type GameSettings struct {
+	Contempt        int  `json:"contempt,omitempty"`
+	DynamicContempt bool `json:"dynamic_contempt,omitempty"`
+	OpponentElo     int  `json:"opponent_elo,omitempty"`
+}
+
+// apply installs the settings on a game's engine.
+func (g GameSettings) apply(e *socrates.RuleEngine) {
+	e.SetContempt(g.Contempt)
+	e.SetDynamicContempt(g.DynamicContempt)
+	e.SetOpponentElo(g.OpponentElo)
+}
+
+// settingsOf reads back the settings of a game's engine.
+func settingsOf(e *socrates.RuleEngine) GameSettings {
+	return GameSettings{Contempt: e.Contempt(), DynamicContempt: e.DynamicContempt(), OpponentElo: e.OpponentElo()}
+}
+
 type CreateGameResponse struct {
 	ID string `json:"game_id"`
 }
@@ -37,14 +61,36 @@ type GameStateResponse struct {
 	Status   string     `json:"status"`
 	BoardFEN string     `json:"board_fen"`
 	Board    [][]string `json:"board"`
+	ECO      string     `json:"eco,omitempty"`
+	Opening  string     `json:"opening,omitempty"` // name and variation
 }
 
 type MoveRequest struct {
 	Move string `json:"move"`
 }
 
+// EngineMoveRequest asks the engine to play the side to move. Omitted
+// fields play at full strength with the default personality; Elo, when
+// set, overrides SkillLevel. The contempt fields, when present, replace the
+// game's settings from this move on; OpponentElo adjusts the contempt to
+// the rating gap.
+type EngineMoveRequest struct {
+	SkillLevel      *int   `json:"skill_level,omitempty"`
+	Elo             int    `json:"elo,omitempty"`
+	Personality     string `json:"personality,omitempty"`
+	Depth           int    `json:"depth,omitempty"`
+	Contempt        *int   `json:"contempt,omitempty"`
+	DynamicContempt *bool  `json:"dynamic_contempt,omitempty"`
+	OpponentElo     *int   `json:"opponent_elo,omitempty"`
+	Book            string `json:"book,omitempty"` // "off" or a book policy
+	BookSeed        int64  `json:"book_seed,omitempty"`
+}
+
+// defaultEngineDepth is the search depth for engine moves.
+const defaultEngineDepth = 4
+
 type GameStore interface {
-	Create() (string, error)
+	Create(settings GameSettings) (string, error)
 	Get(id string) (*shell.GameSession, error)
 	Save(id string, session *shell.GameSession) error
 }
@@ -60,11 +106,13 @@ func NewMemoryStore() *InMemoryStore {
 	return &InMemoryStore{games: make(map[string]*shell.GameSession)}
 }
 
-func (s *InMemoryStore) Create() (string, error) {
+func (s *InMemoryStore) Create(settings GameSettings) (string, error) {
 	s.Lock()
 	defer s.Unlock()
 	id := fmt.Sprintf("game_%d", time.Now().UnixNano())
-	s.games[id] = shell.NewSession(nil)
+	session := shell.NewSession(nil)
+	settings.apply(session.Engine)
+	s.games[id] = session
 	return id, nil
 }
 
@@ -112,23 +160,36 @@ func ensureSchema(db *sql.DB) error {
 	if _, err := db.Exec(`ALTER TABLE games ADD COLUMN IF NOT EXISTS pgn TEXT;`); err != nil {
 		return err
 	}
+	if _, err := db.Exec(`ALTER TABLE games ADD COLUMN IF NOT EXISTS settings TEXT;`); err != nil {
+		return err
+	}
 	return nil
 }
 
-func (s *PostgresStore) Create() (string, error) {
+func (s *PostgresStore) Create(settings GameSettings) (string, error) {
 	id := fmt.Sprintf("game_%d", time.Now().UnixNano())
 	session := shell.NewSession(nil)
 	initialFEN := session.Engine.Board.ToFEN(session.Engine.State)
-	_, err := s.db.Exec("INSERT INTO games (id, fen, pgn) VALUES ($1, $2, '')", id, initialFEN)
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.db.Exec("INSERT INTO games (id, fen, pgn, settings) VALUES ($1, $2, '', $3)", id, initialFEN, string(data))
 	return id, err
 }
 
 func (s *PostgresStore) Get(id string) (*shell.GameSession, error) {
-	var fenData, pgnData sql.NullString
-	err := s.db.QueryRow("SELECT fen, pgn FROM games WHERE id = $1", id).Scan(&fenData, &pgnData)
+	var fenData, pgnData, settingsData sql.NullString
+	err := s.db.QueryRow("SELECT fen, pgn, settings FROM games WHERE id = $1", id).Scan(&fenData, &pgnData, &settingsData)
 	if err != nil {
 		return nil, err
 	}
+	var settings GameSettings
+	if settingsData.Valid && settingsData.String != "" {
+		if err := json.Unmarshal([]byte(settingsData.String), &settings); err != nil {
+			return nil, err
+		}
+	}
 
 	if fenData.Valid {
 		board, state, err := board.FromFEN(fenData.String)
@@ -138,6 +199,7 @@ func (s *PostgresStore) Get(id string) (*shell.GameSession, error) {
 			session.Engine.State = state
 			session.Engine.Turn = state.Turn
 			session.Engine.ResetHashHistory()
+			settings.apply(session.Engine)
 			return session, nil
 		}
 		// fall back to PGN replay if FEN invalid
@@ -149,14 +211,19 @@ func (s *PostgresStore) Get(id string) (*shell.GameSession, error) {
 			return nil, err
 		}
 	}
+	settings.apply(session.Engine)
 	return session, nil
 }
 
 func (s *PostgresStore) Save(id string, session *shell.GameSession) error {
 	// Serialize state to PGN
-	data := pgn.Export(session.Log)
+	data := pgn.Export(session.Engine)
 	fen := session.Engine.Board.ToFEN(session.Engine.State)
-	_, err := s.db.Exec("UPDATE games SET fen = $1, pgn = $2 WHERE id = $3", fen, data, id)
+	settings, err := json.Marshal(settingsOf(session.Engine))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("UPDATE games SET fen = $1, pgn = $2, settings = $3 WHERE id = $4", fen, data, string(settings), id)
 	return err
 }
 
@@ -191,6 +258,10 @@ func main() {
 		store = NewMemoryStore()
 	}
 
+	if err := openingBook.load(os.Getenv("BOOK_FILE"), os.Getenv("BOOK_LEARN_FILE")); err != nil {
+		log.Fatal(err)
+	}
+
 	hub := ws.NewHub()
 	go hub.Run()
 
@@ -212,7 +283,13 @@ func main() {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed) // FIXED
 			return
 		}
-		id, err := store.Create()
+		var settings GameSettings
+		// An empty body, chunked or not, leaves the defaults.
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil && err != io.EOF {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		id, err := store.Create(settings)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError) // FIXED
 			return
@@ -241,6 +318,8 @@ func main() {
 			handleGetState(w, session, gameID)
 		} else if r.Method == http.MethodPost && len(parts) == 3 && parts[2] == "move" {
 			handleMove(w, r, session, store, gameID, hub)
+		} else if r.Method == http.MethodPost && len(parts) == 3 && parts[2] == "engine-move" {
+			handleEngineMove(w, r, session, store, gameID, hub)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed) // FIXED
 		}
@@ -251,9 +330,11 @@ func main() {
 }
 
 func handleGetState(w http.ResponseWriter, s *shell.GameSession, id string) {
-	s.Mu.RLock()
+	// Naming the opening rewinds the engine, so even reading takes the
+	// write lock.
+	s.Mu.Lock()
 	resp := snapshotStateResponse(s, id)
-	s.Mu.RUnlock()
+	s.Mu.Unlock()
 	json.NewEncoder(w).Encode(resp)
 }
 
@@ -278,6 +359,86 @@ func handleMove(w http.ResponseWriter, r *http.Request, s *shell.GameSession, st
 		return
 	}
 
+	publishMove(w, s, store, id, hub)
+}
+
+// handleEngineMove lets the engine reply at the requested strength and style.
+func handleEngineMove(w http.ResponseWriter, r *http.Request, s *shell.GameSession, store GameStore, id string, hub *ws.Hub) {
+	var req EngineMoveRequest
+	// An empty body, chunked or not, leaves the defaults.
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	personality := socrates.Balanced
+	if req.Personality != "" {
+		p, ok := socrates.PersonalityByName(req.Personality)
+		if !ok {
+			http.Error(w, "Unknown personality: "+req.Personality, http.StatusBadRequest)
+			return
+		}
+		personality = p
+	}
+	level := socrates.MaxSkillLevel
+	if req.SkillLevel != nil {
+		level = *req.SkillLevel
+	}
+	if req.Elo != 0 {
+		level = socrates.EloToSkillLevel(req.Elo)
+	}
+	depth := req.Depth
+	if depth <= 0 {
+		depth = defaultEngineDepth
+	}
+	// Each game takes its own reproducible path through the book.
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	book := socrates.BookOptions{Seed: req.BookSeed, Game: int(h.Sum32())}
+	switch p, ok := socrates.BookPolicyByName(req.Book); {
+	case req.Book == "off":
+		book.Disabled = true
+	case ok:
+		book.Policy = p
+	case req.Book != "":
+		http.Error(w, "Unknown book policy: "+req.Book, http.StatusBadRequest)
+		return
+	}
+
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+
+	s.Engine.SetSkillLevel(level)
+	s.Engine.SetPersonality(personality)
+	if req.Contempt != nil {
+		s.Engine.SetContempt(*req.Contempt)
+	}
+	if req.DynamicContempt != nil {
+		s.Engine.SetDynamicContempt(*req.DynamicContempt)
+	}
+	if req.OpponentElo != nil {
+		s.Engine.SetOpponentElo(*req.OpponentElo)
+	}
+	s.Engine.SetBookOptions(book)
+	openingBook.install(s.Engine)
+	res := s.Engine.Search(depth)
+	if res.From == res.To {
+		http.Error(w, "No move available", http.StatusConflict)
+		return
+	}
+	openingBook.played(id, s.Engine, res)
+	if !s.Engine.MakeMove(res.From, res.To, res.Promo) {
+		http.Error(w, "No move available", http.StatusConflict)
+		return
+	}
+
+	publishMove(w, s, store, id, hub)
+}
+
+// publishMove persists the session, broadcasts the new state to watchers
+// and writes it as the response.
+func publishMove(w http.ResponseWriter, s *shell.GameSession, store GameStore, id string, hub *ws.Hub) {
+	openingBook.finish(id, s.Engine)
+
 	// Persist state after move!
 	if err := store.Save(id, s); err != nil {
 		log.Printf("Failed to save game: %v", err)
@@ -311,6 +472,8 @@ func snapshotStateResponse(s *shell.GameSession, id string) GameStateResponse {
 
 	fen := s.Engine.Board.ToFEN(s.Engine.State)
 
+	opening, _ := eco.ClassifyGame(s.Engine)
+
 	return GameStateResponse{
 		ID:       id,
 		Turn:     turn,
@@ -318,6 +481,8 @@ func snapshotStateResponse(s *shell.GameSession, id string) GameStateResponse {
 		Status:   status,
 		BoardFEN: fen,
 		Board:    materializeBoard(s.Engine.Board),
+		ECO:      opening.ECO,
+		Opening:  opening.String(),
 	}
 }
 
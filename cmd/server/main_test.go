@@ -3,14 +3,23 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/polyglot"
+	"github.com/mesb/mchess/shell"
+	"github.com/mesb/mchess/socrates"
 )
 
 func TestHandleGetStateAndMove(t *testing.T) {
 	store := NewMemoryStore()
-	gameID, err := store.Create()
+	gameID, err := store.Create(GameSettings{})
 	if err != nil {
 		t.Fatalf("create game: %v", err)
 	}
@@ -54,4 +63,136 @@ func TestHandleGetStateAndMove(t *testing.T) {
 	if moveResp.BoardFEN == stateResp.BoardFEN {
 		t.Fatalf("board FEN did not change after move")
 	}
+	if moveResp.ECO != "B00" || moveResp.Opening != "King's Pawn Game" {
+		t.Fatalf("opening %s %q after e2e4", moveResp.ECO, moveResp.Opening)
+	}
+}
+
+func TestHandleEngineMove(t *testing.T) {
+	store := NewMemoryStore()
+	gameID, _ := store.Create(GameSettings{})
+	session, _ := store.Get(gameID)
+
+	body := bytes.NewBufferString(`{"skill_level":0,"personality":"aggressive","depth":2,"contempt":30}`)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/games/"+gameID+"/engine-move", body)
+	handleEngineMove(w, req, session, store, gameID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d (%s)", w.Code, w.Body.String())
+	}
+	var resp GameStateResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Turn != "Black" {
+		t.Fatalf("engine did not move for White, turn %s", resp.Turn)
+	}
+	if session.Engine.SkillLevel() != 0 || session.Engine.Personality().Name != "aggressive" || session.Engine.Contempt() != 30 {
+		t.Fatalf("settings not applied: level %d, %s", session.Engine.SkillLevel(), session.Engine.Personality().Name)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/games/"+gameID+"/engine-move", bytes.NewBufferString(`{"personality":"reckless"}`))
+	handleEngineMove(w, req, session, store, gameID, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown personality should be rejected, got %d", w.Code)
+	}
+
+	// A chunked request with an empty body plays with the defaults.
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/games/"+gameID+"/engine-move", struct{ io.Reader }{strings.NewReader("")})
+	if req.ContentLength != -1 {
+		t.Fatalf("request length %d, want unknown", req.ContentLength)
+	}
+	handleEngineMove(w, req, session, store, gameID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("empty chunked body: %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestEngineMoveKeepsGameContempt(t *testing.T) {
+	store := NewMemoryStore()
+	gameID, _ := store.Create(GameSettings{Contempt: 20, DynamicContempt: true, OpponentElo: 1800})
+	session, _ := store.Get(gameID)
+
+	move := func(body string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/games/"+gameID+"/engine-move", bytes.NewBufferString(body))
+		handleEngineMove(w, req, session, store, gameID, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status: %d (%s)", w.Code, w.Body.String())
+		}
+	}
+	want := GameSettings{Contempt: 20, DynamicContempt: true, OpponentElo: 1800}
+	move(`{"depth":1}`)
+	if got := settingsOf(session.Engine); got != want {
+		t.Fatalf("settings %+v after a move without them, want %+v", got, want)
+	}
+
+	// Named fields replace the game's settings, and stay for later moves.
+	move(`{"depth":1,"contempt":0,"dynamic_contempt":false}`)
+	move(`{"depth":1}`)
+	want = GameSettings{OpponentElo: 1800}
+	if got := settingsOf(session.Engine); got != want {
+		t.Fatalf("settings %+v, want %+v", got, want)
+	}
+}
+
+func TestServerBookLearning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.learn")
+	b := &serverBook{lines: map[string]*bookLine{}}
+	if err := b.load("", path); err != nil {
+		t.Fatal(err)
+	}
+	session := shell.NewSession(nil)
+	b.install(session.Engine)
+	res := session.Engine.Search(1)
+	if !res.Book {
+		t.Fatal("expected a book move from the start position")
+	}
+	b.played("g", session.Engine, res)
+	step := session.Engine.BookStep(socrates.SimpleMove{From: res.From, To: res.To})
+
+	// Nothing is learned before the game ends.
+	b.finish("g", session.Engine)
+	if b.learn.Len() != 0 {
+		t.Fatal("learned from an unfinished game")
+	}
+
+	// Fool's mate: White is mated, so its book move scores a loss.
+	bd, st, _ := board.FromFEN("rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3")
+	session.Engine.Board, session.Engine.State, session.Engine.Turn = bd, st, st.Turn
+	b.finish("g", session.Engine)
+	if got := b.learn.Result(step.Key, step.Move); got.Losses != 1 {
+		t.Fatalf("fool's mate recorded as %+v", got)
+	}
+	saved, err := polyglot.OpenLearning(path)
+	if err != nil || saved.Len() != 1 {
+		t.Fatalf("sidecar not saved: %v", err)
+	}
+	if len(b.lines) != 0 {
+		t.Fatalf("finished game's line kept: %v", b.lines)
+	}
+}
+
+func TestServerBookDropsAbandonedGames(t *testing.T) {
+	b := &serverBook{lines: map[string]*bookLine{}}
+	if err := b.load("", filepath.Join(t.TempDir(), "book.learn")); err != nil {
+		t.Fatal(err)
+	}
+	session := shell.NewSession(nil)
+	b.install(session.Engine)
+	res := session.Engine.Search(1)
+	b.played("old", session.Engine, res)
+	b.played("new", session.Engine, res)
+	b.lines["old"].moved = time.Now().Add(-abandonAfter - time.Minute)
+
+	b.finish("new", session.Engine)
+	if _, ok := b.lines["old"]; ok {
+		t.Error("abandoned game's line kept")
+	}
+	if _, ok := b.lines["new"]; !ok {
+		t.Error("game in progress lost its line")
+	}
 }
@@ -0,0 +1,63 @@
+// --- cmd/tune/main.go ---
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/mesb/mchess/socrates"
+	"github.com/mesb/mchess/tune"
+)
+
+// main tunes the evaluation parameters against a labelled dataset and writes
+// them to a JSON file that cmd/engine can load with -evalparams.
+func main() {
+	data := flag.String("data", "", "dataset of quiet positions (.epd) or finished games (.pgn)")
+	out := flag.String("out", "params.json", "where to write the tuned parameters")
+	start := flag.String("start", "", "optional parameter file to start from instead of the defaults")
+	k := flag.Float64("k", 0, "logistic scaling constant (0 = fit automatically)")
+	passes := flag.Int("passes", 0, "maximum passes over all parameters (0 = until converged)")
+	step := flag.Int("step", 1, "parameter increment tried by local search")
+	skip := flag.Int("skip", 8, "opening plies to skip when sampling PGN games")
+	flag.Parse()
+
+	if *data == "" {
+		log.Fatal("missing -data")
+	}
+	positions, err := tune.LoadFile(*data, tune.PGNOptions{SkipPlies: *skip})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("loaded %d positions\n", len(positions))
+
+	params := socrates.DefaultEvalParams()
+	if *start != "" {
+		if params, err = socrates.ReadEvalParams(*start); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	socrates.SetEvalParams(params)
+	if *k == 0 {
+		*k = tune.FindK(positions)
+	}
+	fmt.Printf("K = %.4f, initial error = %.6f\n", *k, tune.Error(positions, *k))
+
+	best, bestErr := tune.Tune(positions, params, tune.Options{
+		K:         *k,
+		MaxPasses: *passes,
+		Step:      *step,
+		Progress: func(pass int, e float64, p socrates.EvalParams) {
+			fmt.Printf("pass %d: error = %.6f\n", pass, e)
+			if err := p.Save(*out); err != nil {
+				log.Printf("save: %v", err)
+			}
+		},
+	})
+	if err := best.Save(*out); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("final error = %.6f, parameters written to %s\n", bestErr, *out)
+}
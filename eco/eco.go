@@ -0,0 +1,142 @@
+// --- eco/eco.go ---
+
+// Package eco names the opening of a game by its ECO code.
+//
+// The embedded table lists the main line of every opening; each line is
+// replayed once and indexed by the Polyglot key of the position it reaches,
+// so a game that arrives there by another move order is still recognised.
+// A game is classified by the last table position it passed through.
+package eco
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/notation"
+	"github.com/mesb/mchess/polyglot"
+	"github.com/mesb/mchess/socrates"
+)
+
+// Opening is one entry of the ECO table.
+type Opening struct {
+	ECO       string // e.g. "C50"
+	Name      string // e.g. "Italian Game"
+	Variation string // may be empty
+	Moves     string // main line in coordinate notation
+}
+
+// String returns the full name, e.g. "Italian Game: Giuoco Piano".
+func (o Opening) String() string {
+	if o.Variation == "" {
+		return o.Name
+	}
+	return o.Name + ": " + o.Variation
+}
+
+//go:embed eco.tsv
+var tableData string
+
+var (
+	tableOnce sync.Once
+	table     map[uint64]Opening
+	maxPly    int // length of the longest line; later moves cannot match
+	tableErr  error
+)
+
+// load replays every line of the table. A line that does not replay is a
+// bug in the table, reported by Check.
+func load() {
+	table = map[uint64]Opening{}
+	e := getReplay()
+	defer replays.Put(e)
+	for n, line := range strings.Split(tableData, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		if len(f) != 4 {
+			tableErr = fmt.Errorf("eco: line %d: want 4 fields, got %d", n+1, len(f))
+			return
+		}
+		o := Opening{ECO: f[0], Name: f[1], Variation: f[2], Moves: f[3]}
+		resetReplay(e)
+		moves := strings.Fields(o.Moves)
+		for _, mv := range moves {
+			from, to, promo, err := socrates.ParseMove(mv)
+			if err != nil || !e.MakeMove(*from, *to, promo) {
+				tableErr = fmt.Errorf("eco: line %d: illegal move %s", n+1, mv)
+				return
+			}
+		}
+		table[polyglot.Key(e.Board, e.State)] = o
+		maxPly = max(maxPly, len(moves))
+	}
+}
+
+// Check reports whether the embedded table is consistent.
+func Check() error {
+	tableOnce.Do(load)
+	return tableErr
+}
+
+// Lookup names the position itself, if it is a table position.
+func Lookup(b *board.Board, s *board.GameState) (Opening, bool) {
+	tableOnce.Do(load)
+	o, ok := table[polyglot.Key(b, s)]
+	return o, ok
+}
+
+// Classify names the opening of a game from its start position and moves.
+// Only games started from the standard position have one: the same moves
+// from another position say nothing about the opening.
+func Classify(fen string, moves []socrates.SimpleMove) (Opening, bool) {
+	tableOnce.Do(load)
+	var best Opening
+	found := false
+	b, st, err := board.FromFEN(fen)
+	if err != nil || polyglot.Key(b, st) != polyglot.Key(board.InitStandard(), board.NewGameState()) {
+		return best, false
+	}
+	e := getReplay()
+	defer replays.Put(e)
+	for i, m := range moves {
+		if i >= maxPly || !e.MakeMove(m.From, m.To, m.Promo) {
+			break
+		}
+		if o, ok := table[polyglot.Key(e.Board, e.State)]; ok {
+			best, found = o, true
+		}
+	}
+	return best, found
+}
+
+// ClassifyGame names the opening of the game played on engine. A game set
+// up from another position is named by the position it has reached, if
+// that is a table position. engine is rewound and replayed, and must not
+// be in use meanwhile.
+func ClassifyGame(engine *socrates.RuleEngine) (Opening, bool) {
+	if o, ok := Classify(notation.Played(engine)); ok {
+		return o, true
+	}
+	return Lookup(engine.Board, engine.State)
+}
+
+// replays recycles the engines used to replay moves: each one carries a
+// transposition table, far too large to allocate per game.
+var replays = sync.Pool{New: func() any {
+	return socrates.New(board.InitStandard())
+}}
+
+func getReplay() *socrates.RuleEngine {
+	e := replays.Get().(*socrates.RuleEngine)
+	resetReplay(e)
+	return e
+}
+
+// resetReplay puts e back on the start position.
+func resetReplay(e *socrates.RuleEngine) {
+	e.SetPosition(board.InitStandard(), board.NewGameState())
+}
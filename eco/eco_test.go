@@ -0,0 +1,87 @@
+package eco
+
+import (
+	"testing"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/notation"
+	"github.com/mesb/mchess/socrates"
+)
+
+func TestTableReplays(t *testing.T) {
+	if err := Check(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func play(t *testing.T, moves ...string) *socrates.RuleEngine {
+	t.Helper()
+	e := socrates.New(board.InitStandard())
+	e.Log = &socrates.Log{}
+	for _, mv := range moves {
+		from, to, promo, err := socrates.ParseMove(mv)
+		if err != nil || !e.MakeMove(*from, *to, promo) {
+			t.Fatalf("illegal move %s", mv)
+		}
+	}
+	return e
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		moves []string
+		eco   string
+		name  string
+	}{
+		{[]string{"e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "f8c5"}, "C50", "Italian Game: Giuoco Piano"},
+		// Out of the table after Black's third move: still a Giuoco Piano.
+		{[]string{"e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "f8c5", "d2d3", "g8f6"}, "C50", "Italian Game: Giuoco Piano"},
+		// 1. c4 e6 2. d4 Nf6 transposes to 1. d4 Nf6 2. c4 e6.
+		{[]string{"c2c4", "e7e6", "d2d4", "g8f6"}, "E00", "Indian Defense"},
+		{[]string{"e2e4", "c7c5", "g1f3", "d7d6", "d2d4", "c5d4", "f3d4", "g8f6", "b1c3", "a7a6"}, "B90", "Sicilian Defense: Najdorf Variation"},
+	}
+	for _, tt := range tests {
+		o, ok := Classify(notation.Played(play(t, tt.moves...)))
+		if !ok || o.ECO != tt.eco || o.String() != tt.name {
+			t.Errorf("%v: got %s %q (%v), want %s %q", tt.moves, o.ECO, o, ok, tt.eco, tt.name)
+		}
+	}
+
+	if _, ok := Classify(notation.Played(play(t))); ok {
+		t.Error("the start position has no opening")
+	}
+}
+
+func TestClassifyFromFEN(t *testing.T) {
+	b, st, err := board.FromFEN("4k3/8/8/8/8/8/4P3/4K1N1 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := socrates.New(b)
+	e.SetPosition(b, st)
+	from, to, _, _ := socrates.ParseMove("g1f3")
+	if !e.MakeMove(*from, *to, 0) {
+		t.Fatal("Nf3 is legal")
+	}
+	if o, ok := Classify(notation.Played(e)); ok {
+		t.Fatalf("a game set up from a FEN was classified as %s %q", o.ECO, o)
+	}
+	if o, ok := ClassifyGame(e); ok {
+		t.Fatalf("ClassifyGame named %s %q", o.ECO, o)
+	}
+
+	// Set up on a table position, the game is named by where it stands.
+	b, st, _ = board.FromFEN("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2")
+	e = socrates.New(b)
+	e.SetPosition(b, st)
+	if o, ok := ClassifyGame(e); !ok || o.ECO != "C20" {
+		t.Fatalf("got %s %q (%v), want C20", o.ECO, o, ok)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	b, s, _ := board.FromFEN("rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2")
+	if o, ok := Lookup(b, s); !ok || o.ECO != "B20" {
+		t.Fatalf("got %+v %v", o, ok)
+	}
+}
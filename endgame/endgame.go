@@ -0,0 +1,210 @@
+// --- endgame/endgame.go ---
+
+// Package endgame holds chess knowledge the general evaluation cannot find
+// at normal search depths: a KPK bitbase, specialised scoring for known
+// material signatures (mating a lone king, KBNK, KRKP, ...) and scale factors
+// that pull drawish endings (opposite-coloured bishops, wrong rook pawn,
+// insufficient mating material) towards zero.
+//
+// Positions are dispatched by a material key such as "KRKP", built from the
+// White pieces followed by the Black ones. Specialised functions are
+// registered with the strong side first and matched in either colour order.
+package endgame
+
+import (
+	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/pieces"
+)
+
+// Piece kinds, in the same order as Zobrist and NNUE piece indices.
+const (
+	Pawn = iota
+	Knight
+	Bishop
+	Rook
+	Queen
+	King
+)
+
+// Values used for material comparisons inside this package (centipawns).
+const (
+	PawnValue   = 100
+	KnightValue = 320
+	BishopValue = 330
+	RookValue   = 500
+	QueenValue  = 900
+
+	// KnownWin is added to scores of theoretically won endings so the search
+	// prefers them to any unclear middlegame advantage, while staying well
+	// below real mate scores.
+	KnownWin = 10000
+
+	// ScaleNormal leaves a score unchanged; ScaleDraw zeroes it.
+	ScaleNormal = 64
+	ScaleDraw   = 0
+)
+
+// Material counts the pieces of each colour by kind.
+type Material [2][6]int
+
+// Position is a compact piece list view of a board.
+type Position struct {
+	Count   Material
+	Squares [2][6][]int // square indices, a1 = 0 .. h8 = 63
+	Turn    int
+}
+
+// NewPosition scans a board into piece lists.
+func NewPosition(b *board.Board, turn int) *Position {
+	pos := &Position{Turn: turn}
+	b.ForEachPiece(func(sq address.Addr, p pieces.Piece) {
+		k := kind(p)
+		if k < 0 {
+			return
+		}
+		c := p.Color()
+		pos.Count[c][k]++
+		pos.Squares[c][k] = append(pos.Squares[c][k], sq.Index())
+	})
+	return pos
+}
+
+func kind(p pieces.Piece) int {
+	switch p.(type) {
+	case *pieces.Pawn:
+		return Pawn
+	case *pieces.Knight:
+		return Knight
+	case *pieces.Bishop:
+		return Bishop
+	case *pieces.Rook:
+		return Rook
+	case *pieces.Queen:
+		return Queen
+	case *pieces.King:
+		return King
+	}
+	return -1
+}
+
+// Key returns the material signature, White pieces first, e.g. "KBNK".
+func (pos *Position) Key() string {
+	return pos.side(pieces.WHITE) + pos.side(pieces.BLACK)
+}
+
+func (pos *Position) side(c int) string {
+	const letters = "PNBRQK"
+	s := make([]byte, 0, 8)
+	for k := King; k >= Pawn; k-- {
+		for i := 0; i < pos.Count[c][k]; i++ {
+			s = append(s, letters[k])
+		}
+	}
+	return string(s)
+}
+
+// NonPawnMaterial sums the piece values of one side, excluding pawns and king.
+func (pos *Position) NonPawnMaterial(c int) int {
+	return pos.Count.nonPawn(c)
+}
+
+func (m *Material) nonPawn(c int) int {
+	return m[c][Knight]*KnightValue + m[c][Bishop]*BishopValue + m[c][Rook]*RookValue + m[c][Queen]*QueenValue
+}
+
+// Known reports whether positions with this material can have a specialised
+// evaluation or a scale factor. When they cannot, Evaluate declines and Scale
+// leaves scores unchanged, so callers that keep the counts incrementally can
+// skip building a Position.
+func (m *Material) Known() bool {
+	if m[pieces.WHITE][King] != 1 || m[pieces.BLACK][King] != 1 {
+		return false
+	}
+	pos := &Position{Count: *m}
+	if m.total() <= 4 { // the size of the largest specialised signature
+		w, bl := pos.side(pieces.WHITE), pos.side(pieces.BLACK)
+		if specialised[w+bl] != nil || specialised[bl+w] != nil {
+			return true
+		}
+	}
+	for strong := pieces.WHITE; strong <= pieces.BLACK; strong++ {
+		if isKXK(pos, strong) || m.minorEdge(strong) || m.rookPawnMaterial(strong) || m.bishopEach(strong) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Material) total() int {
+	n := 0
+	for c := range m {
+		for _, k := range m[c] {
+			n += k
+		}
+	}
+	return n
+}
+
+func (pos *Position) king(c int) int {
+	return pos.Squares[c][King][0]
+}
+
+func (pos *Position) hasKings() bool {
+	return pos.Count[pieces.WHITE][King] == 1 && pos.Count[pieces.BLACK][King] == 1
+}
+
+// evalFunc scores a position from the strong side's point of view.
+type evalFunc func(pos *Position, strong int) int
+
+// specialised maps strong-side-first material keys to their evaluators.
+var specialised = map[string]evalFunc{
+	"KPK":  evalKPK,
+	"KBNK": evalKBNK,
+	"KRKP": evalKRKP,
+	"KRKB": evalKRKB,
+	"KRKN": evalKRKN,
+	"KNNK": evalDraw,
+	"KNK":  evalDraw,
+	"KBK":  evalDraw,
+	"KK":   evalDraw,
+}
+
+// Evaluate returns a White-relative score for positions with a specialised
+// evaluation, and ok = false when the general evaluation should be used.
+func (pos *Position) Evaluate() (int, bool) {
+	if !pos.hasKings() {
+		return 0, false
+	}
+	w, bl := pos.side(pieces.WHITE), pos.side(pieces.BLACK)
+	if fn, ok := specialised[w+bl]; ok {
+		return fn(pos, pieces.WHITE), true
+	}
+	if fn, ok := specialised[bl+w]; ok {
+		return -fn(pos, pieces.BLACK), true
+	}
+	for strong := pieces.WHITE; strong <= pieces.BLACK; strong++ {
+		if isKXK(pos, strong) {
+			score := evalKXK(pos, strong)
+			if strong == pieces.BLACK {
+				score = -score
+			}
+			return score, true
+		}
+	}
+	return 0, false
+}
+
+// Scale applies draw-scaling factors to a White-relative score.
+func (pos *Position) Scale(score int) int {
+	if score == 0 || !pos.hasKings() {
+		return score
+	}
+	strong := pieces.WHITE
+	if score < 0 {
+		strong = pieces.BLACK
+	}
+	return score * pos.ScaleFactor(strong) / ScaleNormal
+}
+
+func evalDraw(*Position, int) int { return 0 }
@@ -0,0 +1,156 @@
+package endgame
+
+import (
+	"testing"
+
+	"github.com/mesb/mchess/board"
+)
+
+func fromFEN(t *testing.T, fen string) *Position {
+	t.Helper()
+	b, state, err := board.FromFEN(fen)
+	if err != nil {
+		t.Fatalf("FEN %q: %v", fen, err)
+	}
+	return NewPosition(b, state.Turn)
+}
+
+func TestMaterialKey(t *testing.T) {
+	pos := fromFEN(t, "8/8/8/4k3/8/8/8/1NB1K3 w - - 0 1")
+	if got := pos.Key(); got != "KBNK" {
+		t.Fatalf("expected KBNK, got %s", got)
+	}
+	pos = fromFEN(t, "8/8/8/4k3/4p3/8/8/R3K3 b - - 0 1")
+	if got := pos.Key(); got != "KRKP" {
+		t.Fatalf("expected KRKP, got %s", got)
+	}
+}
+
+func TestKPKBitbase(t *testing.T) {
+	cases := []struct {
+		fen  string
+		wins bool
+	}{
+		{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", true},  // king on the sixth in front of the pawn
+		{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", true},  // ... whoever moves
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", true},  // spare pawn tempo wins the opposition
+		{"8/8/4k3/8/4K3/4P3/8/8 w - - 0 1", false}, // defender holds the opposition
+		{"k7/8/8/8/8/8/P7/K7 w - - 0 1", false},    // rook pawn, king in the corner
+		{"8/8/8/8/8/k7/7P/7K w - - 0 1", true},     // king outside the square
+		{"8/8/8/8/8/k7/7P/7K b - - 0 1", true},     // still outside after one step
+		{"8/8/8/8/4k3/8/7P/7K b - - 0 1", false},   // inside the square
+	}
+	for _, c := range cases {
+		score, ok := fromFEN(t, c.fen).Evaluate()
+		if !ok {
+			t.Fatalf("%s: KPK not dispatched", c.fen)
+		}
+		if got := score > KnownWin; got != c.wins {
+			t.Errorf("%s: win=%v, want %v (score %d)", c.fen, got, c.wins, score)
+		}
+	}
+}
+
+func TestKPKMirrorsForBlack(t *testing.T) {
+	white := fromFEN(t, "4k3/8/4K3/4P3/8/8/8/8 w - - 0 1")
+	black := fromFEN(t, "8/8/8/8/4p3/4k3/8/4K3 b - - 0 1")
+	ws, _ := white.Evaluate()
+	bs, _ := black.Evaluate()
+	if ws != -bs || ws <= KnownWin {
+		t.Fatalf("colour-flipped KPK should mirror: %d vs %d", ws, bs)
+	}
+}
+
+func TestKXKDrivesKingToEdge(t *testing.T) {
+	centre, _ := fromFEN(t, "8/8/8/3k4/8/2K5/8/Q7 w - - 0 1").Evaluate()
+	edge, _ := fromFEN(t, "8/8/8/k7/8/2K5/8/Q7 w - - 0 1").Evaluate()
+	if centre <= KnownWin || edge <= centre {
+		t.Fatalf("KQK should be a known win preferring the edge: centre %d edge %d", centre, edge)
+	}
+	black, _ := fromFEN(t, "r3k3/8/8/8/8/8/8/4K3 w - - 0 1").Evaluate()
+	if black >= -KnownWin {
+		t.Fatalf("KRK for Black should be a known loss for White, got %d", black)
+	}
+}
+
+func TestKBNKPrefersBishopCorner(t *testing.T) {
+	// Light-squared bishop on f1: a8 and h1 are the mating corners.
+	right, _ := fromFEN(t, "k7/8/2K5/8/8/8/8/3N1B2 w - - 0 1").Evaluate()
+	wrong, _ := fromFEN(t, "7k/8/5K2/8/8/8/8/3N1B2 w - - 0 1").Evaluate()
+	if right <= wrong {
+		t.Fatalf("KBNK should prefer the bishop's corner: right %d wrong %d", right, wrong)
+	}
+}
+
+func TestKRKPKingInFront(t *testing.T) {
+	score, ok := fromFEN(t, "8/8/8/8/4p3/8/4K3/R6k w - - 0 1").Evaluate()
+	if !ok || score < RookValue-10 {
+		t.Fatalf("rook with king blocking the pawn should win, got %d", score)
+	}
+}
+
+func TestInsufficientMaterialIsDraw(t *testing.T) {
+	for _, fen := range []string{
+		"4k3/8/8/8/8/8/8/1N2K3 w - - 0 1",
+		"4k3/8/8/8/8/8/8/NN2K3 w - - 0 1",
+		"4k3/8/8/8/8/8/8/2B1K3 b - - 0 1",
+	} {
+		if score, ok := fromFEN(t, fen).Evaluate(); !ok || score != 0 {
+			t.Errorf("%s: expected draw, got %d (ok=%v)", fen, score, ok)
+		}
+	}
+}
+
+func TestScaleFactors(t *testing.T) {
+	ocb := fromFEN(t, "4k3/5p2/8/3b4/8/2B5/4PP2/4K3 w - - 0 1")
+	if sf := ocb.ScaleFactor(0); sf >= ScaleNormal {
+		t.Fatalf("opposite bishops should scale down, got %d", sf)
+	}
+	wrongBishop := fromFEN(t, "k7/8/8/8/8/8/P7/K1B5 w - - 0 1")
+	if sf := wrongBishop.ScaleFactor(0); sf != ScaleDraw {
+		t.Fatalf("wrong rook pawn should be a draw, got %d", sf)
+	}
+	rightBishop := fromFEN(t, "k7/8/8/8/8/8/P7/K4B2 w - - 0 1")
+	if sf := rightBishop.ScaleFactor(0); sf != ScaleNormal {
+		t.Fatalf("right bishop should keep winning chances, got %d", sf)
+	}
+	if got := wrongBishop.Scale(400); got != 0 {
+		t.Fatalf("Scale should zero a drawn advantage, got %d", got)
+	}
+}
+
+func TestKnownMaterial(t *testing.T) {
+	cases := []struct {
+		fen   string
+		known bool
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", false},
+		{"r1bq1rk1/pp3ppp/2n1pn2/3p4/3P4/2N1PN2/PP3PPP/R1BQKB1R w KQ - 0 9", false},
+		{"6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1", false}, // rook and pawns against pawns
+		{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", true},        // KPK
+		{"8/8/8/4k3/8/8/8/1NB1K3 w - - 0 1", true},       // KBNK
+		{"4k3/8/8/8/8/8/PPPP4/QR2K3 w - - 0 1", true},    // lone king
+		{"r3k3/pp3b2/8/8/8/8/PP6/4KB1R w - - 0 1", true}, // one bishop each, other pieces too
+		{"4k3/8/8/8/8/8/8/1N2K1N1 w - - 0 1", true},      // minor-piece edge
+		{"k7/8/8/8/8/8/P7/K7 w - - 0 1", true},           // rook pawn
+		{"8/8/8/8/8/8/8/8 w - - 0 1", false},             // no kings
+	}
+	for _, c := range cases {
+		pos := fromFEN(t, c.fen)
+		if got := pos.Count.Known(); got != c.known {
+			t.Errorf("%s: Known = %v, want %v", c.fen, got, c.known)
+		}
+		if c.known {
+			continue
+		}
+		// Unknown material must leave the general evaluation untouched.
+		if _, ok := pos.Evaluate(); ok {
+			t.Errorf("%s: evaluated though the material is not known", c.fen)
+		}
+		for _, score := range []int{137, -137} {
+			if got := pos.Scale(score); got != score {
+				t.Errorf("%s: Scale(%d) = %d", c.fen, score, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// --- endgame/functions.go ---
+
+package endgame
+
+// isKXK matches a bare king against enough material to force mate.
+func isKXK(pos *Position, strong int) bool {
+	weak := 1 - strong
+	for k := Pawn; k < King; k++ {
+		if pos.Count[weak][k] != 0 {
+			return false
+		}
+	}
+	return pos.NonPawnMaterial(strong) >= RookValue
+}
+
+// evalKXK drives the lone king to the edge and brings the attacking king
+// closer, which is all the guidance the search needs for KQK, KRK and friends.
+func evalKXK(pos *Position, strong int) int {
+	weak := 1 - strong
+	sk, wk := pos.king(strong), pos.king(weak)
+	score := pos.NonPawnMaterial(strong) + pos.Count[strong][Pawn]*PawnValue +
+		pushToEdge(wk) + pushClose(sk, wk)
+
+	c := pos.Count[strong]
+	if c[Queen] > 0 || c[Rook] > 0 || c[Pawn] > 0 ||
+		(c[Bishop] > 0 && c[Knight] > 0) || hasBishopPair(pos, strong) {
+		score += KnownWin
+	}
+	return score
+}
+
+func hasBishopPair(pos *Position, c int) bool {
+	light, dark := false, false
+	for _, sq := range pos.Squares[c][Bishop] {
+		if squareColor(sq) == 0 {
+			dark = true
+		} else {
+			light = true
+		}
+	}
+	return light && dark
+}
+
+// evalKBNK mates in the corner the bishop controls.
+func evalKBNK(pos *Position, strong int) int {
+	weak := 1 - strong
+	sk, wk := pos.king(strong), pos.king(weak)
+	c1, c2 := 0, 63 // a1, h8: dark corners
+	if squareColor(pos.Squares[strong][Bishop][0]) == 1 {
+		c1, c2 = 56, 7 // a8, h1: light corners
+	}
+	corner := min(distance(wk, c1), distance(wk, c2))
+	return KnownWin + KnightValue + BishopValue +
+		pushClose(sk, wk) + pushToEdge(wk)/2 + 30*(7-corner)
+}
+
+// evalKPK consults the bitbase: won positions score as a known win that
+// grows as the pawn advances, everything else is a dead draw.
+func evalKPK(pos *Position, strong int) int {
+	weak := 1 - strong
+	psq := pos.Squares[strong][Pawn][0]
+	if !ProbeKPK(strong, pos.king(strong), psq, pos.king(weak), pos.Turn) {
+		return 0
+	}
+	return KnownWin + PawnValue + 10*relativeRank(strong, psq)
+}
+
+// evalKRKP scores rook against pawn. The rook usually wins when the strong
+// king stops the pawn or the weak king is far away; otherwise the result
+// depends on the race between the kings and the pawn.
+func evalKRKP(pos *Position, strong int) int {
+	weak := 1 - strong
+	sk, wk := pos.king(strong), pos.king(weak)
+	rsq, psq := pos.Squares[strong][Rook][0], pos.Squares[weak][Pawn][0]
+	if strong == 1 {
+		// Normalise so the strong side is White and the pawn runs down the board.
+		sk, wk, rsq, psq = flipRank(sk), flipRank(wk), flipRank(rsq), flipRank(psq)
+	}
+	queening := square(0, fileOf(psq))
+	weakToMove, strongToMove := 0, 0
+	if pos.Turn == strong {
+		strongToMove = 1
+	} else {
+		weakToMove = 1
+	}
+
+	switch {
+	case fileOf(sk) == fileOf(psq) && rankOf(sk) < rankOf(psq):
+		return RookValue - distance(sk, psq)
+	case distance(wk, psq) >= 3+weakToMove && distance(wk, rsq) >= 3:
+		return RookValue - distance(sk, psq)
+	case rankOf(wk) <= 2 && distance(wk, psq) == 1 && rankOf(sk) >= 3 && distance(sk, psq) > 2+strongToMove:
+		return 80 - 8*distance(sk, psq)
+	}
+	front := psq - 8
+	return 200 - 8*(distance(sk, front)-distance(wk, front)-distance(psq, queening))
+}
+
+// evalKRKB is a draw in most positions; only a king trapped on the edge gives chances.
+func evalKRKB(pos *Position, strong int) int {
+	return pushToEdge(pos.king(1 - strong))
+}
+
+// evalKRKN is drawish, better when the defending king and knight are apart.
+func evalKRKN(pos *Position, strong int) int {
+	weak := 1 - strong
+	wk := pos.king(weak)
+	return pushToEdge(wk) + pushAway(wk, pos.Squares[weak][Knight][0])
+}
@@ -0,0 +1,53 @@
+// --- endgame/geometry.go ---
+
+package endgame
+
+func rankOf(sq int) int { return sq / 8 }
+func fileOf(sq int) int { return sq % 8 }
+
+func square(rank, file int) int { return rank*8 + file }
+
+func flipRank(sq int) int { return sq ^ 56 }
+func flipFile(sq int) int { return sq ^ 7 }
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// distance is the king-move (Chebyshev) distance between two squares.
+func distance(a, b int) int {
+	return max(absInt(rankOf(a)-rankOf(b)), absInt(fileOf(a)-fileOf(b)))
+}
+
+// relativeRank is the rank of sq counted from color's own back rank (0..7).
+func relativeRank(color, sq int) int {
+	if color == 1 {
+		return 7 - rankOf(sq)
+	}
+	return rankOf(sq)
+}
+
+// squareColor is 0 for dark squares (a1) and 1 for light squares.
+func squareColor(sq int) int {
+	return (rankOf(sq) + fileOf(sq)) % 2
+}
+
+// pushToEdge rewards driving a king away from the centre (0 in the centre, 120 in a corner).
+func pushToEdge(sq int) int {
+	fd := max(3-fileOf(sq), fileOf(sq)-4)
+	rd := max(3-rankOf(sq), rankOf(sq)-4)
+	return 20 * (fd + rd)
+}
+
+// pushClose rewards bringing two pieces together.
+func pushClose(a, b int) int {
+	return 140 - 20*distance(a, b)
+}
+
+// pushAway rewards keeping two pieces apart.
+func pushAway(a, b int) int {
+	return 120 - pushClose(a, b)
+}
@@ -0,0 +1,177 @@
+// --- endgame/kpk.go ---
+
+package endgame
+
+import "sync"
+
+// The KPK bitbase stores, for every King+Pawn vs King position with White
+// holding the pawn on files a-d, whether White wins. It is generated once by
+// retrograde iteration: positions are seeded with immediate results (illegal,
+// promotion wins, stalemates and pawn captures) and then repeatedly resolved
+// from their successors until nothing changes.
+
+const kpkSize = 2 * 24 * 64 * 64 // side to move × pawn square × black king × white king
+
+const (
+	kpkInvalid = 0
+	kpkUnknown = 1
+	kpkDraw    = 2
+	kpkWin     = 4
+)
+
+var (
+	kpkOnce sync.Once
+	kpkBits [kpkSize / 32]uint32
+)
+
+// kpkIndex packs a normalised position; the pawn is on files a-d, ranks 2-7.
+func kpkIndex(stm, bk, wk, psq int) int {
+	return wk | bk<<6 | stm<<12 | fileOf(psq)<<13 | (6-rankOf(psq))<<15
+}
+
+// ProbeKPK reports whether the side with the pawn wins. Squares are a1 = 0
+// .. h8 = 63; strong is the colour owning the pawn and stm the side to move.
+func ProbeKPK(strong, strongKing, pawn, weakKing, stm int) bool {
+	kpkOnce.Do(generateKPK)
+
+	// Normalise: strong side is White and the pawn is on files a-d.
+	if strong == 1 {
+		strongKing, pawn, weakKing = flipRank(strongKing), flipRank(pawn), flipRank(weakKing)
+		stm = 1 - stm
+	}
+	if fileOf(pawn) >= 4 {
+		strongKing, pawn, weakKing = flipFile(strongKing), flipFile(pawn), flipFile(weakKing)
+	}
+	idx := kpkIndex(stm, weakKing, strongKing, pawn)
+	return kpkBits[idx/32]&(1<<(idx%32)) != 0
+}
+
+// kpkPos is an unpacked bitbase index.
+type kpkPos struct{ stm, wk, bk, psq int }
+
+func decodeKPK(idx int) kpkPos {
+	return kpkPos{
+		wk:  idx & 63,
+		bk:  (idx >> 6) & 63,
+		stm: (idx >> 12) & 1,
+		psq: square(6-(idx>>15), (idx>>13)&3),
+	}
+}
+
+func generateKPK() {
+	db := make([]uint8, kpkSize)
+	for idx := range db {
+		db[idx] = kpkInitial(decodeKPK(idx))
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for idx := range db {
+			if db[idx] != kpkUnknown {
+				continue
+			}
+			p := decodeKPK(idx)
+			if r := kpkClassify(db, p.stm, p.wk, p.bk, p.psq); r != kpkUnknown {
+				db[idx] = r
+				changed = true
+			}
+		}
+	}
+
+	for idx, r := range db {
+		if r == kpkWin {
+			kpkBits[idx/32] |= 1 << (idx % 32)
+		}
+	}
+}
+
+func kpkInitial(p kpkPos) uint8 {
+	wk, bk, psq := p.wk, p.bk, p.psq
+	switch {
+	case distance(wk, bk) <= 1 || wk == psq || bk == psq:
+		return kpkInvalid
+	case p.stm == 0 && pawnAttacks(psq, bk):
+		return kpkInvalid // Black king in check with White to move
+	case p.stm == 0 && rankOf(psq) == 6:
+		// Immediate promotion wins unless the new queen is simply taken.
+		promo := psq + 8
+		if wk != promo && (distance(bk, promo) > 1 || distance(wk, promo) == 1) {
+			return kpkWin
+		}
+	case p.stm == 1:
+		if !kingHasMove(bk, wk, psq) {
+			return kpkDraw // stalemate
+		}
+		if distance(bk, psq) == 1 && distance(wk, psq) > 1 {
+			return kpkDraw // pawn falls
+		}
+	}
+	return kpkUnknown
+}
+
+// kingHasMove reports whether the black king has a square not covered by the
+// white king or pawn.
+func kingHasMove(bk, wk, psq int) bool {
+	for _, to := range kingSteps(bk) {
+		if distance(to, wk) > 1 && !pawnAttacks(psq, to) {
+			return true
+		}
+	}
+	return false
+}
+
+func kpkClassify(db []uint8, stm, wk, bk, psq int) uint8 {
+	good, bad := uint8(kpkWin), uint8(kpkDraw)
+	if stm == 1 {
+		good, bad = kpkDraw, kpkWin
+	}
+
+	r := uint8(kpkInvalid)
+	if stm == 0 {
+		for _, to := range kingSteps(wk) {
+			r |= db[kpkIndex(1, bk, to, psq)]
+		}
+		if rankOf(psq) < 6 && psq+8 != wk && psq+8 != bk {
+			r |= db[kpkIndex(1, bk, wk, psq+8)]
+			if rankOf(psq) == 1 && psq+16 != wk && psq+16 != bk {
+				r |= db[kpkIndex(1, bk, wk, psq+16)]
+			}
+		}
+	} else {
+		for _, to := range kingSteps(bk) {
+			r |= db[kpkIndex(0, to, wk, psq)]
+		}
+	}
+
+	switch {
+	case r&good != 0:
+		return good
+	case r&kpkUnknown != 0:
+		return kpkUnknown
+	}
+	return bad
+}
+
+// pawnAttacks reports whether a White pawn on psq attacks sq.
+func pawnAttacks(psq, sq int) bool {
+	return rankOf(sq) == rankOf(psq)+1 && absInt(fileOf(sq)-fileOf(psq)) == 1
+}
+
+var kingStepTable [64][]int
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		r, f := rankOf(sq), fileOf(sq)
+		for dr := -1; dr <= 1; dr++ {
+			for df := -1; df <= 1; df++ {
+				if (dr != 0 || df != 0) && r+dr >= 0 && r+dr < 8 && f+df >= 0 && f+df < 8 {
+					kingStepTable[sq] = append(kingStepTable[sq], square(r+dr, f+df))
+				}
+			}
+		}
+	}
+}
+
+func kingSteps(sq int) []int {
+	return kingStepTable[sq]
+}
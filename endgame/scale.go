@@ -0,0 +1,93 @@
+// --- endgame/scale.go ---
+
+package endgame
+
+// ScaleFactor returns how much of the strong side's advantage is real, out
+// of ScaleNormal. Lower values mark endings that are hard or impossible to win.
+func (pos *Position) ScaleFactor(strong int) int {
+	weak := 1 - strong
+	npmStrong, npmWeak := pos.NonPawnMaterial(strong), pos.NonPawnMaterial(weak)
+
+	// Without pawns a minor-piece edge cannot win.
+	if pos.Count.minorEdge(strong) {
+		switch {
+		case npmStrong < RookValue:
+			return ScaleDraw
+		case npmWeak <= BishopValue:
+			return 4
+		default:
+			return 14
+		}
+	}
+
+	if sf, ok := wrongRookPawn(pos, strong); ok {
+		return sf
+	}
+
+	// Opposite-coloured bishops.
+	if pos.Count.bishopEach(strong) &&
+		squareColor(pos.Squares[strong][Bishop][0]) != squareColor(pos.Squares[weak][Bishop][0]) {
+		if npmStrong == BishopValue && npmWeak == BishopValue {
+			if pos.Count[strong][Pawn]-pos.Count[weak][Pawn] <= 1 {
+				return 16
+			}
+			return 32
+		}
+		return 40
+	}
+
+	return ScaleNormal
+}
+
+// wrongRookPawn detects K+pawns (optionally with a bishop that does not
+// control the promotion square) against a king sitting in front of it: all
+// pawns are on the same rook file and the defender reaches the corner.
+func wrongRookPawn(pos *Position, strong int) (int, bool) {
+	weak := 1 - strong
+	if !pos.Count.rookPawnMaterial(strong) {
+		return 0, false
+	}
+	npm := pos.NonPawnMaterial(strong)
+
+	file := fileOf(pos.Squares[strong][Pawn][0])
+	if file != 0 && file != 7 {
+		return 0, false
+	}
+	for _, sq := range pos.Squares[strong][Pawn] {
+		if fileOf(sq) != file {
+			return 0, false
+		}
+	}
+
+	queening := square(7, file)
+	if strong == 1 {
+		queening = square(0, file)
+	}
+	if npm != 0 && squareColor(pos.Squares[strong][Bishop][0]) == squareColor(queening) {
+		return 0, false // the right bishop wins
+	}
+	if distance(pos.king(weak), queening) <= 1 {
+		return ScaleDraw, true
+	}
+	return 0, false
+}
+
+// The material conditions of the scale factors above, which Material.Known
+// checks without a board.
+
+// minorEdge: the strong side has no pawns and at most a minor piece more.
+func (m *Material) minorEdge(strong int) bool {
+	return m[strong][Pawn] == 0 && m.nonPawn(strong)-m.nonPawn(1-strong) <= BishopValue
+}
+
+// rookPawnMaterial: pawns, perhaps with a bishop, against a bare king.
+func (m *Material) rookPawnMaterial(strong int) bool {
+	npm := m.nonPawn(strong)
+	return m[strong][Pawn] > 0 && m.nonPawn(1-strong) == 0 &&
+		(npm == 0 || npm == BishopValue && m[strong][Bishop] == 1)
+}
+
+// bishopEach: one bishop on each side.
+func (m *Material) bishopEach(strong int) bool {
+	return m[strong][Bishop] == 1 && m[1-strong][Bishop] == 1
+}
@@ -0,0 +1,152 @@
+// --- epd/epd.go ---
+
+// Package epd reads Extended Position Description records: the four
+// position fields of a FEN followed by operations such as bm, am and id,
+// as used by opening suites and test suites.
+package epd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Op is one operation: an opcode and its operands, quotes removed.
+type Op struct {
+	Code     string
+	Operands []string
+}
+
+// Position is one EPD record.
+type Position struct {
+	// FEN is the position as a full FEN; the clocks come from the hmvc and
+	// fmvn operations, or default to "0 1".
+	FEN string
+	Ops []Op
+}
+
+// Op returns the operands of the first operation with code.
+func (p Position) Op(code string) ([]string, bool) {
+	for _, op := range p.Ops {
+		if op.Code == code {
+			return op.Operands, true
+		}
+	}
+	return nil, false
+}
+
+// ID returns the id operation, or "" without one.
+func (p Position) ID() string {
+	if id, ok := p.Op("id"); ok && len(id) > 0 {
+		return id[0]
+	}
+	return ""
+}
+
+// Parse reads one record. Plain FENs, with their two clock fields and no
+// operations, are accepted as well.
+func Parse(line string) (Position, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return Position{}, fmt.Errorf("epd %q: expected 4 position fields", line)
+	}
+	rest := line
+	for range 4 {
+		rest = strings.TrimLeft(rest, " \t")
+		rest = rest[strings.IndexAny(rest+" ", " \t"):]
+	}
+
+	halfmove, fullmove := "0", "1"
+	if len(fields) == 6 && isNumber(fields[4]) && isNumber(fields[5]) {
+		halfmove, fullmove = fields[4], fields[5]
+		rest = ""
+	}
+	ops, err := parseOps(rest)
+	if err != nil {
+		return Position{}, fmt.Errorf("epd %q: %v", line, err)
+	}
+	p := Position{Ops: ops}
+	if v, ok := p.Op("hmvc"); ok && len(v) == 1 && isNumber(v[0]) {
+		halfmove = v[0]
+	}
+	if v, ok := p.Op("fmvn"); ok && len(v) == 1 && isNumber(v[0]) {
+		fullmove = v[0]
+	}
+	p.FEN = strings.Join(append(fields[:4:4], halfmove, fullmove), " ")
+	return p, nil
+}
+
+// parseOps splits "bm Nf3; id \"WAC.001\";" into operations.
+func parseOps(s string) ([]Op, error) {
+	var ops []Op
+	var words []string
+	var word strings.Builder
+	quoted, inWord := false, false
+	end := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	for _, c := range s {
+		switch {
+		case quoted:
+			if c == '"' {
+				quoted = false
+				end()
+				continue
+			}
+			word.WriteRune(c)
+		case c == '"':
+			end()
+			quoted, inWord = true, true
+		case c == ';':
+			end()
+			if len(words) > 0 {
+				ops = append(ops, Op{Code: words[0], Operands: words[1:]})
+			}
+			words = nil
+		case c == ' ' || c == '\t':
+			end()
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated string")
+	}
+	end()
+	if len(words) > 0 {
+		// The last operation may lack its semicolon.
+		ops = append(ops, Op{Code: words[0], Operands: words[1:]})
+	}
+	return ops, nil
+}
+
+// Read parses a file of records, one per line, skipping blank lines and
+// lines starting with #.
+func Read(r io.Reader) ([]Position, error) {
+	var out []Position
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := Parse(line)
+		if err != nil {
+			return out, fmt.Errorf("line %d: %v", n, err)
+		}
+		out = append(out, p)
+	}
+	return out, sc.Err()
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
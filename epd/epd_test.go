@@ -0,0 +1,61 @@
+package epd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse(`2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001"; c0 "mate in 3; not 2";`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.FEN != "2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1" {
+		t.Errorf("FEN %q", p.FEN)
+	}
+	if bm, _ := p.Op("bm"); !reflect.DeepEqual(bm, []string{"Qg6"}) {
+		t.Errorf("bm %q", bm)
+	}
+	if p.ID() != "WAC.001" {
+		t.Errorf("id %q", p.ID())
+	}
+	if c0, _ := p.Op("c0"); !reflect.DeepEqual(c0, []string{"mate in 3; not 2"}) {
+		t.Errorf("c0 %q", c0)
+	}
+
+	p, err = Parse("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 hmvc 0; fmvn 1; am d5 Nf6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.FEN != "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1" {
+		t.Errorf("FEN %q", p.FEN)
+	}
+	if am, _ := p.Op("am"); !reflect.DeepEqual(am, []string{"d5", "Nf6"}) {
+		t.Errorf("am %q", am)
+	}
+
+	// A plain FEN keeps its clocks.
+	if p, err := Parse("8/8/8/4k3/8/8/8/4K2R w K - 12 40"); err != nil || p.FEN != "8/8/8/4k3/8/8/8/4K2R w K - 12 40" || len(p.Ops) != 0 {
+		t.Errorf("plain FEN: %+v, %v", p, err)
+	}
+}
+
+func TestParseRejectsBadRecords(t *testing.T) {
+	for _, line := range []string{"8/8/8 w -", `8/8/8/8/8/8/8/8 w - - id "open`} {
+		if _, err := Parse(line); err == nil {
+			t.Errorf("Parse(%q) succeeded", line)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	suite := "# openings\n\nrnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - id \"start\";\nbad\n"
+	ps, err := Read(strings.NewReader(suite))
+	if len(ps) != 1 || ps[0].ID() != "start" {
+		t.Fatalf("read %+v", ps)
+	}
+	if err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Fatalf("got %v, want an error on line 4", err)
+	}
+}
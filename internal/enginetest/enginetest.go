@@ -0,0 +1,77 @@
+// --- internal/enginetest/enginetest.go ---
+
+// Package enginetest gives tests UCI engines to talk to: the project's
+// own engine, built once per test binary, and fakes that misbehave.
+package enginetest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeEnv turns a re-executed test binary into a fake engine.
+const fakeEnv = "MCHESS_FAKE_ENGINE"
+
+// Main runs a package's tests with the project's engine built into a
+// temporary directory and its path stored in *path. A test binary started
+// through Fake runs as the fake engine instead.
+func Main(m *testing.M, path *string) {
+	if mode := os.Getenv(fakeEnv); mode != "" {
+		fake(mode)
+		os.Exit(3)
+	}
+	dir, err := os.MkdirTemp("", "engine")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	*path = filepath.Join(dir, "engine")
+	if out, err := exec.Command("go", "build", "-o", *path, "github.com/mesb/mchess/cmd/engine").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "building the engine: %v\n%s", err, out)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// Fake returns the command of an engine that answers the handshake, then
+// misbehaves on go: "hang" never answers and "crash" dies. The mode is set
+// in t's environment, so the engine must be started during t.
+func Fake(t *testing.T, mode string) string {
+	t.Helper()
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(fakeEnv, mode)
+	return self
+}
+
+func fake(mode string) {
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		switch cmd := strings.Fields(sc.Text()); cmd[0] {
+		case "uci":
+			fmt.Println("id name Fake")
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "go":
+			if mode == "crash" {
+				fmt.Fprintln(os.Stderr, "segmentation fault")
+				os.Exit(2)
+			}
+		case "quit":
+			if mode != "hang" {
+				os.Exit(0)
+			}
+		}
+	}
+	select {}
+}
@@ -0,0 +1,116 @@
+// --- internal/protocoltest/protocoltest.go ---
+
+// Package protocoltest drives a text protocol loop, such as UCI or CECP,
+// over pipes the way a GUI does, for the protocols' tests.
+package protocoltest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Timeout bounds every wait for the engine.
+const Timeout = 10 * time.Second
+
+// Conversation talks to one protocol loop, one line at a time.
+type Conversation struct {
+	t     *testing.T
+	in    *io.PipeWriter
+	lines chan string
+	done  chan error
+}
+
+// Converse starts run on a pair of pipes. The input is closed when the
+// test ends.
+func Converse(t *testing.T, run func(in io.Reader, out io.Writer) error) *Conversation {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &Conversation{t: t, in: inW, lines: make(chan string, 4096), done: make(chan error, 1)}
+	go func() {
+		err := run(inR, outW)
+		outW.Close()
+		c.done <- err
+	}()
+	go func() {
+		sc := bufio.NewScanner(outR)
+		for sc.Scan() {
+			c.lines <- sc.Text()
+		}
+		close(c.lines)
+	}()
+	t.Cleanup(func() { inW.Close() })
+	return c
+}
+
+// Send writes each command as a line.
+func (c *Conversation) Send(cmds ...string) {
+	c.t.Helper()
+	for _, cmd := range cmds {
+		if _, err := fmt.Fprintln(c.in, cmd); err != nil {
+			c.t.Fatalf("send %q: %v", cmd, err)
+		}
+	}
+}
+
+// Expect reads lines until one starts with prefix and returns it with the
+// lines before it.
+func (c *Conversation) Expect(prefix string) (string, []string) {
+	c.t.Helper()
+	var before []string
+	timeout := time.After(Timeout)
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				c.t.Fatalf("output ended waiting for %q after %q", prefix, before)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line, before
+			}
+			before = append(before, line)
+		case <-timeout:
+			c.t.Fatalf("no %q after %q", prefix, before)
+		}
+	}
+}
+
+// Quiet fails if the engine says anything within d.
+func (c *Conversation) Quiet(d time.Duration) {
+	c.t.Helper()
+	select {
+	case line := <-c.lines:
+		c.t.Fatalf("unexpected %q", line)
+	case <-time.After(d):
+	}
+}
+
+// Close ends the input, as a GUI going away does.
+func (c *Conversation) Close() {
+	c.in.Close()
+}
+
+// Wait returns the loop's error once it ends.
+func (c *Conversation) Wait() error {
+	c.t.Helper()
+	select {
+	case err := <-c.done:
+		return err
+	case <-time.After(Timeout):
+		c.t.Fatal("the loop did not end")
+		return nil
+	}
+}
+
+// Quit sends "quit" and fails unless the loop then ends cleanly.
+func (c *Conversation) Quit() {
+	c.t.Helper()
+	c.Send("quit")
+	if err := c.Wait(); err != nil {
+		c.t.Fatalf("Run: %v", err)
+	}
+}
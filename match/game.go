@@ -0,0 +1,248 @@
+// --- match/game.go ---
+
+package match
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mesb/mchess/socrates"
+	"github.com/mesb/mchess/uci/client"
+)
+
+// PGN Termination tag values.
+const (
+	Normal          = "normal"
+	Adjudicated     = "adjudication"
+	TimeForfeit     = "time forfeit"
+	RulesInfraction = "rules infraction"
+	Abandoned       = "abandoned"
+)
+
+// mateCP stands for a mate score when comparing with adjudication limits.
+const mateCP = 100000
+
+// Adjudication ends games whose outcome is clear before the board says so.
+// Zero fields disable their rule.
+type Adjudication struct {
+	// A side whose engine scores its position at -ResignScore centipawns
+	// or worse for ResignMoves moves in a row loses.
+	ResignMoves, ResignScore int
+	// From move DrawMoveNumber on, a game where both engines score within
+	// DrawScore of zero for DrawMoves moves each in a row is drawn.
+	DrawMoveNumber, DrawMoves, DrawScore int
+	// Games reaching MaxMoves moves are drawn.
+	MaxMoves int
+}
+
+// adjudicator follows the scores of one game.
+type adjudicator struct {
+	Adjudication
+	resign [2]int // moves in a row each side saw itself lost
+	draw   int    // plies in a row both sides saw a draw
+}
+
+// add notes the score the engine of color reported for its move number n
+// and returns the result when the game is adjudicated.
+func (a *adjudicator) add(color, n int, score *client.Score) (Outcome, bool) {
+	if score == nil {
+		// Book moves and silent engines break the streaks.
+		a.resign[color], a.draw = 0, 0
+		return Outcome{}, false
+	}
+	cp := score.CP
+	switch {
+	case score.Mate > 0:
+		cp = mateCP
+	case score.Mate < 0:
+		cp = -mateCP
+	}
+
+	a.resign[color]++
+	if a.ResignMoves == 0 || cp > -a.ResignScore {
+		a.resign[color] = 0
+	}
+	if a.ResignMoves > 0 && a.resign[color] >= a.ResignMoves {
+		return winFor(1-color, Adjudicated, fmt.Sprintf("%s wins by adjudication", colorName(1-color))), true
+	}
+
+	a.draw++
+	if a.DrawMoves == 0 || n < a.DrawMoveNumber || abs(cp) > a.DrawScore {
+		a.draw = 0
+	}
+	if a.DrawMoves > 0 && a.draw >= 2*a.DrawMoves {
+		return Outcome{Result: "1/2-1/2", Termination: Adjudicated, Reason: "Draw by adjudication"}, true
+	}
+	return Outcome{}, false
+}
+
+// Outcome is how a game ended.
+type Outcome struct {
+	Result      string // "1-0", "0-1" or "1/2-1/2"
+	Termination string
+	Reason      string // e.g. "White mates", "Black loses on time"
+}
+
+// Points is what color scored: 1, 0.5 or 0.
+func (o Outcome) Points(color int) float64 {
+	white := 0.5
+	switch o.Result {
+	case "1-0":
+		white = 1
+	case "0-1":
+		white = 0
+	}
+	if color == 1 {
+		return 1 - white
+	}
+	return white
+}
+
+func winFor(color int, termination, reason string) Outcome {
+	if color == 0 {
+		return Outcome{Result: "1-0", Termination: termination, Reason: reason}
+	}
+	return Outcome{Result: "0-1", Termination: termination, Reason: reason}
+}
+
+// boardOutcome names the end of a game decided on the board.
+func boardOutcome(eng *socrates.RuleEngine) (Outcome, bool) {
+	if _, over := eng.GameResult(); !over {
+		return Outcome{}, false
+	}
+	switch {
+	case eng.IsCheckmate():
+		return winFor(1-eng.Turn, Normal, colorName(1-eng.Turn)+" mates"), true
+	case eng.IsStalemate():
+		return Outcome{"1/2-1/2", Normal, "Draw by stalemate"}, true
+	case eng.IsFiftyMoveRule():
+		return Outcome{"1/2-1/2", Normal, "Draw by fifty moves rule"}, true
+	case eng.IsInsufficientMaterial():
+		return Outcome{"1/2-1/2", Normal, "Draw by insufficient mating material"}, true
+	}
+	return Outcome{"1/2-1/2", Normal, "Draw by 3-fold repetition"}, true
+}
+
+// Game is one game of a match.
+type Game struct {
+	Round        int // from 1, in the order the games were scheduled
+	White, Black string
+	Opening      Opening
+	Moves        []string // played after the opening, in coordinate notation
+	Comments     []string // for every move: score/depth and time used
+	Outcome
+	Start time.Time
+}
+
+// play plays g between players, White first, returning an error only when
+// ctx ends the game.
+func play(ctx context.Context, g *Game, players [2]*client.Engine, tc TimeControl, adj Adjudication, margin time.Duration) error {
+	g.Start = time.Now()
+	eng, err := g.Opening.engine()
+	if err != nil {
+		return err
+	}
+	for color, p := range players {
+		if err := p.NewGame(); err != nil {
+			g.Outcome = engineFailure(color, err)
+			return nil
+		}
+	}
+	clk := newClock(tc)
+	a := adjudicator{Adjudication: adj}
+	var played [2]int
+	for {
+		if o, over := boardOutcome(eng); over {
+			g.Outcome = o
+			return nil
+		}
+		if adj.MaxMoves > 0 && len(g.Moves) >= 2*adj.MaxMoves {
+			g.Outcome = Outcome{"1/2-1/2", Adjudicated, "Draw by adjudication: move limit"}
+			return nil
+		}
+
+		color := eng.Turn
+		p := players[color]
+		n := played[color] + 1
+		if err := p.Position(g.Opening.FEN, append(g.Opening.Moves[:len(g.Opening.Moves):len(g.Opening.Moves)], g.Moves...)...); err != nil {
+			g.Outcome = engineFailure(color, err)
+			return nil
+		}
+		start := time.Now()
+		res, err := p.Go(ctx, clk.limits(color, n), nil)
+		elapsed := time.Since(start)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch {
+		case errors.Is(err, client.ErrTimeout):
+			g.Outcome = winFor(1-color, TimeForfeit, colorName(color)+" loses on time")
+			return nil
+		case err != nil:
+			g.Outcome = engineFailure(color, err)
+			return nil
+		}
+		played[color] = n
+		if !clk.punch(color, n, elapsed, margin) {
+			g.Outcome = winFor(1-color, TimeForfeit, colorName(color)+" loses on time")
+			return nil
+		}
+
+		from, to, promo, err := socrates.ParseMove(res.Move)
+		if err != nil || !eng.MakeMove(*from, *to, promo) {
+			g.Outcome = winFor(1-color, RulesInfraction, fmt.Sprintf("%s makes an illegal move: %s", colorName(color), res.Move))
+			return nil
+		}
+		g.Moves = append(g.Moves, res.Move)
+		g.Comments = append(g.Comments, comment(res.Info, elapsed))
+
+		if o, over := a.add(color, eng.State.FullmoveNumber, res.Info.Score); over {
+			g.Outcome = o
+			return nil
+		}
+	}
+}
+
+// engineFailure loses the game for the side whose engine stopped working.
+func engineFailure(color int, err error) Outcome {
+	reason := colorName(color) + "'s connection stalls"
+	if errors.Is(err, client.ErrExited) {
+		reason = colorName(color) + "'s engine crashed"
+	}
+	return winFor(1-color, Abandoned, reason)
+}
+
+// comment notes a move's score and depth and the time it took, as
+// cutechess does: "+0.35/12 0.52s".
+func comment(info client.Info, elapsed time.Duration) string {
+	var b strings.Builder
+	switch s := info.Score; {
+	case s == nil:
+		b.WriteString("book")
+	case s.Mate > 0:
+		fmt.Fprintf(&b, "+M%d/%d", s.Mate, info.Depth)
+	case s.Mate < 0:
+		fmt.Fprintf(&b, "-M%d/%d", -s.Mate, info.Depth)
+	default:
+		fmt.Fprintf(&b, "%+.2f/%d", float64(s.CP)/100, info.Depth)
+	}
+	fmt.Fprintf(&b, " %.2fs", elapsed.Seconds())
+	return b.String()
+}
+
+func colorName(color int) string {
+	if color == 0 {
+		return "White"
+	}
+	return "Black"
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
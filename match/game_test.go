@@ -0,0 +1,130 @@
+package match
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesb/mchess/uci/client"
+)
+
+func TestAdjudicator(t *testing.T) {
+	cp := func(n int) *client.Score { return &client.Score{CP: n} }
+	a := adjudicator{Adjudication: Adjudication{ResignMoves: 2, ResignScore: 500}}
+	a.add(0, 10, cp(-600))
+	a.add(1, 10, cp(600))
+	a.add(0, 11, cp(-100)) // the streak breaks
+	a.add(1, 11, cp(600))
+	a.add(0, 12, cp(-600))
+	a.add(1, 12, cp(600))
+	o, over := a.add(0, 13, &client.Score{Mate: -4})
+	if !over || o.Result != "0-1" || o.Termination != Adjudicated {
+		t.Fatalf("resignation: %+v, %v", o, over)
+	}
+
+	a = adjudicator{Adjudication: Adjudication{DrawMoveNumber: 20, DrawMoves: 2, DrawScore: 10}}
+	for n := 18; n < 20; n++ {
+		for color := range 2 {
+			if _, over := a.add(color, n, cp(0)); over {
+				t.Fatalf("drawn before move %d", a.DrawMoveNumber)
+			}
+		}
+	}
+	a.add(0, 20, cp(5))
+	a.add(1, 20, cp(-3))
+	a.add(0, 21, cp(0))
+	if o, over := a.add(1, 21, cp(8)); !over || o.Result != "1/2-1/2" {
+		t.Fatalf("draw: %+v, %v", o, over)
+	}
+}
+
+func TestReadOpenings(t *testing.T) {
+	dir := t.TempDir()
+	pgnPath := filepath.Join(dir, "o.pgn")
+	pgn := `[Event "a"]
+[Opening "King's pawn"]
+
+1. e4 {main line} e5 (1... c5) 2. Nf3 Nc6 *
+
+[Event "b"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"]
+
+1. e2e4 e8d7 1/2-1/2
+`
+	if err := os.WriteFile(pgnPath, []byte(pgn), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := ReadOpenings(pgnPath, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Opening{
+		{Name: "King's pawn", Moves: []string{"e2e4", "e7e5", "g1f3"}},
+		{FEN: "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", Moves: []string{"e2e4", "e8d7"}},
+	}
+	if !reflect.DeepEqual(ops, want) {
+		t.Fatalf("got %+v, want %+v", ops, want)
+	}
+
+	epdPath := filepath.Join(dir, "o.epd")
+	if err := os.WriteFile(epdPath, []byte("4k3/8/8/8/8/8/8/4K3 w - - id \"bare\";\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadOpenings(epdPath, 0); err == nil || !strings.Contains(err.Error(), "over") {
+		t.Fatalf("a drawn opening was accepted: %v", err)
+	}
+	if err := os.WriteFile(pgnPath, []byte("1. e2e4 e7e6 2. e4e6 *\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadOpenings(pgnPath, 0); err == nil || !strings.Contains(err.Error(), `"e4e6": no such legal move`) {
+		t.Fatalf("an illegal opening was accepted: %v", err)
+	}
+}
+
+func TestWritePGN(t *testing.T) {
+	g := Game{
+		Round: 3, White: "dev", Black: "base",
+		Opening:  Opening{FEN: "4k3/8/8/8/8/8/4P3/4K3 b - - 0 12", Moves: []string{"e8d7"}},
+		Moves:    []string{"e2e4", "d7d6"},
+		Comments: []string{"+0.50/9 0.10s", "-0.40/8 0.20s"},
+		Outcome:  Outcome{Result: "1/2-1/2", Termination: Adjudicated, Reason: "Draw by adjudication"},
+		Start:    time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+	}
+	var b strings.Builder
+	if err := WritePGN(&b, g, "Test", TimeControl{Base: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`[Date "2026.10.18"]`, `[Round "3"]`, `[Result "1/2-1/2"]`, `[SetUp "1"]`, `[PlyCount "3"]`,
+		`[TimeControl "60"]`, `[Termination "adjudication"]`,
+		`[Site "?"]`,
+		"12... Kd7 13. e4 {+0.50/9 0.10s} 13... Kd6 {-0.40/8 0.20s} {Draw by adjudication} 1/2-1/2",
+	} {
+		if !strings.Contains(strings.ReplaceAll(out, "\n", " "), want) {
+			t.Errorf("PGN lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestParseEngineConfig(t *testing.T) {
+	c, err := ParseEngineConfig(`name=dev cmd=./engine arg=-evalparams arg=p.json "option.Move Overhead=50" option.Hash=64`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := EngineConfig{
+		Name: "dev", Cmd: "./engine", Args: []string{"-evalparams", "p.json"},
+		Options: []Setting{{"Move Overhead", "50"}, {"Hash", "64"}},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Fatalf("got %+v, want %+v", c, want)
+	}
+	for _, bad := range []string{"cmd", "colour=red", `name="open`} {
+		if _, err := ParseEngineConfig(bad); err == nil {
+			t.Errorf("ParseEngineConfig(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+// --- match/match.go ---
+
+// Package match plays engines against each other to measure strength:
+// colour-swapped pairs of games from an opening suite under a time
+// control, with adjudication, several games at once, and running Elo and
+// SPRT statistics.
+package match
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mesb/mchess/uci/client"
+)
+
+// EngineConfig says how to start an engine.
+type EngineConfig struct {
+	// Name overrides the name the engine gives; it tells apart two
+	// configurations of the same engine.
+	Name    string
+	Cmd     string
+	Args    []string
+	Options []Setting // set in order after the handshake
+}
+
+// Setting is a UCI option value.
+type Setting struct {
+	Name, Value string
+}
+
+// start launches the engine and sets its options.
+func (c EngineConfig) start() (*client.Engine, error) {
+	e, err := client.Start(c.Cmd, c.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", c.label(), err)
+	}
+	for _, s := range c.Options {
+		if err := e.SetOption(s.Name, s.Value); err != nil {
+			e.Close()
+			return nil, fmt.Errorf("%s: %v", c.label(), err)
+		}
+	}
+	return e, nil
+}
+
+func (c EngineConfig) label() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Cmd
+}
+
+// Match is a tournament between two or more engines: every pair of them
+// plays Rounds pairs of games, each pair from the same opening with the
+// colours swapped. The first engine's score against the second is what
+// Elo and SPRT statistics measure.
+type Match struct {
+	Engines      []EngineConfig
+	Openings     []Opening // used in order, cycling; none means the start position
+	Rounds       int
+	TimeControl  TimeControl
+	TimeMargin   time.Duration // grace before a flag falls
+	Adjudication Adjudication
+	Concurrency  int   // games played at once; at least one
+	SPRT         *SPRT // when set, the match ends once the test decides
+
+	// OnGame receives every finished game, in the order they finish,
+	// with the standings it led to.
+	OnGame func(Game, *Standings)
+}
+
+// Standings is the state of a match.
+type Standings struct {
+	Names  []string
+	Scores []Score // of every engine against all others
+	// HeadToHead is the first engine's score against the second.
+	HeadToHead Score
+	Verdict    Verdict
+}
+
+// job is one game to play: the engines by index, White first.
+type job struct {
+	round   int
+	players [2]int
+	opening Opening
+}
+
+type played struct {
+	game Game
+	job  job
+	err  error
+}
+
+// Run plays the match until every game is played, the SPRT decides or
+// ctx ends.
+func (m *Match) Run(ctx context.Context) (*Standings, error) {
+	if len(m.Engines) < 2 {
+		return nil, errors.New("a match needs two engines")
+	}
+	if !m.TimeControl.limited() {
+		return nil, errors.New("the time control sets no limit: give a clock, move time, depth or node count")
+	}
+	names, err := m.names()
+	if err != nil {
+		return nil, err
+	}
+	st := &Standings{Names: names, Scores: make([]Score, len(m.Engines))}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan job)
+	results := make(chan played)
+	go func() {
+		defer close(jobs)
+		for _, j := range m.schedule() {
+			select {
+			case jobs <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	workers := max(m.Concurrency, 1)
+	for range workers {
+		go m.work(ctx, jobs, results, names)
+	}
+
+	var firstErr error
+	for running := workers; running > 0; {
+		r := <-results
+		switch {
+		case r.err == errDone:
+			running--
+			continue
+		case r.err != nil:
+			if firstErr == nil && ctx.Err() == nil {
+				firstErr = r.err
+			}
+			cancel()
+			continue
+		}
+		st.add(r.job, r.game)
+		if m.SPRT != nil {
+			st.Verdict = m.SPRT.Verdict(st.HeadToHead)
+			if st.Verdict != Continue {
+				cancel()
+			}
+		}
+		if m.OnGame != nil {
+			m.OnGame(r.game, st)
+		}
+	}
+	return st, firstErr
+}
+
+// errDone tells Run a worker has finished.
+var errDone = errors.New("done")
+
+// work plays games until jobs runs out, keeping one process of every
+// engine and restarting the ones that failed.
+func (m *Match) work(ctx context.Context, jobs <-chan job, results chan<- played, names []string) {
+	engines := make([]*client.Engine, len(m.Engines))
+	defer func() {
+		for _, e := range engines {
+			if e != nil {
+				e.Close()
+			}
+		}
+		results <- played{err: errDone}
+	}()
+	for j := range jobs {
+		var players [2]*client.Engine
+		for color, i := range j.players {
+			if engines[i] != nil && engines[i].IsReady() != nil {
+				engines[i].Close()
+				engines[i] = nil
+			}
+			if engines[i] == nil {
+				e, err := m.Engines[i].start()
+				if err != nil {
+					results <- played{err: err}
+					return
+				}
+				engines[i] = e
+			}
+			players[color] = engines[i]
+		}
+		g := Game{Round: j.round, White: names[j.players[0]], Black: names[j.players[1]], Opening: j.opening}
+		if err := play(ctx, &g, players, m.TimeControl, m.Adjudication, m.TimeMargin); err != nil {
+			if ctx.Err() == nil {
+				results <- played{err: err}
+			}
+			return
+		}
+		results <- played{game: g, job: j}
+	}
+}
+
+// schedule lists the games: round by round, every pairing plays both
+// colours of the round's opening.
+func (m *Match) schedule() []job {
+	openings := m.Openings
+	if len(openings) == 0 {
+		openings = []Opening{{}}
+	}
+	var jobs []job
+	for r := range m.Rounds {
+		o := openings[r%len(openings)]
+		for a := range m.Engines {
+			for b := a + 1; b < len(m.Engines); b++ {
+				jobs = append(jobs,
+					job{round: len(jobs) + 1, players: [2]int{a, b}, opening: o},
+					job{round: len(jobs) + 2, players: [2]int{b, a}, opening: o})
+			}
+		}
+	}
+	return jobs
+}
+
+// names settles the engines' names, asking the ones without a configured
+// name for theirs.
+func (m *Match) names() ([]string, error) {
+	names := make([]string, len(m.Engines))
+	seen := map[string]bool{}
+	for i, c := range m.Engines {
+		names[i] = c.Name
+		if names[i] == "" {
+			e, err := c.start()
+			if err != nil {
+				return nil, err
+			}
+			names[i] = e.Name
+			e.Close()
+		}
+		if seen[names[i]] {
+			return nil, fmt.Errorf("two engines are called %q; name them apart", names[i])
+		}
+		seen[names[i]] = true
+	}
+	return names, nil
+}
+
+// add counts a finished game.
+func (s *Standings) add(j job, g Game) {
+	for color, i := range j.players {
+		points := g.Points(color)
+		s.Scores[i].add(points)
+		if i == 0 && j.players[1-color] == 1 {
+			s.HeadToHead.add(points)
+		}
+	}
+}
+
+// ParseEngineConfig reads an engine description of key=value fields:
+// name=, cmd=, arg= (repeatable) and option.<Name>=<value>. Fields with
+// spaces are double-quoted, e.g. `cmd=./sf "option.Move Overhead=50"`.
+func ParseEngineConfig(spec string) (EngineConfig, error) {
+	var c EngineConfig
+	words, err := splitQuoted(spec)
+	if err != nil {
+		return c, fmt.Errorf("engine %q: %v", spec, err)
+	}
+	for _, w := range words {
+		key, value, ok := strings.Cut(w, "=")
+		if !ok {
+			return c, fmt.Errorf("engine %q: %q is not key=value", spec, w)
+		}
+		switch {
+		case key == "name":
+			c.Name = value
+		case key == "cmd":
+			c.Cmd = value
+		case key == "arg":
+			c.Args = append(c.Args, value)
+		case strings.HasPrefix(key, "option."):
+			c.Options = append(c.Options, Setting{Name: strings.TrimPrefix(key, "option."), Value: value})
+		default:
+			return c, fmt.Errorf("engine %q: unknown field %q", spec, key)
+		}
+	}
+	return c, nil
+}
+
+func splitQuoted(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	quoted, inWord := false, false
+	for _, c := range s {
+		switch {
+		case c == '"':
+			quoted, inWord = !quoted, true
+		case !quoted && (c == ' ' || c == '\t'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,77 @@
+package match
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/mesb/mchess/internal/enginetest"
+)
+
+// enginePath is the project's own engine, built once for the tests.
+var enginePath string
+
+func TestMain(m *testing.M) {
+	enginetest.Main(m, &enginePath)
+}
+
+func builtin(name string) EngineConfig {
+	return EngineConfig{Name: name, Cmd: enginePath, Options: []Setting{{"OwnBook", "false"}}}
+}
+
+func TestMatchPlaysColourSwappedPairs(t *testing.T) {
+	var mu sync.Mutex
+	var games []Game
+	m := &Match{
+		Engines:      []EngineConfig{builtin("dev"), builtin("base")},
+		Openings:     []Opening{{Moves: []string{"e2e4"}}, {Moves: []string{"d2d4"}}},
+		Rounds:       2,
+		TimeControl:  TimeControl{Depth: 1},
+		Adjudication: Adjudication{MaxMoves: 6},
+		Concurrency:  2,
+		OnGame: func(g Game, _ *Standings) {
+			mu.Lock()
+			games = append(games, g)
+			mu.Unlock()
+		},
+	}
+	st, err := m.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 4 || st.HeadToHead.Games() != 4 || st.Scores[1].Games() != 4 {
+		t.Fatalf("%d games reported, standings %+v", len(games), st)
+	}
+	byRound := map[int]Game{}
+	for _, g := range games {
+		byRound[g.Round] = g
+		if g.Result == "" || len(g.Moves) == 0 || len(g.Comments) != len(g.Moves) {
+			t.Errorf("game %d: %+v", g.Round, g)
+		}
+	}
+	for r := 1; r <= 3; r += 2 {
+		a, b := byRound[r], byRound[r+1]
+		if a.White != b.Black || a.Black != b.White || a.Opening.Moves[0] != b.Opening.Moves[0] {
+			t.Errorf("games %d and %d are not a colour-swapped pair: %s-%s %v, %s-%s %v",
+				r, r+1, a.White, a.Black, a.Opening.Moves, b.White, b.Black, b.Opening.Moves)
+		}
+	}
+	if byRound[1].Opening.Moves[0] == byRound[3].Opening.Moves[0] {
+		t.Error("the second round replayed the first opening")
+	}
+}
+
+func TestCrashedEngineLosesAndIsRestarted(t *testing.T) {
+	m := &Match{
+		Engines:     []EngineConfig{builtin("dev"), {Name: "crasher", Cmd: enginetest.Fake(t, "crash")}},
+		Rounds:      1,
+		TimeControl: TimeControl{Depth: 1},
+	}
+	st, err := m.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.HeadToHead != (Score{Wins: 2}) {
+		t.Fatalf("head to head %v, want two wins for dev", st.HeadToHead)
+	}
+}
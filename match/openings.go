@@ -0,0 +1,104 @@
+// --- match/openings.go ---
+
+package match
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/epd"
+	"github.com/mesb/mchess/pgn"
+	"github.com/mesb/mchess/socrates"
+)
+
+// Opening is where a pair of games starts: a position, or the standard
+// start position when FEN is empty, and the moves played from there.
+type Opening struct {
+	Name  string
+	FEN   string
+	Moves []string // coordinate notation
+}
+
+// ReadOpenings loads an opening suite: EPD positions (.epd or .fen) or
+// the first plies of the main lines of PGN games (.pgn; plies <= 0 keeps
+// whole games).
+func ReadOpenings(path string, plies int) ([]Opening, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ops []Opening
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pgn":
+		ops, err = readPGNOpenings(f, plies)
+	default:
+		var ps []epd.Position
+		ps, err = epd.Read(f)
+		for _, p := range ps {
+			ops = append(ops, Opening{Name: p.ID(), FEN: p.FEN})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for i, o := range ops {
+		if _, err := o.engine(); err != nil {
+			return nil, fmt.Errorf("%s: opening %d: %v", path, i+1, err)
+		}
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("%s: no openings", path)
+	}
+	return ops, nil
+}
+
+// engine sets the opening up on a new engine.
+func (o Opening) engine() (*socrates.RuleEngine, error) {
+	b, s := board.InitStandard(), board.NewGameState()
+	if o.FEN != "" {
+		var err error
+		if b, s, err = board.FromFEN(o.FEN); err != nil {
+			return nil, err
+		}
+	}
+	eng := socrates.New(b)
+	eng.SetPosition(b, s)
+	for _, mv := range o.Moves {
+		from, to, promo, err := socrates.ParseMove(mv)
+		if err != nil || !eng.MakeMove(*from, *to, promo) {
+			return nil, fmt.Errorf("illegal move %s", mv)
+		}
+	}
+	if _, over := eng.GameResult(); over {
+		return nil, fmt.Errorf("the game is already over")
+	}
+	return eng, nil
+}
+
+// readPGNOpenings takes the FEN tag and the first plies of every game.
+func readPGNOpenings(r io.Reader, plies int) ([]Opening, error) {
+	games, err := pgn.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("game %d: %v", len(games)+1, err)
+	}
+	ops := make([]Opening, len(games))
+	for i, g := range games {
+		ops[i] = Opening{Name: g.Tag("Opening"), FEN: g.Tag("FEN")}
+		if ops[i].Name == "" {
+			ops[i].Name = g.Tag("ECO")
+		}
+		for _, m := range g.Moves {
+			if plies > 0 && len(ops[i].Moves) >= plies {
+				break
+			}
+			ops[i].Moves = append(ops[i].Moves, m.SimpleMove.String())
+		}
+	}
+	return ops, nil
+}
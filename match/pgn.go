@@ -0,0 +1,62 @@
+// --- match/pgn.go ---
+
+package match
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/mesb/mchess/notation"
+	"github.com/mesb/mchess/pgn"
+	"github.com/mesb/mchess/socrates"
+)
+
+// WritePGN writes g as an export-format PGN game in SAN, the engines'
+// scores and times as move comments.
+func WritePGN(w io.Writer, g Game, event string, tc TimeControl) error {
+	eng, err := Opening{FEN: g.Opening.FEN}.engine()
+	if err != nil {
+		return err
+	}
+	all := append(g.Opening.Moves[:len(g.Opening.Moves):len(g.Opening.Moves)], g.Moves...)
+	moves := make([]socrates.SimpleMove, len(all))
+	for i, mv := range all {
+		from, to, promo, err := socrates.ParseMove(mv)
+		if err != nil {
+			return fmt.Errorf("move %s: %v", mv, err)
+		}
+		moves[i] = socrates.SimpleMove{From: *from, To: *to, Promo: promo}
+	}
+	sans, err := notation.Line(eng, moves)
+	if err != nil {
+		return err
+	}
+
+	out := &pgn.Game{Result: g.Result}
+	for i, m := range moves {
+		pm := pgn.Move{SimpleMove: m, SAN: sans[i]}
+		if j := i - len(g.Opening.Moves); j >= 0 && g.Comments[j] != "" {
+			pm.Comments = []string{g.Comments[j]}
+		}
+		out.Moves = append(out.Moves, pm)
+	}
+	if g.Reason != "" && len(out.Moves) > 0 {
+		last := &out.Moves[len(out.Moves)-1]
+		last.Comments = append(last.Comments, g.Reason)
+	}
+
+	out.SetTag("Event", event)
+	out.SetTag("Date", g.Start.Format("2006.01.02"))
+	out.SetTag("Round", strconv.Itoa(g.Round))
+	out.SetTag("White", g.White)
+	out.SetTag("Black", g.Black)
+	if g.Opening.FEN != "" {
+		out.SetTag("SetUp", "1")
+		out.SetTag("FEN", g.Opening.FEN)
+	}
+	out.SetTag("PlyCount", strconv.Itoa(len(moves)))
+	out.SetTag("TimeControl", tc.String())
+	out.SetTag("Termination", g.Termination)
+	return pgn.Write(w, out)
+}
@@ -0,0 +1,144 @@
+// --- match/stats.go ---
+
+package match
+
+import (
+	"fmt"
+	"math"
+)
+
+// Score counts the games of one side of a match.
+type Score struct {
+	Wins, Draws, Losses int
+}
+
+// Games is the number of finished games.
+func (s Score) Games() int { return s.Wins + s.Draws + s.Losses }
+
+// Ratio is the points scored per game, from 0 to 1.
+func (s Score) Ratio() float64 {
+	if s.Games() == 0 {
+		return 0.5
+	}
+	return (float64(s.Wins) + float64(s.Draws)/2) / float64(s.Games())
+}
+
+// add counts one game by its result from this side: 1, 0.5 or 0.
+func (s *Score) add(points float64) {
+	switch points {
+	case 1:
+		s.Wins++
+	case 0:
+		s.Losses++
+	default:
+		s.Draws++
+	}
+}
+
+// variance is the per-game variance of the points scored.
+func (s Score) variance() float64 {
+	n := float64(s.Games())
+	r := s.Ratio()
+	w, d, l := float64(s.Wins)/n, float64(s.Draws)/n, float64(s.Losses)/n
+	return w*(1-r)*(1-r) + d*(0.5-r)*(0.5-r) + l*r*r
+}
+
+// Elo estimates the rating difference the score shows, with the margin
+// of its 95% confidence interval. Both are infinite while one side has
+// scored everything.
+func (s Score) Elo() (elo, margin float64) {
+	if s.Games() == 0 {
+		return 0, math.Inf(1)
+	}
+	r := s.Ratio()
+	dev := 1.959964 * math.Sqrt(s.variance()/float64(s.Games()))
+	return eloFromRatio(r), (eloFromRatio(r+dev) - eloFromRatio(r-dev)) / 2
+}
+
+// LOS is the likelihood of superiority: the chance, from wins and losses
+// alone, that this side is the stronger one.
+func (s Score) LOS() float64 {
+	decisive := float64(s.Wins + s.Losses)
+	if decisive == 0 {
+		return 0.5
+	}
+	return 0.5 * (1 + math.Erf(float64(s.Wins-s.Losses)/math.Sqrt(2*decisive)))
+}
+
+func (s Score) String() string {
+	return fmt.Sprintf("%d - %d - %d  [%.3f] %d", s.Wins, s.Losses, s.Draws, s.Ratio(), s.Games())
+}
+
+func eloFromRatio(r float64) float64 {
+	switch {
+	case r <= 0:
+		return math.Inf(-1)
+	case r >= 1:
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/r-1)
+}
+
+func ratioFromElo(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// SPRT is a sequential probability ratio test of H0, that the first
+// engine is Elo0 stronger than the second, against H1, that it is Elo1
+// stronger, with false positive rate Alpha and false negative rate Beta.
+type SPRT struct {
+	Elo0, Elo1  float64
+	Alpha, Beta float64
+}
+
+// Verdict is the state of an SPRT.
+type Verdict int
+
+const (
+	Continue Verdict = iota // neither hypothesis is accepted yet
+	AcceptH0                // fail: the change is not Elo1 better
+	AcceptH1                // pass
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case AcceptH0:
+		return "H0 accepted"
+	case AcceptH1:
+		return "H1 accepted"
+	}
+	return "continue"
+}
+
+// Bounds are the log-likelihood ratios at which H0 and H1 are accepted.
+func (t SPRT) Bounds() (lower, upper float64) {
+	return math.Log(t.Beta / (1 - t.Alpha)), math.Log((1 - t.Beta) / t.Alpha)
+}
+
+// LLR is the log-likelihood ratio of H1 to H0 for s, in the normal
+// approximation of the generalized SPRT on game results.
+func (t SPRT) LLR(s Score) float64 {
+	if s.Games() == 0 {
+		return 0
+	}
+	v := s.variance()
+	if v == 0 {
+		// All results alike: too early to tell.
+		return 0
+	}
+	s0, s1 := ratioFromElo(t.Elo0), ratioFromElo(t.Elo1)
+	return float64(s.Games()) * (s1 - s0) * (2*s.Ratio() - s0 - s1) / (2 * v)
+}
+
+// Verdict tests s.
+func (t SPRT) Verdict(s Score) Verdict {
+	llr := t.LLR(s)
+	lower, upper := t.Bounds()
+	switch {
+	case llr >= upper:
+		return AcceptH1
+	case llr <= lower:
+		return AcceptH0
+	}
+	return Continue
+}
@@ -0,0 +1,61 @@
+package match
+
+import (
+	"math"
+	"testing"
+)
+
+func TestElo(t *testing.T) {
+	elo, margin := Score{Wins: 50, Draws: 0, Losses: 50}.Elo()
+	if elo != 0 || margin <= 0 {
+		t.Errorf("even score: %.1f +/- %.1f", elo, margin)
+	}
+	elo, _ = Score{Wins: 60, Draws: 30, Losses: 10}.Elo()
+	if math.Abs(elo-190.8) > 0.1 {
+		t.Errorf("75%%: Elo %.1f, want 190.8", elo)
+	}
+	_, wide := Score{Wins: 6, Draws: 3, Losses: 1}.Elo()
+	_, narrow := Score{Wins: 600, Draws: 300, Losses: 100}.Elo()
+	if narrow >= wide {
+		t.Errorf("100 times the games narrowed the margin from %.1f only to %.1f", wide, narrow)
+	}
+	if elo, _ := (Score{Wins: 3}).Elo(); !math.IsInf(elo, 1) {
+		t.Errorf("a clean sweep gave %.1f", elo)
+	}
+}
+
+func TestLOS(t *testing.T) {
+	if los := (Score{Wins: 10, Draws: 80, Losses: 10}).LOS(); los != 0.5 {
+		t.Errorf("even LOS %.3f", los)
+	}
+	if los := (Score{Wins: 60, Losses: 40}).LOS(); math.Abs(los-0.9772) > 0.001 {
+		t.Errorf("60-40 LOS %.4f, want 0.9772", los)
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	sprt := SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}
+	lower, upper := sprt.Bounds()
+	if math.Abs(lower+2.944) > 0.001 || math.Abs(upper-2.944) > 0.001 {
+		t.Fatalf("bounds %.3f %.3f, want -2.944 2.944", lower, upper)
+	}
+	tests := []struct {
+		s    Score
+		want Verdict
+	}{
+		{Score{}, Continue},
+		{Score{Wins: 10, Draws: 10, Losses: 10}, Continue},
+		{Score{Wins: 600, Draws: 800, Losses: 400}, AcceptH1},
+		{Score{Wins: 400, Draws: 800, Losses: 600}, AcceptH0},
+		{Score{Draws: 500}, Continue},
+	}
+	for _, tt := range tests {
+		if got := sprt.Verdict(tt.s); got != tt.want {
+			t.Errorf("%v: %v (llr %.2f), want %v", tt.s, got, sprt.LLR(tt.s), tt.want)
+		}
+	}
+	// A score halfway between the hypotheses favours neither.
+	if llr := (SPRT{Elo0: -10, Elo1: 10, Alpha: 0.05, Beta: 0.05}).LLR(Score{Wins: 30, Draws: 40, Losses: 30}); math.Abs(llr) > 1e-9 {
+		t.Errorf("llr %.3f, want 0", llr)
+	}
+}
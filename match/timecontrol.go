@@ -0,0 +1,137 @@
+// --- match/timecontrol.go ---
+
+package match
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mesb/mchess/uci/client"
+)
+
+// TimeControl limits the engines' thinking. Base and Inc make a clock,
+// refilled every Moves moves when Moves is set; MoveTime, Depth and Nodes
+// limit each move instead.
+type TimeControl struct {
+	Moves     int
+	Base, Inc time.Duration
+	MoveTime  time.Duration
+	Depth     int
+	Nodes     int
+}
+
+// ParseTimeControl reads the cutechess notation "moves/base+inc" with
+// times in seconds: "10+0.1", "40/60", "40/120+1". "inf" has no clock, so
+// a match on it needs a MoveTime, Depth or Nodes limit.
+func ParseTimeControl(s string) (TimeControl, error) {
+	var tc TimeControl
+	if s == "inf" || s == "" {
+		return tc, nil
+	}
+	rest := s
+	if moves, clock, ok := strings.Cut(s, "/"); ok {
+		n, err := strconv.Atoi(moves)
+		if err != nil || n <= 0 {
+			return tc, fmt.Errorf("time control %q: bad move count %q", s, moves)
+		}
+		tc.Moves, rest = n, clock
+	}
+	base, inc, _ := strings.Cut(rest, "+")
+	var err error
+	if tc.Base, err = parseSeconds(base); err != nil || tc.Base <= 0 {
+		return tc, fmt.Errorf("time control %q: bad base time %q", s, base)
+	}
+	if inc != "" {
+		if tc.Inc, err = parseSeconds(inc); err != nil {
+			return tc, fmt.Errorf("time control %q: bad increment %q", s, inc)
+		}
+	}
+	return tc, nil
+}
+
+// parseSeconds reads "90", "2.5" or "1:30".
+func parseSeconds(s string) (time.Duration, error) {
+	var d time.Duration
+	if m, sec, ok := strings.Cut(s, ":"); ok {
+		n, err := strconv.Atoi(m)
+		if err != nil {
+			return 0, err
+		}
+		d, s = time.Duration(n)*time.Minute, sec
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("bad time %q", s)
+	}
+	return d + time.Duration(f*float64(time.Second)), nil
+}
+
+// Timed reports whether the engines play on a clock.
+func (tc TimeControl) Timed() bool { return tc.Base > 0 }
+
+// limited reports whether every search ends on its own: an engine sent
+// no limit at all searches until stopped.
+func (tc TimeControl) limited() bool {
+	return tc.Timed() || tc.MoveTime > 0 || tc.Depth > 0 || tc.Nodes > 0
+}
+
+// String renders the control as a PGN TimeControl tag value.
+func (tc TimeControl) String() string {
+	switch {
+	case tc.Timed():
+		s := strconv.FormatFloat(tc.Base.Seconds(), 'f', -1, 64)
+		if tc.Inc > 0 {
+			s += "+" + strconv.FormatFloat(tc.Inc.Seconds(), 'f', -1, 64)
+		}
+		if tc.Moves > 0 {
+			s = strconv.Itoa(tc.Moves) + "/" + s
+		}
+		return s
+	case tc.MoveTime > 0:
+		return "1/" + strconv.FormatFloat(tc.MoveTime.Seconds(), 'f', -1, 64)
+	}
+	return "-"
+}
+
+// clock keeps both sides' time in a game.
+type clock struct {
+	tc   TimeControl
+	left [2]time.Duration
+}
+
+func newClock(tc TimeControl) *clock {
+	return &clock{tc: tc, left: [2]time.Duration{tc.Base, tc.Base}}
+}
+
+// limits are the go parameters for color's move number n (from 1, counted
+// from the start of the game).
+func (c *clock) limits(color, n int) client.Limits {
+	l := client.Limits{Depth: c.tc.Depth, Nodes: c.tc.Nodes, MoveTime: c.tc.MoveTime}
+	if c.tc.Timed() {
+		l.WTime, l.BTime = c.left[0], c.left[1]
+		l.WInc, l.BInc = c.tc.Inc, c.tc.Inc
+		if c.tc.Moves > 0 {
+			l.MovesToGo = c.tc.Moves - (n-1)%c.tc.Moves
+		}
+	}
+	return l
+}
+
+// punch charges color's move number n with elapsed, reporting false when
+// the flag fell: when more than margin over the time left was used.
+func (c *clock) punch(color, n int, elapsed, margin time.Duration) bool {
+	if !c.tc.Timed() {
+		return c.tc.MoveTime == 0 || elapsed <= c.tc.MoveTime+margin
+	}
+	c.left[color] -= elapsed
+	if c.left[color] < -margin {
+		return false
+	}
+	c.left[color] = max(c.left[color], 0) + c.tc.Inc
+	if c.tc.Moves > 0 && n%c.tc.Moves == 0 {
+		c.left[color] += c.tc.Base
+	}
+	return true
+}
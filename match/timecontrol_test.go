@@ -0,0 +1,70 @@
+package match
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		s    string
+		want TimeControl
+	}{
+		{"10+0.1", TimeControl{Base: 10 * time.Second, Inc: 100 * time.Millisecond}},
+		{"40/60", TimeControl{Moves: 40, Base: time.Minute}},
+		{"40/1:30+2", TimeControl{Moves: 40, Base: 90 * time.Second, Inc: 2 * time.Second}},
+		{"inf", TimeControl{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeControl(tt.s)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseTimeControl(%q) = %+v, %v; want %+v", tt.s, got, err, tt.want)
+		}
+		if tt.s != "inf" && got.String() != tt.s && tt.s != "40/1:30+2" {
+			t.Errorf("%q renders as %q", tt.s, got.String())
+		}
+	}
+	for _, bad := range []string{"x", "0/10", "10+y", "-5"} {
+		if _, err := ParseTimeControl(bad); err == nil {
+			t.Errorf("ParseTimeControl(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestUnlimitedTimeControlIsRejected(t *testing.T) {
+	tc, err := ParseTimeControl("inf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Match{Engines: []EngineConfig{{Name: "a"}, {Name: "b"}}, TimeControl: tc}
+	if _, err := m.Run(context.Background()); err == nil {
+		t.Error("a match on \"inf\" without a depth, node or move-time limit started")
+	}
+	if tc.Depth = 3; !tc.limited() {
+		t.Error("\"inf\" with a depth limit is unlimited")
+	}
+}
+
+func TestClock(t *testing.T) {
+	c := newClock(TimeControl{Moves: 2, Base: time.Second, Inc: 100 * time.Millisecond})
+	if l := c.limits(0, 1); l.WTime != time.Second || l.WInc != 100*time.Millisecond || l.MovesToGo != 2 {
+		t.Fatalf("first move: %+v", l)
+	}
+	if !c.punch(0, 1, 400*time.Millisecond, 0) || c.left[0] != 700*time.Millisecond {
+		t.Fatalf("after 400ms: %v", c.left[0])
+	}
+	if l := c.limits(0, 2); l.MovesToGo != 1 {
+		t.Fatalf("second move: %+v", l)
+	}
+	// The control is reached: the base time comes back on top.
+	if !c.punch(0, 2, 200*time.Millisecond, 0) || c.left[0] != 1600*time.Millisecond {
+		t.Fatalf("after the control: %v", c.left[0])
+	}
+	if c.punch(1, 1, 1200*time.Millisecond, 100*time.Millisecond) {
+		t.Fatal("overrunning the clock by more than the margin kept the flag up")
+	}
+	if !newClock(TimeControl{}).punch(0, 1, time.Hour, 0) {
+		t.Fatal("a game without a clock lost on time")
+	}
+}
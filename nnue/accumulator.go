@@ -0,0 +1,90 @@
+// --- nnue/accumulator.go ---
+
+package nnue
+
+// Accumulator holds the hidden-layer pre-activations for both perspectives.
+type Accumulator struct {
+	Values [2][]int16 // indexed by perspective colour
+}
+
+// NewAccumulator allocates an accumulator sized for the network.
+func (n *Network) NewAccumulator() *Accumulator {
+	return &Accumulator{Values: [2][]int16{
+		make([]int16, n.Hidden),
+		make([]int16, n.Hidden),
+	}}
+}
+
+// Reset loads the feature biases into both perspectives, i.e. an empty board.
+func (n *Network) Reset(acc *Accumulator) {
+	copy(acc.Values[0], n.FeatureBias)
+	copy(acc.Values[1], n.FeatureBias)
+}
+
+// AddPiece activates the features of a piece appearing on a square.
+func (n *Network) AddPiece(acc *Accumulator, color, pieceType, sq int) {
+	for p := 0; p < 2; p++ {
+		addInt16(acc.Values[p], n.column(FeatureIndex(p, color, pieceType, sq)))
+	}
+}
+
+// RemovePiece deactivates the features of a piece leaving a square.
+func (n *Network) RemovePiece(acc *Accumulator, color, pieceType, sq int) {
+	for p := 0; p < 2; p++ {
+		subInt16(acc.Values[p], n.column(FeatureIndex(p, color, pieceType, sq)))
+	}
+}
+
+func (n *Network) column(feature int) []int16 {
+	off := feature * n.Hidden
+	return n.FeatureWeights[off : off+n.Hidden]
+}
+
+// Stack is a make/unmake history of accumulators. Push copies the current
+// accumulator so the incremental deltas of a move can be applied in place,
+// and Pop restores the previous one without recomputation.
+type Stack struct {
+	net   *Network
+	accs  []*Accumulator
+	depth int
+}
+
+// NewStack creates an accumulator stack for the network.
+func (n *Network) NewStack() *Stack {
+	return &Stack{net: n, accs: []*Accumulator{n.NewAccumulator()}}
+}
+
+// Current returns the accumulator for the current position.
+func (s *Stack) Current() *Accumulator {
+	return s.accs[s.depth]
+}
+
+// Depth reports how many pushes are outstanding.
+func (s *Stack) Depth() int {
+	return s.depth
+}
+
+// Push duplicates the current accumulator on top of the stack.
+func (s *Stack) Push() {
+	if s.depth+1 == len(s.accs) {
+		s.accs = append(s.accs, s.net.NewAccumulator())
+	}
+	next := s.accs[s.depth+1]
+	copy(next.Values[0], s.accs[s.depth].Values[0])
+	copy(next.Values[1], s.accs[s.depth].Values[1])
+	s.depth++
+}
+
+// Pop discards the top accumulator. It returns false if the stack is at its base.
+func (s *Stack) Pop() bool {
+	if s.depth == 0 {
+		return false
+	}
+	s.depth--
+	return true
+}
+
+// Clear drops every pushed accumulator, keeping the base.
+func (s *Stack) Clear() {
+	s.depth = 0
+}
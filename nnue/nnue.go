@@ -0,0 +1,183 @@
+// --- nnue/nnue.go ---
+
+// Package nnue implements an efficiently updatable neural network evaluator.
+//
+// The network is a single hidden layer "768 -> Hx2 -> 1" perceptron. Every
+// (colour, piece type, square) triple is one binary input feature, seen from
+// both sides of the board: each perspective keeps its own accumulator of
+// hidden activations, which is updated by adding or subtracting a weight
+// column whenever a piece appears on or leaves a square. Evaluation only runs
+// the cheap output layer over the two accumulators.
+//
+// # Weight file format
+//
+// All integers are little-endian.
+//
+//	offset  type                 field
+//	0       [4]byte              magic "MCNN"
+//	4       uint32               version (currently 1)
+//	8       uint32               H, hidden layer width (multiple of 8)
+//	12      int32                Scale, centipawns per unit of network output
+//	16      int16[768][H]        feature weights, feature-major
+//	...     int16[H]             feature biases
+//	...     int8[2H]             output weights (side to move first, then opponent)
+//	...     int32                output bias
+//
+// Feature index for a piece, relative to a perspective, is
+// (relColor*6 + pieceType)*64 + square, where relColor is 0 for the
+// perspective's own pieces and 1 for the opponent's, pieceType follows
+// P, N, B, R, Q, K (0..5), and squares are a1=0..h8=63 mirrored vertically
+// for Black's perspective.
+//
+// Inference clamps accumulator values to [0, QA] (clipped ReLU), multiplies by
+// the int8 output weights and returns
+//
+//	(Σ crelu(acc)·w + bias) · Scale / (QA·QB)
+//
+// in centipawns from the side to move's point of view.
+package nnue
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// Inputs is the number of binary features per perspective (2 colours × 6 pieces × 64 squares).
+	Inputs = 768
+	// QA is the clipped ReLU ceiling applied to accumulator values.
+	QA = 127
+	// QB is the fixed-point scale of the int8 output weights.
+	QB = 64
+
+	magic   = "MCNN"
+	version = 1
+)
+
+// Network holds the quantized weights of an evaluation network.
+type Network struct {
+	Hidden         int
+	Scale          int32
+	FeatureWeights []int16 // Inputs*Hidden, feature-major
+	FeatureBias    []int16 // Hidden
+	OutputWeights  []int8  // 2*Hidden
+	OutputBias     int32
+}
+
+// NewNetwork allocates a zeroed network with the given hidden width.
+func NewNetwork(hidden int, scale int32) (*Network, error) {
+	if hidden <= 0 || hidden%8 != 0 {
+		return nil, fmt.Errorf("nnue: hidden width %d must be a positive multiple of 8", hidden)
+	}
+	return &Network{
+		Hidden:         hidden,
+		Scale:          scale,
+		FeatureWeights: make([]int16, Inputs*hidden),
+		FeatureBias:    make([]int16, hidden),
+		OutputWeights:  make([]int8, 2*hidden),
+	}, nil
+}
+
+// Load reads a network from a weight file on disk.
+func Load(path string) (*Network, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(bufio.NewReader(f))
+}
+
+// Read decodes a network in the documented weight format.
+func Read(r io.Reader) (*Network, error) {
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		Hidden  uint32
+		Scale   int32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("nnue: reading header: %w", err)
+	}
+	if string(header.Magic[:]) != magic {
+		return nil, errors.New("nnue: bad magic, not an MCNN weight file")
+	}
+	if header.Version != version {
+		return nil, fmt.Errorf("nnue: unsupported version %d", header.Version)
+	}
+	if header.Hidden > 4096 {
+		return nil, fmt.Errorf("nnue: hidden width %d too large", header.Hidden)
+	}
+	n, err := NewNetwork(int(header.Hidden), header.Scale)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range []any{n.FeatureWeights, n.FeatureBias, n.OutputWeights, &n.OutputBias} {
+		if err := binary.Read(r, binary.LittleEndian, field); err != nil {
+			return nil, fmt.Errorf("nnue: truncated weight file: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// Write encodes the network in the documented weight format.
+func (n *Network) Write(w io.Writer) error {
+	header := struct {
+		Magic   [4]byte
+		Version uint32
+		Hidden  uint32
+		Scale   int32
+	}{Version: version, Hidden: uint32(n.Hidden), Scale: n.Scale}
+	copy(header.Magic[:], magic)
+	for _, field := range []any{&header, n.FeatureWeights, n.FeatureBias, n.OutputWeights, n.OutputBias} {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save writes the network to a file.
+func (n *Network) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	if err := n.Write(bw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// FeatureIndex maps a piece to its input feature as seen from perspective.
+// color and perspective are 0 (White) or 1 (Black); pieceType is 0..5 (P..K).
+func FeatureIndex(perspective, color, pieceType, sq int) int {
+	if perspective == 1 {
+		sq ^= 56
+	}
+	rel := 0
+	if color != perspective {
+		rel = 1
+	}
+	return (rel*6+pieceType)*64 + sq
+}
+
+// Evaluate runs the output layer on an accumulator for the side to move.
+func (n *Network) Evaluate(acc *Accumulator, sideToMove int) int {
+	us := acc.Values[sideToMove]
+	them := acc.Values[1-sideToMove]
+	sum := dotCReLU(us, n.OutputWeights[:n.Hidden]) +
+		dotCReLU(them, n.OutputWeights[n.Hidden:]) +
+		n.OutputBias
+	return int(int64(sum) * int64(n.Scale) / (QA * QB))
+}
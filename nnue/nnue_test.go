@@ -0,0 +1,104 @@
+package nnue
+
+import (
+	"bytes"
+	"testing"
+)
+
+func loadTiny(t *testing.T) *Network {
+	t.Helper()
+	n, err := Load("testdata/tiny.nnue")
+	if err != nil {
+		t.Fatalf("load tiny network: %v", err)
+	}
+	return n
+}
+
+func TestLoadTinyNetwork(t *testing.T) {
+	n := loadTiny(t)
+	if n.Hidden != 8 || n.Scale != 12700 {
+		t.Fatalf("unexpected header: hidden=%d scale=%d", n.Hidden, n.Scale)
+	}
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	n := loadTiny(t)
+	var buf bytes.Buffer
+	if err := n.Write(&buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	m, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if m.Hidden != n.Hidden || m.OutputBias != n.OutputBias || !bytes.Equal(int8Bytes(m.OutputWeights), int8Bytes(n.OutputWeights)) {
+		t.Fatalf("round trip mismatch")
+	}
+	for i := range n.FeatureWeights {
+		if m.FeatureWeights[i] != n.FeatureWeights[i] {
+			t.Fatalf("feature weight %d mismatch", i)
+		}
+	}
+}
+
+func TestReadRejectsBadMagic(t *testing.T) {
+	if _, err := Read(bytes.NewReader([]byte("NOPE0000000000000000"))); err == nil {
+		t.Fatal("expected bad magic error")
+	}
+}
+
+func TestTinyNetworkScoresMaterial(t *testing.T) {
+	n := loadTiny(t)
+	acc := n.NewAccumulator()
+	n.Reset(acc)
+	n.AddPiece(acc, 0, 5, 4)  // white king e1
+	n.AddPiece(acc, 1, 5, 60) // black king e8
+	if got := n.Evaluate(acc, 0); got != 0 {
+		t.Fatalf("bare kings should be 0, got %d", got)
+	}
+
+	n.AddPiece(acc, 0, 3, 0) // white rook a1
+	if got := n.Evaluate(acc, 0); got != 500 {
+		t.Fatalf("white to move, up a rook: want 500, got %d", got)
+	}
+	if got := n.Evaluate(acc, 1); got != -500 {
+		t.Fatalf("black to move, down a rook: want -500, got %d", got)
+	}
+
+	n.RemovePiece(acc, 0, 3, 0)
+	if got := n.Evaluate(acc, 0); got != 0 {
+		t.Fatalf("remove should undo add, got %d", got)
+	}
+}
+
+func TestStackPushPop(t *testing.T) {
+	n := loadTiny(t)
+	s := n.NewStack()
+	n.Reset(s.Current())
+	s.Push()
+	n.AddPiece(s.Current(), 1, 4, 59) // black queen d8
+	if n.Evaluate(s.Current(), 1) != 900 {
+		t.Fatalf("expected queen up for black")
+	}
+	if !s.Pop() || n.Evaluate(s.Current(), 1) != 0 {
+		t.Fatalf("pop should restore previous accumulator")
+	}
+	if s.Pop() {
+		t.Fatalf("pop at base should fail")
+	}
+}
+
+func TestFeatureIndexMirrorsForBlack(t *testing.T) {
+	// A white pawn on e2 seen by White equals a black pawn on e7 seen by Black.
+	if FeatureIndex(0, 0, 0, 12) != FeatureIndex(1, 1, 0, 52) {
+		t.Fatal("perspective mirroring broken")
+	}
+}
+
+func int8Bytes(v []int8) []byte {
+	out := make([]byte, len(v))
+	for i, x := range v {
+		out[i] = byte(x)
+	}
+	return out
+}
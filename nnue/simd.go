@@ -0,0 +1,65 @@
+// --- nnue/simd.go ---
+
+package nnue
+
+// The kernels below work on lanes of 8 so the compiler can keep them in
+// registers and elide bounds checks; hidden widths are always multiples of 8.
+
+// addInt16 computes dst += src element-wise.
+func addInt16(dst, src []int16) {
+	src = src[:len(dst)]
+	for i := 0; i+8 <= len(dst); i += 8 {
+		d := dst[i : i+8 : i+8]
+		s := src[i : i+8 : i+8]
+		d[0] += s[0]
+		d[1] += s[1]
+		d[2] += s[2]
+		d[3] += s[3]
+		d[4] += s[4]
+		d[5] += s[5]
+		d[6] += s[6]
+		d[7] += s[7]
+	}
+}
+
+// subInt16 computes dst -= src element-wise.
+func subInt16(dst, src []int16) {
+	src = src[:len(dst)]
+	for i := 0; i+8 <= len(dst); i += 8 {
+		d := dst[i : i+8 : i+8]
+		s := src[i : i+8 : i+8]
+		d[0] -= s[0]
+		d[1] -= s[1]
+		d[2] -= s[2]
+		d[3] -= s[3]
+		d[4] -= s[4]
+		d[5] -= s[5]
+		d[6] -= s[6]
+		d[7] -= s[7]
+	}
+}
+
+// dotCReLU returns Σ clamp(acc[i], 0, QA) * w[i].
+func dotCReLU(acc []int16, w []int8) int32 {
+	w = w[:len(acc)]
+	var s0, s1, s2, s3 int32
+	for i := 0; i+8 <= len(acc); i += 8 {
+		a := acc[i : i+8 : i+8]
+		b := w[i : i+8 : i+8]
+		s0 += crelu(a[0])*int32(b[0]) + crelu(a[4])*int32(b[4])
+		s1 += crelu(a[1])*int32(b[1]) + crelu(a[5])*int32(b[5])
+		s2 += crelu(a[2])*int32(b[2]) + crelu(a[6])*int32(b[6])
+		s3 += crelu(a[3])*int32(b[3]) + crelu(a[7])*int32(b[7])
+	}
+	return s0 + s1 + s2 + s3
+}
+
+func crelu(v int16) int32 {
+	if v < 0 {
+		return 0
+	}
+	if v > QA {
+		return QA
+	}
+	return int32(v)
+}
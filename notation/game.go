@@ -0,0 +1,83 @@
+// --- notation/game.go ---
+
+package notation
+
+import (
+	"unicode"
+
+	"github.com/mesb/mchess/pieces"
+	"github.com/mesb/mchess/socrates"
+)
+
+// Line writes moves, played one after another from eng's position, in SAN,
+// as for a principal variation. It stops at the first illegal move,
+// returning the moves before it with the error. eng is left as it was.
+func Line(eng *socrates.RuleEngine, moves []socrates.SimpleMove) ([]string, error) {
+	out := make([]string, 0, len(moves))
+	defer func() {
+		for range out {
+			eng.UndoMove()
+		}
+	}()
+	for _, m := range moves {
+		san, err := SAN(eng, m)
+		if err != nil {
+			return out, err
+		}
+		eng.MakeMove(m.From, m.To, m.Promo)
+		out = append(out, san)
+	}
+	return out, nil
+}
+
+// History returns the FEN of the position eng's game started from and the
+// moves played since, from its log, in SAN. eng is rewound and replayed,
+// and must not be in use meanwhile.
+func History(eng *socrates.RuleEngine) (fen string, moves []string) {
+	fen, played := Played(eng)
+	for range played {
+		eng.UndoMove()
+	}
+	moves = make([]string, len(played))
+	for i, m := range played {
+		moves[i], _ = SAN(eng, m) // the move was legal when it was played
+		eng.MakeMove(m.From, m.To, m.Promo)
+	}
+	return fen, moves
+}
+
+// Played returns the FEN of the position eng's game started from and the
+// moves of its log as simple moves. eng is rewound and replayed, and must
+// not be in use meanwhile.
+func Played(eng *socrates.RuleEngine) (fen string, moves []socrates.SimpleMove) {
+	if eng.Log != nil {
+		moves = make([]socrates.SimpleMove, len(eng.Log.Moves()))
+	}
+	for i := len(moves) - 1; i >= 0; i-- {
+		m := eng.Log.Moves()[i]
+		moves[i] = Simple(m, eng.Board.PieceAt(m.To))
+		eng.UndoMove()
+	}
+	fen = eng.Board.ToFEN(eng.State)
+	for _, m := range moves {
+		eng.MakeMove(m.From, m.To, m.Promo)
+	}
+	return fen, moves
+}
+
+// Simple turns a logged move into the simple move that replays it; landed
+// is the piece that stood on m.To right after the move, which tells what a
+// pawn promoted to. A promotion with landed unknown (nil) is to a queen.
+func Simple(m socrates.Move, landed pieces.Piece) socrates.SimpleMove {
+	s := socrates.SimpleMove{From: m.From, To: m.To}
+	if _, pawn := m.Piece.(*pieces.Pawn); !pawn || (m.To.Rank != 0 && m.To.Rank != 7) {
+		return s
+	}
+	s.Promo = 'q'
+	if landed != nil {
+		if k := letter(landed); k != 'K' && k != 'P' {
+			s.Promo = unicode.ToLower(k)
+		}
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package notation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/socrates"
+)
+
+func TestLine(t *testing.T) {
+	eng := socrates.New(board.InitStandard())
+	var moves []socrates.SimpleMove
+	for _, c := range []string{"e2e4", "e7e5", "d1h5", "b8c6", "f1c4", "g8f6", "h5f7"} {
+		moves = append(moves, socrates.SimpleMove{From: square(c[:2]), To: square(c[2:])})
+	}
+	got, err := Line(eng, moves)
+	want := []string{"e4", "e5", "Qh5", "Nc6", "Bc4", "Nf6", "Qxf7#"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, %v, want %q", got, err, want)
+	}
+	if len(eng.Log.Moves()) != 0 {
+		t.Fatal("Line left moves on the board")
+	}
+
+	moves[3].To = square("c5")
+	if got, err := Line(eng, moves); err == nil || len(got) != 3 {
+		t.Fatalf("illegal fourth move: got %q, %v", got, err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	start := "4k3/P7/8/8/8/8/4K2p/8 w - - 0 1"
+	eng := engineAt(t, start)
+	for _, m := range []string{"a7a8n", "h2h1r", "a8c7"} {
+		from, to, promo, err := socrates.ParseMove(m)
+		if err != nil || !eng.MakeMove(*from, *to, promo) {
+			t.Fatalf("%s: %v", m, err)
+		}
+	}
+	after := eng.Board.ToFEN(eng.State)
+
+	fen, moves := History(eng)
+	if fen != start {
+		t.Errorf("start %s, want %s", fen, start)
+	}
+	if want := []string{"a8=N", "h1=R", "Nc7+"}; !reflect.DeepEqual(moves, want) {
+		t.Errorf("moves %q, want %q", moves, want)
+	}
+	if got := eng.Board.ToFEN(eng.State); got != after || len(eng.Log.Moves()) != 3 {
+		t.Errorf("History left %s with %d moves, want %s", got, len(eng.Log.Moves()), after)
+	}
+}
@@ -0,0 +1,251 @@
+// --- notation/san.go ---
+
+// Package notation converts moves between coordinate notation and the
+// Standard Algebraic Notation (SAN) of PGN files and test suites.
+package notation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/pieces"
+	"github.com/mesb/mchess/socrates"
+)
+
+// ParseSAN finds the legal move that san names in eng's position. It is
+// lenient about the forms seen in the wild: check and annotation suffixes,
+// "0-0" for castling, promotions without "=" ("e8Q"), long algebraic moves
+// ("Ng1f3", "Ng1-f3") and coordinate notation ("g1f3").
+func ParseSAN(eng *socrates.RuleEngine, san string) (socrates.SimpleMove, error) {
+	s := strings.TrimRight(san, "+#!?")
+	s = strings.TrimSpace(strings.TrimSuffix(s, "e.p."))
+	switch s {
+	case "O-O", "0-0":
+		return castle(eng, san, 6)
+	case "O-O-O", "0-0-0":
+		return castle(eng, san, 2)
+	}
+
+	// The piece letter, then the destination and any promotion.
+	var kind rune
+	if len(s) > 0 && strings.ContainsRune("KQRBN", rune(s[0])) {
+		kind, s = rune(s[0]), s[1:]
+	}
+	var promo rune
+	if i := strings.IndexAny(s, "=("); i >= 0 {
+		p := strings.Trim(s[i:], "=()")
+		if len(p) != 1 {
+			return socrates.SimpleMove{}, fmt.Errorf("san %q: bad promotion piece", san)
+		}
+		promo, s = unicode.ToLower(rune(p[0])), s[:i]
+	} else if n := len(s); n > 2 && kind == 0 && strings.ContainsRune("QRBNqrbn", rune(s[n-1])) && isRank(s[n-2]) {
+		promo, s = unicode.ToLower(rune(s[n-1])), s[:n-1]
+	}
+	s = strings.NewReplacer("x", "", "-", "", ":", "").Replace(s)
+	if len(s) < 2 || !isFile(s[len(s)-2]) || !isRank(s[len(s)-1]) {
+		return socrates.SimpleMove{}, fmt.Errorf("san %q: no destination square", san)
+	}
+	to := square(s[len(s)-2:])
+	from := s[:len(s)-2] // disambiguation: a file, a rank or both
+	if len(from) > 2 || (len(from) == 2 && (!isFile(from[0]) || !isRank(from[1]))) ||
+		(len(from) == 1 && !isFile(from[0]) && !isRank(from[0])) {
+		return socrates.SimpleMove{}, fmt.Errorf("san %q: bad origin %q", san, from)
+	}
+	if kind == 0 && len(from) < 2 {
+		kind = 'P' // only coordinate notation names no piece
+	}
+	if promo != 0 && !strings.ContainsRune("qrbn", promo) {
+		return socrates.SimpleMove{}, fmt.Errorf("san %q: bad promotion piece", san)
+	}
+
+	var found []socrates.SimpleMove
+	pawn := false
+	eng.Board.ForEachPiece(func(sq address.Addr, p pieces.Piece) {
+		if p.Color() != eng.Turn || (kind != 0 && letter(p) != kind) || !matches(sq, from) {
+			return
+		}
+		for _, dest := range p.ValidMoves(sq, eng.Board, eng.State) {
+			if dest == to && eng.IsLegalMove(sq, to) {
+				found = append(found, socrates.SimpleMove{From: sq, To: to, Promo: promo})
+				pawn = letter(p) == 'P'
+			}
+		}
+	})
+	switch len(found) {
+	case 0:
+		return socrates.SimpleMove{}, fmt.Errorf("san %q: no such legal move", san)
+	case 1:
+	default:
+		return socrates.SimpleMove{}, fmt.Errorf("san %q: ambiguous", san)
+	}
+	m := found[0]
+	if pawn && (to.Rank == 0 || to.Rank == 7) {
+		if m.Promo == 0 {
+			m.Promo = 'q'
+		}
+	} else if m.Promo != 0 {
+		return socrates.SimpleMove{}, fmt.Errorf("san %q: only pawns promote, on the last rank", san)
+	}
+	return m, nil
+}
+
+// SAN writes m, a legal move in eng's position, in Standard Algebraic
+// Notation: the piece letter, just enough of the origin square to tell it
+// from its twins, "x" for captures, "=Q" for promotions and "+" or "#"
+// after checks and mates. A pawn reaching the last rank without a
+// promotion piece becomes a queen, as in MakeMove. eng is left as it was.
+func SAN(eng *socrates.RuleEngine, m socrates.SimpleMove) (string, error) {
+	p := eng.Board.PieceAt(m.From)
+	if p == nil || p.Color() != eng.Turn || !eng.IsLegalMove(m.From, m.To) {
+		return "", fmt.Errorf("move %s%s: not legal", name(m.From), name(m.To))
+	}
+	kind := letter(p)
+	promo := m.Promo
+	if kind == 'P' && (m.To.Rank == 0 || m.To.Rank == 7) {
+		if promo == 0 {
+			promo = 'q'
+		}
+		if !strings.ContainsRune("qrbn", promo) {
+			return "", fmt.Errorf("move %s%s: bad promotion piece %q", name(m.From), name(m.To), promo)
+		}
+	} else if promo != 0 {
+		return "", fmt.Errorf("move %s%s: only pawns promote, on the last rank", name(m.From), name(m.To))
+	}
+
+	var b strings.Builder
+	capture := eng.Board.PieceAt(m.To) != nil
+	switch df := int(m.To.File) - int(m.From.File); {
+	case kind == 'K' && df == 2:
+		b.WriteString("O-O")
+	case kind == 'K' && df == -2:
+		b.WriteString("O-O-O")
+	default:
+		if kind == 'P' {
+			if df != 0 {
+				capture = true // en passant leaves the square empty
+				b.WriteByte(byte(m.From.File.Char()))
+			}
+		} else {
+			b.WriteRune(kind)
+			b.WriteString(disambiguate(eng, p, m))
+		}
+		if capture {
+			b.WriteByte('x')
+		}
+		b.WriteString(name(m.To))
+		if kind == 'P' && promo != 0 {
+			b.WriteString("=" + string(unicode.ToUpper(promo)))
+		}
+	}
+
+	eng.MakeMove(m.From, m.To, promo)
+	switch {
+	case eng.IsCheckmate():
+		b.WriteByte('#')
+	case eng.IsInCheck(eng.Turn):
+		b.WriteByte('+')
+	}
+	eng.UndoMove()
+	return b.String(), nil
+}
+
+// disambiguate names as little of m's origin as tells p apart from the
+// pieces of its kind that could also go to m.To: the file if that will
+// do, else the rank, else both.
+func disambiguate(eng *socrates.RuleEngine, p pieces.Piece, m socrates.SimpleMove) string {
+	twins, sameFile, sameRank := false, false, false
+	eng.Board.ForEachPiece(func(sq address.Addr, q pieces.Piece) {
+		if sq == m.From || q.Color() != p.Color() || letter(q) != letter(p) || !eng.IsLegalMove(sq, m.To) {
+			return
+		}
+		twins = true
+		sameFile = sameFile || sq.File == m.From.File
+		sameRank = sameRank || sq.Rank == m.From.Rank
+	})
+	switch {
+	case !twins:
+		return ""
+	case !sameFile:
+		return string(m.From.File.Char())
+	case !sameRank:
+		return name(m.From)[1:]
+	}
+	return name(m.From)
+}
+
+// ParseStrict is ParseSAN for input that must be in the standard form:
+// it accepts san only if it is exactly what SAN writes for the move, with
+// minimal disambiguation, "=" before promotions and the right check or
+// mate suffix.
+func ParseStrict(eng *socrates.RuleEngine, san string) (socrates.SimpleMove, error) {
+	m, err := ParseSAN(eng, san)
+	if err != nil {
+		return m, err
+	}
+	want, err := SAN(eng, m)
+	if err != nil {
+		return socrates.SimpleMove{}, fmt.Errorf("san %q: %v", san, err)
+	}
+	if want != san {
+		return socrates.SimpleMove{}, fmt.Errorf("san %q: not standard, want %q", san, want)
+	}
+	return m, nil
+}
+
+// castle finds the king's castling move towards file.
+func castle(eng *socrates.RuleEngine, san string, file address.File) (socrates.SimpleMove, error) {
+	var m socrates.SimpleMove
+	ok := false
+	eng.Board.ForEachPiece(func(sq address.Addr, p pieces.Piece) {
+		if _, king := p.(*pieces.King); king && p.Color() == eng.Turn && sq.File == 4 {
+			to := address.MakeAddr(sq.Rank, file)
+			m, ok = socrates.SimpleMove{From: sq, To: to}, eng.IsLegalMove(sq, to)
+		}
+	})
+	if !ok {
+		return m, fmt.Errorf("san %q: castling is not legal", san)
+	}
+	return m, nil
+}
+
+// letter is the SAN letter of p's kind, P for pawns.
+func letter(p pieces.Piece) rune {
+	switch p.(type) {
+	case *pieces.King:
+		return 'K'
+	case *pieces.Queen:
+		return 'Q'
+	case *pieces.Rook:
+		return 'R'
+	case *pieces.Bishop:
+		return 'B'
+	case *pieces.Knight:
+		return 'N'
+	}
+	return 'P'
+}
+
+// matches reports whether sq fits a disambiguation such as "g", "1" or "g1".
+func matches(sq address.Addr, from string) bool {
+	for i := 0; i < len(from); i++ {
+		c := from[i]
+		if isFile(c) && address.File(c-'a') != sq.File || isRank(c) && address.Rank(c-'1') != sq.Rank {
+			return false
+		}
+	}
+	return true
+}
+
+// name writes a square such as "e4".
+func name(a address.Addr) string {
+	return fmt.Sprintf("%c%d", a.File.Char(), int(a.Rank)+1)
+}
+
+func square(s string) address.Addr {
+	return address.MakeAddr(address.Rank(s[1]-'1'), address.File(s[0]-'a'))
+}
+
+func isFile(c byte) bool { return c >= 'a' && c <= 'h' }
+func isRank(c byte) bool { return c >= '1' && c <= '8' }
@@ -0,0 +1,187 @@
+package notation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/socrates"
+)
+
+func engineAt(t *testing.T, fen string) *socrates.RuleEngine {
+	t.Helper()
+	b, s, err := board.FromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eng := socrates.New(b)
+	eng.SetPosition(b, s)
+	return eng
+}
+
+func TestParseSAN(t *testing.T) {
+	tests := []struct {
+		fen, san, want string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Nf3", "g1f3"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e4", "e2e4"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Ng1-f3", "g1f3"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "g1f3", "g1f3"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "exd5", "e4d5"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "0-0-0", "e8c8"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "Rad1", "a1d1"},
+		{"4k3/8/8/8/8/8/8/R3K2R w K - 0 1", "Rhf1+", "h1f1"},
+		{"4k3/8/8/8/R7/8/8/R3K3 w - - 0 1", "R1a2", "a1a2"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8=N", "a7a8n"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8Q", "a7a8q"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8", "a7a8q"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2", "exd6 e.p.", "e5d6"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "Ra8#", "a1a8"},
+	}
+	for _, tt := range tests {
+		m, err := ParseSAN(engineAt(t, tt.fen), tt.san)
+		if err != nil {
+			t.Errorf("%s in %s: %v", tt.san, tt.fen, err)
+			continue
+		}
+		if got := m.String(); got != tt.want {
+			t.Errorf("%s in %s: got %s, want %s", tt.san, tt.fen, got, tt.want)
+		}
+	}
+}
+
+func TestParseSANRejects(t *testing.T) {
+	tests := []struct {
+		fen, san, err string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Nf4", "no such legal move"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "O-O", "castling is not legal"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rd1", "ambiguous"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e4=Q", "only pawns promote"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8=K", "bad promotion"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Nz3", "no destination"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSAN(engineAt(t, tt.fen), tt.san)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want %q", tt.san, err, tt.err)
+		}
+	}
+}
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		fen, move, want string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "g1f3", "Nf3"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2e4", "e4"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "e4d5", "exd5"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2", "e5d6", "exd6"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "a1d1", "Rd1"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1", "Rad1"},
+		{"4k3/8/8/8/R7/8/8/R3K3 w - - 0 1", "a1a2", "R1a2"},
+		{"6k1/8/8/Q7/8/8/8/Q3Q2K w - - 0 1", "a1e5", "Qa1e5"},
+		{"6k1/8/8/Q7/8/8/8/Q3Q2K w - - 0 1", "a5e5", "Q5e5"},
+		{"6k1/8/8/Q7/8/8/8/Q3Q2K w - - 0 1", "e1e5", "Qee5"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8n", "a8=N"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8", "a8=Q+"},
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7b8q", "axb8=Q+"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8", "Ra8#"},
+	}
+	for _, tt := range tests {
+		eng := engineAt(t, tt.fen)
+		m, err := ParseSAN(eng, tt.move)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.move, err)
+		}
+		if len(tt.move) == 4 {
+			m.Promo = 0
+		}
+		got, err := SAN(eng, m)
+		if err != nil || got != tt.want {
+			t.Errorf("%s in %s: got %q, %v, want %q", tt.move, tt.fen, got, err, tt.want)
+		}
+		if fen := eng.Board.ToFEN(eng.State); fen != tt.fen {
+			t.Errorf("%s changed the position to %s", tt.move, fen)
+		}
+	}
+}
+
+func TestSANRejectsIllegalMoves(t *testing.T) {
+	eng := engineAt(t, "4k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	for _, m := range []socrates.SimpleMove{
+		{From: square("e8"), To: square("e7")},             // not White's piece
+		{From: square("e1"), To: square("e3")},             // not a king move
+		{From: square("a7"), To: square("a8"), Promo: 'k'}, // no such promotion
+		{From: square("e1"), To: square("e2"), Promo: 'q'}, // not a pawn
+	} {
+		if san, err := SAN(eng, m); err == nil {
+			t.Errorf("%s: got %q", m.String(), san)
+		}
+	}
+}
+
+// TestSANRoundTrip writes every legal move, under-promotions included,
+// and reads it back strictly.
+func TestSANRoundTrip(t *testing.T) {
+	for _, fen := range []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1",
+		"6k1/8/8/Q7/8/8/8/Q3Q2K w - - 0 1",
+	} {
+		eng := engineAt(t, fen)
+		var moves []socrates.SimpleMove
+		for _, m := range eng.GenerateLegalMoves() {
+			moves = append(moves, m)
+			if m.Promo == 'q' {
+				for _, p := range "rbn" {
+					moves = append(moves, socrates.SimpleMove{From: m.From, To: m.To, Promo: p})
+				}
+			}
+		}
+		seen := map[string]bool{}
+		for _, m := range moves {
+			san, err := SAN(eng, m)
+			if err != nil {
+				t.Fatalf("%s in %s: %v", m.String(), fen, err)
+			}
+			if seen[san] {
+				t.Errorf("%s in %s: %q names two moves", m.String(), fen, san)
+			}
+			seen[san] = true
+			back, err := ParseStrict(eng, san)
+			if err != nil || back != m {
+				t.Errorf("%s in %s: %q reads back as %s, %v", m.String(), fen, san, back.String(), err)
+			}
+		}
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	start := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	if m, err := ParseStrict(engineAt(t, start), "Nf3"); err != nil || m.String() != "g1f3" {
+		t.Fatalf("Nf3: got %s, %v", m.String(), err)
+	}
+	tests := []struct {
+		fen, san string
+	}{
+		{start, "Ng1f3"},
+		{start, "g1f3"},
+		{start, "Nf3!"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8Q+"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8=Q"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "Ra8+"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "Rad1"},
+	}
+	for _, tt := range tests {
+		if m, err := ParseStrict(engineAt(t, tt.fen), tt.san); err == nil {
+			t.Errorf("%s: accepted as %s", tt.san, m.String())
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// --- pgn/lexer.go ---
+
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// kind tells the tokens of PGN apart.
+type kind int
+
+const (
+	tokEOF        kind = iota
+	tokSymbol          // a move, move number, result or tag name
+	tokString          // a tag value, unquoted and unescaped
+	tokPeriod          // after a move number
+	tokAsterisk        // the unknown result
+	tokNAG             // "$14"
+	tokAnnotation      // a suffix such as "!?"
+	tokComment         // "{...}" or "; ..." up to the end of the line
+	tokLBracket
+	tokRBracket
+	tokLParen
+	tokRParen
+)
+
+var kindNames = [...]string{"end of input", "symbol", "string", `"."`, `"*"`, "NAG", "annotation", "comment", `"["`, `"]"`, `"("`, `")"`}
+
+func (k kind) String() string { return kindNames[k] }
+
+// token is one lexeme and where it starts.
+type token struct {
+	kind      kind
+	text      string
+	line, col int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF || t.kind == tokComment {
+		return t.kind.String()
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// SyntaxError is a malformed or illegal part of a PGN file, with the line
+// and column (both from 1, counting runes) where it starts.
+type SyntaxError struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("pgn:%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// lexer splits PGN into tokens, one lookahead at a time.
+type lexer struct {
+	r         *bufio.Reader
+	line, col int // of the next rune
+	prev      int // length of the line before a newline, for unread
+	peeked    *token
+	err       error // a read error other than io.EOF
+}
+
+func newLexer(r io.Reader) *lexer {
+	return &lexer{r: bufio.NewReader(r), line: 1, col: 1}
+}
+
+// peek returns the next token without consuming it.
+func (l *lexer) peek() (token, error) {
+	if l.peeked == nil {
+		t, err := l.scan()
+		if err != nil {
+			return t, err
+		}
+		l.peeked = &t
+	}
+	return *l.peeked, nil
+}
+
+// next consumes the next token.
+func (l *lexer) next() (token, error) {
+	t, err := l.peek()
+	l.peeked = nil
+	return t, err
+}
+
+func (l *lexer) read() (rune, bool) {
+	c, _, err := l.r.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			l.err = err
+		}
+		return 0, false
+	}
+	if c == '\n' {
+		l.line, l.prev, l.col = l.line+1, l.col, 1
+	} else {
+		l.col++
+	}
+	return c, true
+}
+
+func (l *lexer) unread(c rune) {
+	l.r.UnreadRune()
+	if c == '\n' {
+		l.line, l.col = l.line-1, l.prev
+	} else {
+		l.col--
+	}
+}
+
+func (l *lexer) errorf(line, col int, format string, args ...any) error {
+	return &SyntaxError{Line: line, Col: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// scan reads one token, skipping white space and escaped lines: those
+// starting with "%".
+func (l *lexer) scan() (token, error) {
+	for {
+		line, col := l.line, l.col
+		c, ok := l.read()
+		if !ok {
+			return token{kind: tokEOF, line: line, col: col}, l.err
+		}
+		t := token{line: line, col: col, text: string(c)}
+		switch {
+		case c == '%' && col == 1:
+			l.skipLine()
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == '\v' || c == '\ufeff':
+			continue
+		case c == '.':
+			t.kind = tokPeriod
+		case c == '*':
+			t.kind = tokAsterisk
+		case c == '[':
+			t.kind = tokLBracket
+		case c == ']':
+			t.kind = tokRBracket
+		case c == '(':
+			t.kind = tokLParen
+		case c == ')':
+			t.kind = tokRParen
+		case c == '"':
+			return l.str(t)
+		case c == '{':
+			return l.braceComment(t)
+		case c == ';':
+			t.kind, t.text = tokComment, strings.TrimSpace(l.skipLine())
+		case c == '$':
+			t.kind, t.text = tokNAG, l.run(isDigit)
+			if t.text == "" {
+				return t, l.errorf(line, col, "NAG needs a number after $")
+			}
+		case c == '!' || c == '?':
+			t.kind, t.text = tokAnnotation, string(c)+l.run(func(c rune) bool { return c == '!' || c == '?' })
+		case isSymbolStart(c):
+			t.kind, t.text = tokSymbol, string(c)+l.run(isSymbolChar)
+			l.enPassant(&t)
+		default:
+			return t, l.errorf(line, col, "unexpected %q", c)
+		}
+		return t, nil
+	}
+}
+
+// run reads the runes that fit in.
+func (l *lexer) run(in func(rune) bool) string {
+	var b strings.Builder
+	for {
+		c, ok := l.read()
+		if !ok {
+			return b.String()
+		}
+		if !in(c) {
+			l.unread(c)
+			return b.String()
+		}
+		b.WriteRune(c)
+	}
+}
+
+// enPassant folds the "e.p." some files write after en passant captures
+// into the symbol "e.p.", which the PGN grammar would split at its dots.
+func (l *lexer) enPassant(t *token) {
+	if t.text != "e" {
+		return
+	}
+	if b, err := l.r.Peek(3); err == nil && string(b) == ".p." {
+		l.r.Discard(3)
+		l.col += 3
+		t.text = "e.p."
+	}
+}
+
+// skipLine reads to the end of the line, returning what it read.
+func (l *lexer) skipLine() string {
+	return l.run(func(c rune) bool { return c != '\n' })
+}
+
+// str reads a string token after its opening quote; within it \" is a
+// quote and \\ a backslash.
+func (l *lexer) str(t token) (token, error) {
+	t.kind = tokString
+	var b strings.Builder
+	for {
+		c, ok := l.read()
+		switch {
+		case !ok || c == '\n':
+			return t, l.errorf(t.line, t.col, "unterminated string")
+		case c == '"':
+			t.text = b.String()
+			return t, nil
+		case c == '\\':
+			next, ok := l.read()
+			if !ok {
+				return t, l.errorf(t.line, t.col, "unterminated string")
+			}
+			if next != '"' && next != '\\' {
+				b.WriteRune(c) // not an escape: keep the backslash
+			}
+			b.WriteRune(next)
+		default:
+			b.WriteRune(c)
+		}
+	}
+}
+
+// braceComment reads a comment up to its closing brace; comments do not
+// nest.
+func (l *lexer) braceComment(t token) (token, error) {
+	t.kind = tokComment
+	var b strings.Builder
+	for {
+		c, ok := l.read()
+		if !ok {
+			return t, l.errorf(t.line, t.col, "unterminated comment")
+		}
+		if c == '}' {
+			t.text = strings.Join(strings.Fields(b.String()), " ")
+			return t, nil
+		}
+		b.WriteRune(c)
+	}
+}
+
+func isDigit(c rune) bool { return c >= '0' && c <= '9' }
+
+func isSymbolStart(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c)
+}
+
+func isSymbolChar(c rune) bool {
+	return isSymbolStart(c) || strings.ContainsRune("_+#=:-/", c)
+}
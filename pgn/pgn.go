@@ -3,94 +3,46 @@
 package pgn
 
 import (
-	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 
-	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/board"
 	"github.com/mesb/mchess/socrates"
 )
 
-func isCoordToken(s string) bool {
-	if len(s) != 4 && len(s) != 5 {
-		return false
-	}
-	if s[0] < 'a' || s[0] > 'h' || s[2] < 'a' || s[2] > 'h' {
-		return false
-	}
-	if s[1] < '1' || s[1] > '8' || s[3] < '1' || s[3] > '8' {
-		return false
-	}
-	if len(s) == 5 {
-		c := s[4]
-		if c != 'q' && c != 'r' && c != 'b' && c != 'n' {
-			return false
-		}
-	}
-	return true
-}
-
-// Export converts the game log into a PGN string.
-func Export(log *socrates.Log) string {
-	if log == nil {
+// Export writes the game played on engine as export-format PGN, dated
+// today: see Record and Write. engine is rewound and replayed, and must
+// not be in use meanwhile.
+func Export(engine *socrates.RuleEngine) string {
+	if engine == nil || engine.Log == nil {
 		return ""
 	}
-	var builder strings.Builder
-	builder.WriteString("[Event \"MCHESS Game\"]\n")
-	builder.WriteString("[Site \"MCHESS Server\"]\n")
-	builder.WriteString("\n")
-
-	for i, m := range log.Moves() {
-		if i%2 == 0 {
-			builder.WriteString(fmt.Sprintf("%d. ", i/2+1))
-		}
-		builder.WriteString(fmt.Sprintf("%s%s ", formatSquare(m.From), formatSquare(m.To)))
-	}
-	builder.WriteString("*")
-	return builder.String()
+	g := Record(engine)
+	g.SetTag("Event", "MCHESS Game")
+	g.SetTag("Site", "MCHESS Server")
+	g.SetTag("Date", time.Now().Format("2006.01.02"))
+	var b strings.Builder
+	Write(&b, g)
+	return b.String()
 }
 
-// Import parses a PGN string and replays it on the engine.
+// Import reads the first game of a PGN text and replays its main line on
+// the engine, from the position of its FEN tag or else the standard start.
+// Text without a game leaves the engine as it was.
 func Import(engine *socrates.RuleEngine, data string) error {
-	words := strings.Fields(data)
-	for _, word := range words {
-		if strings.Contains(word, ".") || strings.Contains(word, "[") || strings.Contains(word, "]") {
-			continue
-		}
-		if !isCoordToken(word) && !isCoordToken(strings.TrimSuffix(strings.TrimSuffix(word, "+"), "#")) {
-			continue
-		}
-
-		// Handle standard moves (e2e4) and promotions (a7a8q)
-		// Simplified parsing for PGN replay
-		clean := strings.TrimSuffix(word, "+") // Remove check indicator if present
-		clean = strings.TrimSuffix(clean, "#") // Remove mate indicator
-		if !isCoordToken(clean) {
-			continue
-		}
-
-		from := parseSquare(clean[:2])
-		to := parseSquare(clean[2:4])
-		if from == nil || to == nil {
-			continue
-		}
-
-		var promo rune
-		if len(clean) == 5 {
-			promo = rune(clean[4])
-		}
-
-		if !engine.MakeMove(*from, *to, promo) {
-			return fmt.Errorf("illegal move in PGN: %s", word)
-		}
+	r := &Reader{lex: newLexer(strings.NewReader(data)), eng: engine}
+	if _, err := r.Read(); err != nil && err != io.EOF {
+		return err
 	}
 	return nil
 }
 
 // --- File Wrappers (Backward Compatibility) ---
 
-func Save(log *socrates.Log, filename string) error {
-	data := Export(log)
+func Save(engine *socrates.RuleEngine, filename string) error {
+	data := Export(engine)
 	return os.WriteFile(filename, []byte(data), 0644)
 }
 
@@ -104,19 +56,9 @@ func Load(engine *socrates.RuleEngine, filename string) error {
 
 // --- Helpers ---
 
-func formatSquare(a address.Addr) string {
-	return fmt.Sprintf("%c%d", a.File.Char(), int(a.Rank)+1)
-}
+// startFEN is the standard start position.
+var startFEN = board.InitStandard().ToFEN(board.NewGameState())
 
-func parseSquare(s string) *address.Addr {
-	if len(s) < 2 {
-		return nil
-	}
-	if s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
-		return nil
-	}
-	f := int(s[0] - 'a')
-	r := int(s[1] - '1')
-	a := address.MakeAddr(address.Rank(r), address.File(f))
-	return &a
+func isResult(s string) bool {
+	return s == "1-0" || s == "0-1" || s == "1/2-1/2" || s == "*"
 }
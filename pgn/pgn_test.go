@@ -21,10 +21,13 @@ func TestExportAndImportRoundTrip(t *testing.T) {
 		}
 	}
 
-	pgnData := Export(engine.Log)
-	if !strings.Contains(pgnData, "1. e2e4 e7e5 2. g1f3") {
+	pgnData := Export(engine)
+	if !strings.Contains(pgnData, "\n\n1. e4 e5 2. Nf3 *") {
 		t.Fatalf("unexpected PGN: %s", pgnData)
 	}
+	if !strings.Contains(pgnData, "[ECO \"C40\"]\n[Opening \"King's Knight Opening\"]\n") {
+		t.Fatalf("missing opening tags: %s", pgnData)
+	}
 
 	other := socrates.New(board.InitStandard())
 	if err := Import(other, pgnData); err != nil {
@@ -38,6 +41,54 @@ func TestExportAndImportRoundTrip(t *testing.T) {
 	}
 }
 
+func TestExportFromAPosition(t *testing.T) {
+	fen := "4k3/P7/8/8/8/8/8/4K3 b - - 3 40"
+	b, s, err := board.FromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := socrates.New(b)
+	engine.SetPosition(b, s)
+	for _, mv := range []string{"e8d7", "a7a8"} {
+		from, to := parseCoords(mv)
+		if !engine.MakeMove(from, to, 0) {
+			t.Fatalf("move %s failed", mv)
+		}
+	}
+
+	pgnData := Export(engine)
+	if !strings.Contains(pgnData, "[SetUp \"1\"]\n[FEN \""+fen+"\"]\n") || !strings.Contains(pgnData, "\n\n40... Kd7 41. a8=Q *\n") {
+		t.Fatalf("unexpected PGN: %s", pgnData)
+	}
+	other := socrates.New(board.InitStandard())
+	if err := Import(other, pgnData); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if got, want := other.Board.ToFEN(other.State), engine.Board.ToFEN(engine.State); got != want {
+		t.Fatalf("FEN mismatch after import:\nA: %s\nB: %s", want, got)
+	}
+}
+
+func TestImportSAN(t *testing.T) {
+	data := `[Event "Casual"]
+[Result "1-0"]
+
+1.e4 e5 2. Nf3 {the usual} Nc6 (2... d6 3. d4) 3. Bb5 $1 a6 ; Morphy
+4. Ba4 Nf6 5. O-O!? 1-0`
+	engine := socrates.New(board.InitStandard())
+	if err := Import(engine, data); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	want := "r1bqkb1r/1ppp1ppp/p1n2n2/4p3/B3P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 3 5"
+	if got := engine.Board.ToFEN(engine.State); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	if err := Import(socrates.New(board.InitStandard()), "1. e4 e5 2. Ke3"); err == nil || !strings.Contains(err.Error(), "Ke3") {
+		t.Fatalf("illegal move: got %v", err)
+	}
+}
+
 func parseCoords(m string) (address.Addr, address.Addr) {
 	from := address.MakeAddr(address.Rank(m[1]-'1'), address.File(m[0]-'a'))
 	to := address.MakeAddr(address.Rank(m[3]-'1'), address.File(m[2]-'a'))
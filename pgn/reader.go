@@ -0,0 +1,342 @@
+// --- pgn/reader.go ---
+
+package pgn
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/notation"
+	"github.com/mesb/mchess/socrates"
+)
+
+// Tag is a tag pair such as [White "Morphy"].
+type Tag struct {
+	Name, Value string
+}
+
+// Move is one move of a game or variation with what annotates it.
+type Move struct {
+	socrates.SimpleMove
+	SAN        string   // as written, without suffix annotations
+	NAGs       []int    // from "$14" and suffixes such as "!?" ($5)
+	Before     []string // comments before the first move of a line
+	Comments   []string // comments after the move
+	Variations [][]Move // alternatives to the move, from the position before it
+}
+
+// Game is one game of a PGN file.
+type Game struct {
+	Tags   []Tag  // in file order
+	Moves  []Move // the main line
+	Result string // the termination marker: "1-0", "0-1", "1/2-1/2" or "*"
+}
+
+// Tag returns the value of the tag called name, or "" if there is none.
+func (g *Game) Tag(name string) string {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// FEN returns the position the game starts from: its FEN tag, or the
+// standard start position.
+func (g *Game) FEN() string {
+	if fen := g.Tag("FEN"); fen != "" {
+		return fen
+	}
+	return startFEN
+}
+
+// Play sets up the game's start position on engine and plays the main line.
+func (g *Game) Play(engine *socrates.RuleEngine) error {
+	if err := setUp(engine, g.FEN()); err != nil {
+		return err
+	}
+	for _, m := range g.Moves {
+		if !engine.MakeMove(m.From, m.To, m.Promo) {
+			return errors.New("illegal move in PGN: " + m.SAN)
+		}
+	}
+	return nil
+}
+
+// suffixNAGs are the NAGs that suffix annotations stand for.
+var suffixNAGs = map[string]int{"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6}
+
+// Reader reads the games of a PGN stream one after another. It accepts
+// the import format of the standard: SAN moves with or without check
+// marks, move numbers, brace and rest-of-line comments, NAGs and suffix
+// annotations, nested variations, escaped lines and FEN/SetUp tags. Moves
+// are checked for legality as they are read.
+type Reader struct {
+	lex *lexer
+	eng *socrates.RuleEngine
+}
+
+// NewReader returns a reader of the games in r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{lex: newLexer(r)}
+}
+
+// Read returns the next game, or io.EOF after the last one. Malformed
+// input and illegal moves are reported as a *SyntaxError; the rest of the
+// bad game is then skipped, so reading can go on with the next one.
+func (r *Reader) Read() (*Game, error) {
+	g, err := r.game()
+	var syntax *SyntaxError
+	if errors.As(err, &syntax) {
+		r.skipGame()
+	}
+	return g, err
+}
+
+// ReadAll reads every game of r, stopping at the first error.
+func ReadAll(r io.Reader) ([]*Game, error) {
+	var games []*Game
+	rd := NewReader(r)
+	for {
+		g, err := rd.Read()
+		if err == io.EOF {
+			return games, nil
+		}
+		if err != nil {
+			return games, err
+		}
+		games = append(games, g)
+	}
+}
+
+func (r *Reader) game() (*Game, error) {
+	// Comments before the tags belong to no game.
+	t, err := r.lex.peek()
+	for err == nil && t.kind == tokComment {
+		r.lex.next()
+		t, err = r.lex.peek()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.kind == tokEOF {
+		return nil, io.EOF
+	}
+
+	g := &Game{}
+	var setup token
+	for t.kind == tokLBracket {
+		tag, value, err := r.tag()
+		if err != nil {
+			return nil, err
+		}
+		g.Tags = append(g.Tags, tag)
+		if tag.Name == "FEN" || tag.Name == "SetUp" && tag.Value == "1" && setup.kind == tokEOF {
+			setup = value
+		}
+		if t, err = r.lex.peek(); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.eng == nil {
+		r.eng = socrates.New(board.InitStandard())
+	}
+	if err := setUp(r.eng, g.FEN()); err != nil {
+		return nil, &SyntaxError{Line: setup.line, Col: setup.col, Msg: "bad FEN: " + err.Error()}
+	}
+	if g.Tag("SetUp") == "1" && g.Tag("FEN") == "" {
+		return nil, &SyntaxError{Line: setup.line, Col: setup.col, Msg: "SetUp tag without a FEN tag"}
+	}
+
+	if g.Moves, err = r.line(0); err != nil {
+		return nil, err
+	}
+	// The termination marker; a game cut short by the next one or the end
+	// of the file takes its result from its tag.
+	switch t, _ := r.lex.peek(); {
+	case t.kind == tokAsterisk || t.kind == tokSymbol:
+		r.lex.next()
+		g.Result = t.text
+	case isResult(g.Tag("Result")):
+		g.Result = g.Tag("Result")
+	default:
+		g.Result = "*"
+	}
+	return g, nil
+}
+
+// tag reads [Name "value"], returning the value's token for errors.
+func (r *Reader) tag() (Tag, token, error) {
+	var tag Tag
+	r.lex.next() // [
+	name, err := r.expect(tokSymbol, "tag name")
+	if err != nil {
+		return tag, name, err
+	}
+	value, err := r.expect(tokString, "tag value")
+	if err != nil {
+		return tag, value, err
+	}
+	if _, err := r.expect(tokRBracket, `"]"`); err != nil {
+		return tag, value, err
+	}
+	return Tag{Name: name.text, Value: value.text}, value, nil
+}
+
+func (r *Reader) expect(k kind, what string) (token, error) {
+	t, err := r.lex.next()
+	if err != nil {
+		return t, err
+	}
+	if t.kind != k {
+		return t, &SyntaxError{Line: t.line, Col: t.col, Msg: "expected " + what + ", got " + t.String()}
+	}
+	return t, nil
+}
+
+// line reads the moves of the main line (depth 0) or of a variation up to
+// its closing parenthesis, playing them on the engine. A variation's moves
+// are taken back before it returns; the main line's stay played.
+func (r *Reader) line(depth int) ([]Move, error) {
+	var moves []Move
+	var before []string
+	fail := func(t token, msg string) ([]Move, error) {
+		return moves, &SyntaxError{Line: t.line, Col: t.col, Msg: msg}
+	}
+	for {
+		t, err := r.lex.peek()
+		if err != nil {
+			return moves, err
+		}
+		switch t.kind {
+		case tokEOF, tokLBracket, tokAsterisk:
+			if depth > 0 {
+				return fail(t, "variation not closed before "+t.String())
+			}
+			return moves, nil
+		case tokRParen:
+			if depth == 0 {
+				return fail(t, `")" closes no variation`)
+			}
+			r.lex.next()
+			for range moves {
+				r.eng.UndoMove()
+			}
+			return moves, nil
+		case tokLParen:
+			r.lex.next()
+			if len(moves) == 0 {
+				return fail(t, "variation before any move")
+			}
+			last := &moves[len(moves)-1]
+			r.eng.UndoMove()
+			v, err := r.line(depth + 1)
+			if err != nil {
+				return moves, err
+			}
+			r.eng.MakeMove(last.From, last.To, last.Promo)
+			last.Variations = append(last.Variations, v)
+		case tokComment:
+			r.lex.next()
+			if len(moves) == 0 {
+				before = append(before, t.text)
+			} else {
+				last := &moves[len(moves)-1]
+				last.Comments = append(last.Comments, t.text)
+			}
+		case tokNAG, tokAnnotation:
+			r.lex.next()
+			nag, ok := suffixNAGs[t.text]
+			if t.kind == tokNAG {
+				n, err := strconv.Atoi(t.text)
+				nag, ok = n, err == nil && n <= 255
+			}
+			if !ok {
+				return fail(t, "bad annotation "+t.String())
+			}
+			if len(moves) == 0 {
+				return fail(t, "annotation before any move")
+			}
+			last := &moves[len(moves)-1]
+			last.NAGs = append(last.NAGs, nag)
+		case tokPeriod:
+			r.lex.next() // as in "1. ... e5"
+		case tokSymbol:
+			if isResult(t.text) {
+				if depth > 0 {
+					return fail(t, "variation not closed before "+t.String())
+				}
+				return moves, nil
+			}
+			r.lex.next()
+			if isNumber(t.text) || t.text == "e.p." {
+				continue // move numbers are not checked
+			}
+			m, err := notation.ParseSAN(r.eng, t.text)
+			if err != nil {
+				return fail(t, err.Error())
+			}
+			r.eng.MakeMove(m.From, m.To, m.Promo)
+			moves = append(moves, Move{SimpleMove: m, SAN: t.text, Before: before})
+			before = nil
+		default:
+			r.lex.next()
+			return fail(t, "unexpected "+t.String()+" in movetext")
+		}
+	}
+}
+
+// skipGame reads past the rest of a bad game: up to its termination
+// marker, or to a tag at the start of a line once movetext has been seen.
+func (r *Reader) skipGame() {
+	inTag, movetext := false, false
+	for {
+		t, err := r.lex.peek()
+		if err != nil {
+			if r.lex.err != nil {
+				return // the stream itself failed
+			}
+			r.lex.next() // a malformed token
+			continue
+		}
+		switch {
+		case t.kind == tokEOF:
+			return
+		case t.kind == tokLBracket && t.col == 1 && movetext:
+			return
+		case t.kind == tokLBracket:
+			inTag = true
+		case t.kind == tokRBracket:
+			inTag = false
+		case t.kind == tokAsterisk || t.kind == tokSymbol && isResult(t.text):
+			r.lex.next()
+			return
+		case !inTag:
+			movetext = true
+		}
+		r.lex.next()
+	}
+}
+
+// setUp puts engine at the start of a game from fen.
+func setUp(engine *socrates.RuleEngine, fen string) error {
+	b, s, err := board.FromFEN(fen)
+	if err != nil {
+		return err
+	}
+	engine.SetPosition(b, s)
+	return nil
+}
+
+func isNumber(s string) bool {
+	for _, c := range s {
+		if !isDigit(c) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,176 @@
+package pgn
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/socrates"
+)
+
+const annotated = `% exported by hand
+[Event "The \"Immortal\" Game"]
+[Site "London \\ Simpson's Divan"]
+[White "Anderssen"]
+[Black "Kieseritzky"]
+[Result "1-0"]
+
+{King's Gambit} 1.e4 e5 2. f4 exf4 3. Bc4 Qh4+ $2 4. Kf1 b5?! ; Bryan's
+{countergambit}
+5. Bxb5 Nf6 6. Nf3 (6. Nc3 c6 (6... Bb7) 7. Nf3) 6... Qh6 7. d3!? 1-0
+`
+
+func TestReadAnnotatedGame(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(annotated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("%d games", len(games))
+	}
+	g := games[0]
+	if got := g.Tag("Event"); got != `The "Immortal" Game` {
+		t.Errorf("Event %q", got)
+	}
+	if got := g.Tag("Site"); got != `London \ Simpson's Divan` {
+		t.Errorf("Site %q", got)
+	}
+	if g.Result != "1-0" || g.Tag("Round") != "" || len(g.Tags) != 5 {
+		t.Errorf("result %q, tags %v", g.Result, g.Tags)
+	}
+
+	var sans []string
+	for _, m := range g.Moves {
+		sans = append(sans, m.SAN)
+	}
+	want := []string{"e4", "e5", "f4", "exf4", "Bc4", "Qh4+", "Kf1", "b5", "Bxb5", "Nf6", "Nf3", "Qh6", "d3"}
+	if !reflect.DeepEqual(sans, want) {
+		t.Fatalf("moves %q, want %q", sans, want)
+	}
+	if m := g.Moves[0]; !reflect.DeepEqual(m.Before, []string{"King's Gambit"}) || m.SimpleMove.String() != "e2e4" {
+		t.Errorf("first move %+v", m)
+	}
+	if !reflect.DeepEqual(g.Moves[5].NAGs, []int{2}) || !reflect.DeepEqual(g.Moves[7].NAGs, []int{6}) || !reflect.DeepEqual(g.Moves[12].NAGs, []int{5}) {
+		t.Errorf("NAGs %v %v %v", g.Moves[5].NAGs, g.Moves[7].NAGs, g.Moves[12].NAGs)
+	}
+	if got := g.Moves[7].Comments; !reflect.DeepEqual(got, []string{"Bryan's", "countergambit"}) {
+		t.Errorf("comments %q", got)
+	}
+
+	vars := g.Moves[10].Variations
+	if len(vars) != 1 || len(vars[0]) != 3 || vars[0][0].SAN != "Nc3" || vars[0][2].SimpleMove.String() != "g1f3" {
+		t.Fatalf("variation %+v", vars)
+	}
+	if sub := vars[0][1].Variations; len(sub) != 1 || len(sub[0]) != 1 || sub[0][0].SimpleMove.String() != "c8b7" {
+		t.Fatalf("nested variation %+v", sub)
+	}
+
+	eng := socrates.New(board.InitStandard())
+	if err := g.Play(eng); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := eng.Board.ToFEN(eng.State), "rnb1kb1r/p1pp1ppp/5n1q/1B6/4Pp2/3P1N2/PPP3PP/RNBQ1K1R b kq - 0 7"; got != want {
+		t.Errorf("after the main line: %s, want %s", got, want)
+	}
+}
+
+func TestReadSeveralGamesAndSetUp(t *testing.T) {
+	data := `[Event "one"]
+[Result "*"]
+
+1. d4 *
+
+[Event "two"]
+[SetUp "1"]
+[FEN "4k3/P7/8/8/8/8/8/4K3 b - - 0 40"]
+
+40... Kd7 41. a8=N 1/2-1/2
+[Event "three"]
+1. e4`
+	r := NewReader(strings.NewReader(data))
+	var events, results []string
+	for {
+		g, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		events, results = append(events, g.Tag("Event")), append(results, g.Result)
+		if g.Tag("Event") == "two" {
+			if g.FEN() != "4k3/P7/8/8/8/8/8/4K3 b - - 0 40" || len(g.Moves) != 2 || g.Moves[1].Promo != 'n' {
+				t.Errorf("game two: %s %+v", g.FEN(), g.Moves)
+			}
+		}
+	}
+	if !reflect.DeepEqual(events, []string{"one", "two", "three"}) || !reflect.DeepEqual(results, []string{"*", "1/2-1/2", "*"}) {
+		t.Fatalf("events %q, results %q", events, results)
+	}
+}
+
+func TestReadErrorsCarryPositions(t *testing.T) {
+	tests := []struct {
+		data      string
+		line, col int
+		msg       string
+	}{
+		{"1. e4 e5 2. Ke3 *", 1, 13, "no such legal move"},
+		{"[Event \"x\"]\n\n1. e4 (1. d4 d5\n*", 4, 1, "variation not closed"},
+		{"1. e4 ) *", 1, 7, "closes no variation"},
+		{"1. ( e4 ) *", 1, 4, "variation before any move"},
+		{"[Event \"x]\n1. e4 *", 1, 8, "unterminated string"},
+		{"[Event x]\n1. e4 *", 1, 8, "expected tag value"},
+		{"1. e4 {good *", 1, 7, "unterminated comment"},
+		{"1. e4 $ *", 1, 7, "NAG needs a number"},
+		{"1. e4 & *", 1, 7, `unexpected '&'`},
+		{"[SetUp \"1\"]\n1. e4 *", 1, 8, "SetUp tag without a FEN tag"},
+		{"[FEN \"8/8/8 w - - 0 1\"]\n1. e4 *", 1, 6, "bad FEN"},
+		{"1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Bxc6 dxc6\n5. O-O-O *", 2, 4, "castling is not legal"},
+	}
+	for _, tt := range tests {
+		_, err := NewReader(strings.NewReader(tt.data)).Read()
+		var syntax *SyntaxError
+		if !errors.As(err, &syntax) {
+			t.Errorf("%q: got %v, want a syntax error", tt.data, err)
+			continue
+		}
+		if syntax.Line != tt.line || syntax.Col != tt.col || !strings.Contains(syntax.Msg, tt.msg) {
+			t.Errorf("%q: got %v, want %d:%d %s", tt.data, err, tt.line, tt.col, tt.msg)
+		}
+	}
+}
+
+func TestReadGoesOnAfterABadGame(t *testing.T) {
+	data := `[Event "good"]
+
+1. e4 e5 1-0
+
+[Event "bad"]
+
+1. e4 e5 2. Ke3 (2. Nf3) Nc6 0-1
+
+[Event "also good"]
+
+1. d4 *
+`
+	r := NewReader(strings.NewReader(data))
+	var got []string
+	for {
+		g, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			got = append(got, "error")
+			continue
+		}
+		got = append(got, g.Tag("Event"))
+	}
+	if want := []string{"good", "error", "also good"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
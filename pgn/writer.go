@@ -0,0 +1,222 @@
+// --- pgn/writer.go ---
+
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/eco"
+	"github.com/mesb/mchess/notation"
+	"github.com/mesb/mchess/pieces"
+	"github.com/mesb/mchess/socrates"
+)
+
+// sevenTagRoster lists the tags every exported game carries, in order.
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// lineWidth bounds movetext lines: the standard keeps them under 80
+// columns.
+const lineWidth = 79
+
+// SetTag sets the tag called name, replacing its value if g has it.
+func (g *Game) SetTag(name, value string) {
+	for i, t := range g.Tags {
+		if t.Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, Tag{Name: name, Value: value})
+}
+
+// Record returns the game played on engine, ready for Write: its start
+// position and moves from the log in SAN, the result the board shows ("*"
+// while the game goes on) and the SetUp/FEN, ECO/Opening/Variation,
+// Termination and PlyCount tags. The Seven Tag Roster is left to the
+// caller. engine is rewound and replayed, and must not be in use meanwhile.
+func Record(engine *socrates.RuleEngine) *Game {
+	g := &Game{Result: "*"}
+	fen, played := notation.Played(engine)
+	for range played {
+		engine.UndoMove()
+	}
+	moves, _ := notation.Line(engine, played) // the moves were legal when played
+	for i, m := range played {
+		engine.MakeMove(m.From, m.To, m.Promo)
+		g.Moves = append(g.Moves, Move{SimpleMove: m, SAN: moves[i]})
+	}
+	if fen != startFEN {
+		g.SetTag("SetUp", "1")
+		g.SetTag("FEN", fen)
+	}
+	if o, ok := eco.Classify(fen, played); ok {
+		g.SetTag("ECO", o.ECO)
+		g.SetTag("Opening", o.Name)
+		if o.Variation != "" {
+			g.SetTag("Variation", o.Variation)
+		}
+	}
+	termination := "unterminated"
+	if score, over := engine.GameResult(); over {
+		g.Result, termination = resultString(score), "normal"
+	}
+	g.SetTag("Termination", termination)
+	g.SetTag("PlyCount", strconv.Itoa(len(moves)))
+	return g
+}
+
+func resultString(white float64) string {
+	switch white {
+	case 1:
+		return "1-0"
+	case 0:
+		return "0-1"
+	}
+	return "1/2-1/2"
+}
+
+// Write writes g in the export format of the PGN standard: the Seven Tag
+// Roster first, "?" standing for what is unknown, then g's other tags in
+// their order; the movetext with move numbers, comments, NAGs and
+// variations, wrapped before column 80; then g.Result, which is also the
+// Result tag. Moves are written as their SAN fields.
+func Write(w io.Writer, g *Game) error {
+	result := g.Result
+	if result == "" {
+		result = "*"
+	}
+	var b strings.Builder
+	for _, name := range sevenTagRoster {
+		value := g.Tag(name)
+		switch {
+		case name == "Result":
+			value = result
+		case value == "" && name == "Date":
+			value = "????.??.??"
+		case value == "":
+			value = "?"
+		}
+		writeTag(&b, name, value)
+	}
+	for _, t := range g.Tags {
+		if !isRosterTag(t.Name) {
+			writeTag(&b, t.Name, t.Value)
+		}
+	}
+	b.WriteByte('\n')
+
+	mt := movetext{}
+	ply := 0
+	if _, s, err := board.FromFEN(g.FEN()); err == nil {
+		ply = 2 * max(s.FullmoveNumber-1, 0)
+		if s.Turn == pieces.BLACK {
+			ply++
+		}
+	}
+	mt.line(g.Moves, ply)
+	mt.word(result)
+	mt.wrap(&b)
+	b.WriteString("\n\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func isRosterTag(name string) bool {
+	for _, r := range sevenTagRoster {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTag writes a tag pair, escaping quotes and backslashes.
+func writeTag(b *strings.Builder, name, value string) {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+	fmt.Fprintf(b, "[%s \"%s\"]\n", name, value)
+}
+
+// movetext collects the words of the movetext for wrapping; a variation's
+// parentheses stick to the words they enclose.
+type movetext struct {
+	words []string
+	open  string // parentheses waiting for the next word
+}
+
+func (mt *movetext) word(s string) {
+	mt.words = append(mt.words, mt.open+s)
+	mt.open = ""
+}
+
+// comment adds a brace comment, word by word so that a long one can be
+// wrapped; comments cannot contain a closing brace.
+func (mt *movetext) comment(text string) {
+	words := strings.Fields(strings.ReplaceAll(text, "}", ""))
+	if len(words) == 0 {
+		mt.word("{}")
+		return
+	}
+	words[0] = "{" + words[0]
+	words[len(words)-1] += "}"
+	for _, w := range words {
+		mt.word(w)
+	}
+}
+
+// line adds moves starting at ply (0 is White's first move), numbering
+// White's moves and Black's where they do not follow White's.
+func (mt *movetext) line(moves []Move, ply int) {
+	number := true // Black's move needs its number
+	for _, m := range moves {
+		for _, c := range m.Before {
+			mt.comment(c)
+		}
+		if ply%2 == 0 {
+			mt.word(fmt.Sprintf("%d.", ply/2+1))
+		} else if number {
+			mt.word(fmt.Sprintf("%d...", ply/2+1))
+		}
+		mt.word(m.SAN)
+		number = false
+		for _, n := range m.NAGs {
+			mt.word("$" + strconv.Itoa(n))
+		}
+		for _, c := range m.Comments {
+			mt.comment(c)
+			number = true
+		}
+		for _, v := range m.Variations {
+			if len(v) == 0 {
+				mt.word("()") // nothing to close the parenthesis onto
+			} else {
+				mt.open += "("
+				mt.line(v, ply)
+				mt.words[len(mt.words)-1] += ")"
+			}
+			number = true
+		}
+		ply++
+	}
+}
+
+// wrap writes the words, breaking lines before they grow past lineWidth.
+func (mt *movetext) wrap(b *strings.Builder) {
+	n := 0
+	for i, w := range mt.words {
+		if i > 0 {
+			if n+1+len(w) > lineWidth {
+				b.WriteByte('\n')
+				n = 0
+			} else {
+				b.WriteByte(' ')
+				n++
+			}
+		}
+		b.WriteString(w)
+		n += len(w)
+	}
+}
@@ -0,0 +1,142 @@
+package pgn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/socrates"
+)
+
+func TestWriteExportFormat(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(annotated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := Write(&b, games[0]); err != nil {
+		t.Fatal(err)
+	}
+	want := `[Event "The \"Immortal\" Game"]
+[Site "London \\ Simpson's Divan"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Anderssen"]
+[Black "Kieseritzky"]
+[Result "1-0"]
+
+{King's Gambit} 1. e4 e5 2. f4 exf4 3. Bc4 Qh4+ $2 4. Kf1 b5 $6 {Bryan's}
+{countergambit} 5. Bxb5 Nf6 6. Nf3 (6. Nc3 c6 (6... Bb7) 7. Nf3) 6... Qh6 7. d3
+$5 1-0
+
+`
+	if got := b.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// What is written reads back as the same game.
+	again, err := ReadAll(strings.NewReader(b.String()))
+	if err != nil || len(again) != 1 {
+		t.Fatalf("reading back: %v", err)
+	}
+	var c strings.Builder
+	Write(&c, again[0])
+	if c.String() != want {
+		t.Fatalf("second write:\n%s", c.String())
+	}
+}
+
+func TestWriteEmptyVariation(t *testing.T) {
+	games, err := ReadAll(strings.NewReader("1. e4 ( ) e5 *"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := games[0].Moves[0].Variations; len(v) != 1 || len(v[0]) != 0 {
+		t.Fatalf("variations %v, want one empty one", v)
+	}
+	var b strings.Builder
+	if err := Write(&b, games[0]); err != nil {
+		t.Fatal(err)
+	}
+	movetext := b.String()[strings.LastIndex(b.String(), "]\n\n")+3:]
+	if want := "1. e4 () 1... e5 *\n\n"; movetext != want {
+		t.Fatalf("got %q, want %q", movetext, want)
+	}
+	again, err := ReadAll(strings.NewReader(b.String()))
+	if err != nil || len(again) != 1 || len(again[0].Moves) != 2 || len(again[0].Moves[0].Variations) != 1 {
+		t.Fatalf("reading back: %v %+v", err, again)
+	}
+}
+
+func TestWriteWrapsLongMovetext(t *testing.T) {
+	eng := socrates.New(board.InitStandard())
+	moves := strings.Fields("g1f3 g8f6 f3g1 f6g8")
+	for i := 0; i < 12; i++ {
+		for _, mv := range moves[:2] {
+			from, to := parseCoords(mv)
+			eng.MakeMove(from, to, 0)
+		}
+		moves[0], moves[1], moves[2], moves[3] = moves[2], moves[3], moves[0], moves[1]
+	}
+	g := Record(eng)
+	for i := range g.Moves {
+		g.Moves[i].Comments = []string{"a rather long comment that goes on and on"}
+	}
+	var b strings.Builder
+	Write(&b, g)
+	for _, line := range strings.Split(b.String(), "\n") {
+		if len(line) >= 80 {
+			t.Errorf("%d columns: %q", len(line), line)
+		}
+	}
+	again, err := ReadAll(strings.NewReader(b.String()))
+	if err != nil || len(again) != 1 || len(again[0].Moves) != 24 || again[0].Moves[23].Comments[0] != g.Moves[23].Comments[0] {
+		t.Fatalf("reading back: %v\n%s", err, b.String())
+	}
+}
+
+func TestRecord(t *testing.T) {
+	eng := socrates.New(board.InitStandard())
+	for _, mv := range []string{"f2f3", "e7e5", "g2g4"} {
+		from, to := parseCoords(mv)
+		eng.MakeMove(from, to, 0)
+	}
+	g := Record(eng)
+	if g.Result != "*" || g.Tag("Termination") != "unterminated" || g.Tag("PlyCount") != "3" || g.Tag("FEN") != "" {
+		t.Fatalf("game in progress: %s %v", g.Result, g.Tags)
+	}
+
+	from, to := parseCoords("d8h4")
+	eng.MakeMove(from, to, 0)
+	g = Record(eng)
+	if g.Result != "0-1" || g.Tag("Termination") != "normal" || g.Tag("PlyCount") != "4" || g.Moves[3].SAN != "Qh4#" {
+		t.Fatalf("mate: %s %v %+v", g.Result, g.Tags, g.Moves)
+	}
+
+	b, s, _ := board.FromFEN("4k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	eng = socrates.New(b)
+	eng.SetPosition(b, s)
+	from, to = parseCoords("a7a8")
+	eng.MakeMove(from, to, 'r')
+	g = Record(eng)
+	if g.Tag("SetUp") != "1" || g.Tag("FEN") != "4k3/P7/8/8/8/8/8/4K3 w - - 0 1" || g.Moves[0].Promo != 'r' || g.Moves[0].SAN != "a8=R+" {
+		t.Fatalf("promotion from a position: %v %+v", g.Tags, g.Moves)
+	}
+}
+
+func TestRecordClassifiesOnlyStandardStarts(t *testing.T) {
+	b, s, _ := board.FromFEN("4k3/8/8/8/8/8/4P3/4K1N1 w - - 0 1")
+	eng := socrates.New(b)
+	eng.SetPosition(b, s)
+	from, to := parseCoords("g1f3")
+	eng.MakeMove(from, to, 0)
+	if g := Record(eng); g.Tag("ECO") != "" || g.Tag("Opening") != "" {
+		t.Fatalf("a game set up from a FEN got an opening: %v", g.Tags)
+	}
+
+	eng = socrates.New(board.InitStandard())
+	eng.MakeMove(from, to, 0)
+	if g := Record(eng); g.Tag("ECO") != "A04" {
+		t.Fatalf("1. Nf3 from the start: %v", g.Tags)
+	}
+}
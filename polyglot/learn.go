@@ -0,0 +1,183 @@
+// --- polyglot/learn.go ---
+
+package polyglot
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Step is a book move the engine played: the position key, the encoded move
+// and the colour that played it.
+type Step struct {
+	Key   uint64
+	Move  uint16
+	Color int
+}
+
+// Result counts game outcomes after a book move, from the mover's side.
+type Result struct {
+	Wins, Draws, Losses int
+}
+
+// DropLosses is how many more losses than wins retire a book move.
+const DropLosses = 3
+
+// learnScale keeps adjusted weights integral when they shrink.
+const learnScale = 64
+
+type learnKey struct {
+	key  uint64
+	move uint16
+}
+
+// Learning holds results of the engine's own games per book move. It is
+// kept apart from the book so the source file is never rewritten, and is
+// safe to share between engines.
+type Learning struct {
+	mu      sync.RWMutex
+	results map[learnKey]*Result
+}
+
+// NewLearning returns an empty record.
+func NewLearning() *Learning {
+	return &Learning{results: map[learnKey]*Result{}}
+}
+
+// Record credits a finished game to every step. score is White's result:
+// 1 for a win, 0.5 for a draw, 0 for a loss.
+func (l *Learning) Record(steps []Step, score float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, s := range steps {
+		res := l.results[learnKey{s.Key, s.Move}]
+		if res == nil {
+			res = &Result{}
+			l.results[learnKey{s.Key, s.Move}] = res
+		}
+		mine := score
+		if s.Color == 1 {
+			mine = 1 - score
+		}
+		switch {
+		case mine > 0.5:
+			res.Wins++
+		case mine < 0.5:
+			res.Losses++
+		default:
+			res.Draws++
+		}
+	}
+}
+
+// Result returns what is known about a book move.
+func (l *Learning) Result(key uint64, move uint16) Result {
+	if l == nil {
+		return Result{}
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if res := l.results[learnKey{key, move}]; res != nil {
+		return *res
+	}
+	return Result{}
+}
+
+// Adjust scales a book weight by the move's record: up for wins, down for
+// losses, and to 0 once it has lost DropLosses games more than it won.
+// Every weight is scaled by the same constant, so only the ratios between
+// candidates carry meaning.
+func (l *Learning) Adjust(key uint64, move uint16, weight int) int {
+	res := l.Result(key, move)
+	if res.Losses-res.Wins >= DropLosses {
+		return 0
+	}
+	return weight * learnScale * (2 + 2*res.Wins + res.Draws) / (2 + 2*res.Losses + res.Draws)
+}
+
+// Len returns the number of book moves with a record.
+func (l *Learning) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.results)
+}
+
+// OpenLearning reads a learning file; a missing file is an empty record.
+func OpenLearning(path string) (*Learning, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewLearning(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadLearning(f)
+}
+
+// ReadLearning parses lines of "key move wins draws losses", with key and
+// move in hex as they appear in the book.
+func ReadLearning(r io.Reader) (*Learning, error) {
+	l := NewLearning()
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var k learnKey
+		var res Result
+		if _, err := fmt.Sscanf(line, "%x %x %d %d %d", &k.key, &k.move, &res.Wins, &res.Draws, &res.Losses); err != nil {
+			return nil, fmt.Errorf("polyglot: learning line %d: %v", n, err)
+		}
+		l.results[k] = &res
+	}
+	return l, sc.Err()
+}
+
+// Write stores the record in key order.
+func (l *Learning) Write(w io.Writer) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	keys := make([]learnKey, 0, len(l.results))
+	for k := range l.results {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].key != keys[j].key {
+			return keys[i].key < keys[j].key
+		}
+		return keys[i].move < keys[j].move
+	})
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# key move wins draws losses")
+	for _, k := range keys {
+		res := l.results[k]
+		fmt.Fprintf(bw, "%016x %04x %d %d %d\n", k.key, k.move, res.Wins, res.Draws, res.Losses)
+	}
+	return bw.Flush()
+}
+
+// Save writes the record to path, replacing it atomically so a crash never
+// leaves half a file.
+func (l *Learning) Save(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := l.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,306 @@
+// --- polyglot/polyglot.go ---
+
+// Package polyglot reads and writes opening books in the Polyglot .bin
+// format used by most chess GUIs and engines.
+//
+// A book is a sequence of 16-byte big-endian entries sorted by position key:
+// the 64-bit Zobrist key of the position (computed with the fixed Polyglot
+// random table, not the engine's own hash), a 16-bit move, a 16-bit weight
+// and a 32-bit learn field. Castling moves are stored as the king capturing
+// its own rook (e1h1); Move translates them to the usual king move.
+package polyglot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/pieces"
+)
+
+// EntrySize is the size of one book entry on disk.
+const EntrySize = 16
+
+// ErrCorrupt reports a file whose length is not a whole number of entries.
+var ErrCorrupt = errors.New("polyglot: corrupt book file")
+
+// Entry is one book move for one position.
+type Entry struct {
+	Key    uint64
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+// Move is a decoded book move in engine coordinates.
+type Move struct {
+	From, To address.Addr
+	Promo    rune // 0, or 'n', 'b', 'r', 'q'
+}
+
+// String returns the move in coordinate notation, e.g. "e2e4" or "a7a8q".
+func (m Move) String() string {
+	s := fmt.Sprintf("%c%d%c%d", m.From.File.Char(), m.From.Rank+1, m.To.File.Char(), m.To.Rank+1)
+	if m.Promo != 0 {
+		s += string(m.Promo)
+	}
+	return s
+}
+
+// Book is an in-memory Polyglot book.
+type Book struct {
+	entries []Entry // sorted by key
+}
+
+// Open reads a book file.
+func Open(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read loads a book from r.
+func Read(r io.Reader) (*Book, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%EntrySize != 0 {
+		return nil, ErrCorrupt
+	}
+	entries := make([]Entry, len(data)/EntrySize)
+	for i := range entries {
+		e := data[i*EntrySize:]
+		entries[i] = Entry{
+			Key:    binary.BigEndian.Uint64(e[0:8]),
+			Move:   binary.BigEndian.Uint16(e[8:10]),
+			Weight: binary.BigEndian.Uint16(e[10:12]),
+			Learn:  binary.BigEndian.Uint32(e[12:16]),
+		}
+	}
+	// Books are sorted by key; only the relative order of moves within a
+	// position (by falling weight, by convention) is preserved here.
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return &Book{entries: entries}, nil
+}
+
+// New builds a book from entries in any order.
+func New(entries []Entry) *Book {
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Key != sorted[j].Key {
+			return sorted[i].Key < sorted[j].Key
+		}
+		return sorted[i].Weight > sorted[j].Weight
+	})
+	return &Book{entries: sorted}
+}
+
+// Len returns the number of entries.
+func (b *Book) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.entries)
+}
+
+// Entries returns every entry in key order (read-only).
+func (b *Book) Entries() []Entry {
+	return b.entries
+}
+
+// Lookup returns the entries stored for key.
+func (b *Book) Lookup(key uint64) []Entry {
+	if b == nil {
+		return nil
+	}
+	i := sort.Search(len(b.entries), func(i int) bool { return b.entries[i].Key >= key })
+	j := i
+	for j < len(b.entries) && b.entries[j].Key == key {
+		j++
+	}
+	return b.entries[i:j]
+}
+
+// Probe returns the decoded moves and weights for a position.
+func (b *Book) Probe(bd *board.Board, s *board.GameState) ([]Move, []uint16) {
+	entries := b.Lookup(Key(bd, s))
+	moves := make([]Move, 0, len(entries))
+	weights := make([]uint16, 0, len(entries))
+	for _, e := range entries {
+		moves = append(moves, DecodeMove(bd, e.Move))
+		weights = append(weights, e.Weight)
+	}
+	return moves, weights
+}
+
+// Pick chooses a book move at random in proportion to the entry weights.
+// Entries of weight 0 are never played. ok is false when the position is not
+// in the book.
+func (b *Book) Pick(bd *board.Board, s *board.GameState, rnd *rand.Rand) (m Move, ok bool) {
+	moves, weights := b.Probe(bd, s)
+	total := 0
+	for _, w := range weights {
+		total += int(w)
+	}
+	if total == 0 {
+		return Move{}, false
+	}
+	n := rnd.Intn(total)
+	for i, w := range weights {
+		if n < int(w) {
+			return moves[i], true
+		}
+		n -= int(w)
+	}
+	return Move{}, false
+}
+
+// Write stores the book in Polyglot format.
+func (b *Book) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var buf [EntrySize]byte
+	for _, e := range b.entries {
+		binary.BigEndian.PutUint64(buf[0:8], e.Key)
+		binary.BigEndian.PutUint16(buf[8:10], e.Move)
+		binary.BigEndian.PutUint16(buf[10:12], e.Weight)
+		binary.BigEndian.PutUint32(buf[12:16], e.Learn)
+		if _, err := bw.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Save writes the book to path.
+func (b *Book) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := b.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Key computes the Polyglot key of a position. The en passant file only
+// counts when a pawn of the side to move stands ready to capture.
+func Key(b *board.Board, s *board.GameState) uint64 {
+	var key uint64
+	b.ForEachPiece(func(sq address.Addr, p pieces.Piece) {
+		key ^= random64[64*pieceKind(p)+sq.Index()]
+	})
+	for i, c := range "KQkq" {
+		for _, r := range s.CastlingRights {
+			if r == c {
+				key ^= random64[768+i]
+			}
+		}
+	}
+	if ep := s.EnPassant; ep != nil && canCaptureEnPassant(b, s.Turn, *ep) {
+		key ^= random64[772+int(ep.File)]
+	}
+	if s.Turn == pieces.WHITE {
+		key ^= random64[780]
+	}
+	return key
+}
+
+// pieceKind maps a piece onto the Polyglot order bp wp bn wn ... bk wk.
+func pieceKind(p pieces.Piece) int {
+	kind := 0
+	switch p.(type) {
+	case *pieces.Knight:
+		kind = 1
+	case *pieces.Bishop:
+		kind = 2
+	case *pieces.Rook:
+		kind = 3
+	case *pieces.Queen:
+		kind = 4
+	case *pieces.King:
+		kind = 5
+	}
+	white := 0
+	if p.Color() == pieces.WHITE {
+		white = 1
+	}
+	return 2*kind + white
+}
+
+func canCaptureEnPassant(b *board.Board, turn int, ep address.Addr) bool {
+	// The capturing pawns stand beside the double-pushed pawn, one rank
+	// behind the target square from the mover's point of view.
+	dr := -1
+	if turn == pieces.BLACK {
+		dr = 1
+	}
+	for _, df := range []int{-1, 1} {
+		sq, ok := ep.Shift(dr, df)
+		if !ok {
+			continue
+		}
+		if p, isPawn := b.PieceAt(sq).(*pieces.Pawn); isPawn && p.Color() == turn {
+			return true
+		}
+	}
+	return false
+}
+
+const promoPieces = " nbrq"
+
+// EncodeMove packs a move into the Polyglot format; castling must be given
+// as the king move and is stored as king-takes-rook.
+func EncodeMove(b *board.Board, m Move) uint16 {
+	to := m.To
+	if _, isKing := b.PieceAt(m.From).(*pieces.King); isKing && m.From.File == 4 {
+		switch int(m.To.File) - int(m.From.File) {
+		case 2:
+			to = address.MakeAddr(m.From.Rank, 7)
+		case -2:
+			to = address.MakeAddr(m.From.Rank, 0)
+		}
+	}
+	promo := 0
+	for i, c := range promoPieces {
+		if m.Promo != 0 && c == m.Promo {
+			promo = i
+		}
+	}
+	return uint16(to.File) | uint16(to.Rank)<<3 | uint16(m.From.File)<<6 | uint16(m.From.Rank)<<9 | uint16(promo)<<12
+}
+
+// DecodeMove unpacks a book move for the position on b, turning
+// king-takes-rook castling into the king's two-square move.
+func DecodeMove(b *board.Board, v uint16) Move {
+	m := Move{
+		To:   address.MakeAddr(address.Rank(v>>3&7), address.File(v&7)),
+		From: address.MakeAddr(address.Rank(v>>9&7), address.File(v>>6&7)),
+	}
+	if promo := int(v >> 12 & 7); promo > 0 && promo < len(promoPieces) {
+		m.Promo = rune(promoPieces[promo])
+	}
+	if k, isKing := b.PieceAt(m.From).(*pieces.King); isKing && m.From.File == 4 && m.From.Rank == m.To.Rank {
+		if r, isRook := b.PieceAt(m.To).(*pieces.Rook); isRook && r.Color() == k.Color() {
+			switch m.To.File {
+			case 7:
+				m.To = address.MakeAddr(m.From.Rank, 6)
+			case 0:
+				m.To = address.MakeAddr(m.From.Rank, 2)
+			}
+		}
+	}
+	return m
+}
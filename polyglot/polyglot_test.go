@@ -0,0 +1,175 @@
+package polyglot
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/board"
+)
+
+func mustFEN(t *testing.T, fen string) (*board.Board, *board.GameState) {
+	t.Helper()
+	b, s, err := board.FromFEN(fen)
+	if err != nil {
+		t.Fatalf("FromFEN(%q): %v", fen, err)
+	}
+	return b, s
+}
+
+func sq(s string) address.Addr {
+	return address.MakeAddr(address.Rank(s[1]-'1'), address.File(s[0]-'a'))
+}
+
+// The test vectors published with the Polyglot book format.
+func TestKeyMatchesReferenceVectors(t *testing.T) {
+	tests := []struct {
+		fen string
+		key uint64
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 0x463b96181691fc9c},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", 0x823c9b50fd114196},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", 0x0756b94461c50fb0},
+		{"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2", 0x662fafb965db29d4},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", 0x22a48b5a8e47ff78},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR b kq - 0 3", 0x652a607ca3f242c1},
+		{"rnbq1bnr/ppp1pkpp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR w - - 0 4", 0x00fdd303c946bdd9},
+		{"rnbqkbnr/p1pppppp/8/8/PpP4P/8/1P1PPPP1/RNBQKBNR b KQkq c3 0 3", 0x3c8123ea7b067637},
+		{"rnbqkbnr/p1pppppp/8/8/P6P/R1p5/1P1PPPP1/1NBQKBNR b Kkq - 0 4", 0x5c3f9b829b279560},
+	}
+	for _, tt := range tests {
+		b, s := mustFEN(t, tt.fen)
+		if got := Key(b, s); got != tt.key {
+			t.Errorf("Key(%s) = %016x, want %016x", tt.fen, got, tt.key)
+		}
+	}
+}
+
+func TestMoveEncoding(t *testing.T) {
+	b, _ := mustFEN(t, "r3k2r/1P6/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	tests := []struct {
+		move   Move
+		stored string // the move as it appears in the book
+	}{
+		{Move{From: sq("e1"), To: sq("g1")}, "e1h1"},
+		{Move{From: sq("e1"), To: sq("c1")}, "e1a1"},
+		{Move{From: sq("b7"), To: sq("a8"), Promo: 'q'}, "b7a8q"},
+		{Move{From: sq("a1"), To: sq("a8")}, "a1a8"},
+	}
+	for _, tt := range tests {
+		v := EncodeMove(b, tt.move)
+		raw := Move{
+			From:  address.MakeAddr(address.Rank(v>>9&7), address.File(v>>6&7)),
+			To:    address.MakeAddr(address.Rank(v>>3&7), address.File(v&7)),
+			Promo: tt.move.Promo,
+		}
+		if raw.String() != tt.stored {
+			t.Errorf("EncodeMove(%s) stored as %s, want %s", tt.move, raw, tt.stored)
+		}
+		if got := DecodeMove(b, v); got != tt.move {
+			t.Errorf("DecodeMove(%s) = %s", tt.stored, got)
+		}
+	}
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	book := New([]Entry{
+		{Key: 7, Move: 1, Weight: 1},
+		{Key: 3, Move: 2, Weight: 5, Learn: 9},
+		{Key: 7, Move: 3, Weight: 4},
+	})
+	var buf bytes.Buffer
+	if err := book.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 3*EntrySize {
+		t.Fatalf("wrote %d bytes", buf.Len())
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Len() != 3 || got.Entries()[0] != (Entry{Key: 3, Move: 2, Weight: 5, Learn: 9}) {
+		t.Fatalf("entries %+v", got.Entries())
+	}
+	if e := got.Lookup(7); len(e) != 2 || e[0].Weight != 4 {
+		t.Fatalf("Lookup(7) = %+v", e)
+	}
+	if e := got.Lookup(5); len(e) != 0 {
+		t.Fatalf("Lookup(5) = %+v", e)
+	}
+
+	if _, err := Read(bytes.NewReader(make([]byte, EntrySize+1))); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("short file: %v", err)
+	}
+}
+
+func TestPickFollowsWeights(t *testing.T) {
+	b, s := mustFEN(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	key := Key(b, s)
+	e4 := Move{From: sq("e2"), To: sq("e4")}
+	d4 := Move{From: sq("d2"), To: sq("d4")}
+	a4 := Move{From: sq("a2"), To: sq("a4")}
+	book := New([]Entry{
+		{Key: key, Move: EncodeMove(b, e4), Weight: 3},
+		{Key: key, Move: EncodeMove(b, d4), Weight: 1},
+		{Key: key, Move: EncodeMove(b, a4), Weight: 0},
+	})
+
+	rnd := rand.New(rand.NewSource(1))
+	counts := map[Move]int{}
+	for i := 0; i < 4000; i++ {
+		m, ok := book.Pick(b, s, rnd)
+		if !ok {
+			t.Fatal("start position not found")
+		}
+		counts[m]++
+	}
+	if counts[a4] != 0 {
+		t.Fatal("a weight-0 move was played")
+	}
+	if counts[e4] < 2*counts[d4] || counts[d4] == 0 {
+		t.Fatalf("picks %v do not follow weights 3:1", counts)
+	}
+
+	b, s = mustFEN(t, "4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if _, ok := book.Pick(b, s, rnd); ok {
+		t.Fatal("picked a move outside the book")
+	}
+}
+
+func TestLearningAdjustsWeights(t *testing.T) {
+	l := NewLearning()
+	white := Step{Key: 1, Move: 10, Color: 0}
+	black := Step{Key: 2, Move: 20, Color: 1}
+	l.Record([]Step{white, black}, 1) // White won
+	if got := l.Result(1, 10); got != (Result{Wins: 1}) {
+		t.Fatalf("white step %+v", got)
+	}
+	if got := l.Result(2, 20); got != (Result{Losses: 1}) {
+		t.Fatalf("black step %+v", got)
+	}
+	if base := l.Adjust(9, 9, 5); l.Adjust(1, 10, 5) <= base || l.Adjust(2, 20, 5) >= base {
+		t.Fatal("a win should raise and a loss lower the weight")
+	}
+	for i := 1; i < DropLosses; i++ {
+		l.Record([]Step{black}, 1)
+	}
+	if got := l.Adjust(2, 20, 5); got != 0 {
+		t.Fatalf("a line lost %d times still weighs %d", DropLosses, got)
+	}
+
+	var buf bytes.Buffer
+	if err := l.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	back, err := ReadLearning(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Len() != 2 || back.Result(2, 20) != l.Result(2, 20) {
+		t.Fatalf("round trip lost records: %+v", back.Result(2, 20))
+	}
+}
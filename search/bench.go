@@ -0,0 +1,95 @@
+// --- search/bench.go ---
+
+package search
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/socrates"
+)
+
+const (
+	// BenchDepth is how deep Bench searches by default.
+	BenchDepth = 5
+	// benchHashMB fixes the table size, which the node count depends on.
+	benchHashMB = 16
+)
+
+// BenchPositions is the fixed workload of Bench: openings, middlegames
+// and endgames with tactics, promotions and checks.
+var BenchPositions = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 10",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 11",
+	"4rrk1/pp1n3p/3q2pQ/2p1pb2/2PP4/2P3N1/P2B2PP/4RRK1 b - - 7 19",
+	"rq3rk1/ppp2ppp/1bnpb3/3N2B1/3NP3/7P/PPPQ1PP1/2KR3R w - - 7 14",
+	"r1bq1r1k/1pp1n1pp/1p1p4/4p2Q/4Pp2/1BNP4/PPP2PPP/3R1RK1 w - - 2 14",
+	"r3r1k1/2p2ppp/p1p1bn2/8/1q2P3/2NPQN2/PPP3PP/R4RK1 b - - 2 15",
+	"r1bbk1nr/pp3p1p/2n5/1N4p1/2Np1B2/8/PPP2PPP/2KR1B1R w kq - 0 13",
+	"r1bq1rk1/ppp1nppp/4n3/3p3Q/3P4/1BP1B3/PP1N2PP/R4RK1 w - - 1 16",
+	"3r1rk1/p5pp/bpp1pp2/8/q1PP1P2/b3P3/P2NQRPP/1R2B1K1 b - - 6 22",
+	"6k1/6p1/6Pp/ppp5/3pn2P/1P3K2/1PP2P2/3N4 b - - 0 1",
+	"3b4/5kp1/1p1p1p1p/pP1PpP1P/P1P1P3/3KN3/8/8 w - - 0 1",
+	"8/8/8/8/5kp1/P7/8/1K1N4 w - - 0 1",
+	"8/3k4/8/8/8/4B3/4KB2/2B5 w - - 0 1",
+	"6k1/4pp1p/3p2p1/P1pPb3/R7/1r2P1PP/3B1P2/6K1 w - - 0 1",
+	"2K5/p7/7P/5pR1/8/5k2/r7/8 w - - 0 1",
+}
+
+// BenchResult totals a Bench run. Nodes is the signature: it only changes
+// when the search or the evaluation does.
+type BenchResult struct {
+	Positions int
+	Nodes     int
+	Time      time.Duration
+}
+
+// NPS is the search speed in nodes per second.
+func (b BenchResult) NPS() int64 {
+	return int64(b.Nodes) * 1000 / max(b.Time.Milliseconds(), 1)
+}
+
+// Bench searches every bench position to depth with a fresh engine and a
+// cleared table, writing a line per position and the totals to w.
+func Bench(w io.Writer, depth int) BenchResult {
+	if depth <= 0 {
+		depth = BenchDepth
+	}
+	eng := socrates.New(board.InitStandard())
+	eng.SetHashSize(benchHashMB)
+	bo := eng.BookOptions()
+	bo.Disabled = true
+	eng.SetBookOptions(bo)
+
+	var total BenchResult
+	for i, fen := range BenchPositions {
+		b, s, err := board.FromFEN(fen)
+		if err != nil {
+			panic(fmt.Sprintf("bench position %d: %v", i+1, err))
+		}
+		eng.SetPosition(b, s)
+		eng.ClearHash()
+
+		start := time.Now()
+		res := think(eng, depth, false, func() bool { return true }, silent{})
+		total.Time += time.Since(start)
+		total.Nodes += res.Nodes
+		total.Positions++
+		fmt.Fprintf(w, "Position %2d/%d: %-8d %s\n", i+1, len(BenchPositions), res.Nodes, fen)
+	}
+	fmt.Fprintf(w, "\nTotal time (ms) : %d\n", total.Time.Milliseconds())
+	fmt.Fprintf(w, "Nodes searched  : %d\n", total.Nodes)
+	fmt.Fprintf(w, "Nodes/second    : %d\n", total.NPS())
+	return total
+}
+
+// silent discards a search's reports.
+type silent struct{}
+
+func (silent) Iteration(Info)                         {}
+func (silent) CurrMove(int, socrates.SimpleMove, int) {}
+func (silent) BookMove(socrates.SimpleMove)           {}
+func (silent) BestMove(Result)                        {}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/socrates"
+)
+
+func TestBenchSignatureIsStable(t *testing.T) {
+	var out strings.Builder
+	first := Bench(&out, 2)
+	second := Bench(io.Discard, 2)
+	if first.Positions != len(BenchPositions) || first.Nodes == 0 {
+		t.Fatalf("bench searched %d positions, %d nodes", first.Positions, first.Nodes)
+	}
+	if first.Nodes != second.Nodes {
+		t.Fatalf("signature changed between runs: %d, then %d", first.Nodes, second.Nodes)
+	}
+	if !strings.Contains(out.String(), "Nodes searched  : ") {
+		t.Fatalf("no totals in %q", out.String())
+	}
+}
+
+// benchEngines sets up every bench position on its own engine.
+func benchEngines(b *testing.B) []*socrates.RuleEngine {
+	b.Helper()
+	var engs []*socrates.RuleEngine
+	for _, fen := range BenchPositions {
+		bd, s, err := board.FromFEN(fen)
+		if err != nil {
+			b.Fatal(err)
+		}
+		eng := socrates.New(bd)
+		eng.SetPosition(bd, s)
+		engs = append(engs, eng)
+	}
+	return engs
+}
+
+// BenchmarkBench is the bench command: one op searches every position.
+func BenchmarkBench(b *testing.B) {
+	nodes := 0
+	for range b.N {
+		nodes = Bench(io.Discard, 4).Nodes
+	}
+	b.ReportMetric(float64(nodes), "nodes/op")
+}
+
+func BenchmarkSearch(b *testing.B) {
+	engs := benchEngines(b)
+	b.ResetTimer()
+	for i := range b.N {
+		eng := engs[i%len(engs)]
+		eng.ClearHash()
+		eng.Analyze(3)
+	}
+}
+
+func BenchmarkGenerateLegalMoves(b *testing.B) {
+	engs := benchEngines(b)
+	b.ResetTimer()
+	for i := range b.N {
+		engs[i%len(engs)].GenerateLegalMoves()
+	}
+}
+
+func BenchmarkEvaluatePosition(b *testing.B) {
+	engs := benchEngines(b)
+	b.ResetTimer()
+	for i := range b.N {
+		eng := engs[i%len(engs)]
+		socrates.EvaluatePosition(eng.Board, eng.State)
+	}
+}
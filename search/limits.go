@@ -0,0 +1,62 @@
+// --- search/limits.go ---
+
+package search
+
+import "time"
+
+const (
+	// DefaultDepth is searched when no limit at all is given.
+	DefaultDepth = 5
+	// MaxDepth bounds timed and infinite searches.
+	MaxDepth = 64
+	// defaultMovesToGo spreads the clock when the GUI does not say how
+	// many moves remain until the next time control.
+	defaultMovesToGo = 30
+)
+
+// Limits bound one search. The zero value searches to DefaultDepth.
+type Limits struct {
+	Depth     int
+	Nodes     int              // nodes to search, over all iterations
+	MoveTime  time.Duration    // exact time per move
+	Time, Inc [2]time.Duration // clock and increment, by color
+	MovesToGo int              // moves until the next time control; 0 for sudden death
+	Infinite  bool             // search until stopped
+	Ponder    bool             // think on the opponent's time until PonderHit
+
+	// Overhead is kept back from the clock for GUI and network lag.
+	Overhead time.Duration
+}
+
+// Budget returns how long the side to move may think: the search is
+// stopped at hard, and no new iteration starts after soft. Zero means no
+// time limit.
+func (l Limits) Budget(turn int) (hard, soft time.Duration) {
+	if l.MoveTime > 0 {
+		t := max(l.MoveTime-l.Overhead, time.Millisecond)
+		return t, t
+	}
+	left := l.Time[turn]
+	if left <= 0 {
+		return 0, 0
+	}
+	usable := max(left-l.Overhead, time.Millisecond)
+	mtg := l.MovesToGo
+	if mtg <= 0 {
+		mtg = defaultMovesToGo
+	}
+	soft = min(usable/time.Duration(mtg)+l.Inc[turn]*3/4, usable)
+	hard = min(3*soft, usable)
+	return hard, soft
+}
+
+// MaxDepth is the deepest iteration the search may start.
+func (l Limits) MaxDepth() int {
+	switch {
+	case l.Depth > 0:
+		return l.Depth
+	case l.Infinite || l.Ponder || l.Nodes > 0 || l.MoveTime > 0 || l.Time[0] > 0 || l.Time[1] > 0:
+		return MaxDepth
+	}
+	return DefaultDepth
+}
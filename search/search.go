@@ -0,0 +1,314 @@
+// --- search/search.go ---
+
+// Package search runs the engine's searches in the background for the
+// protocol front ends: iterative deepening with aspiration windows, time
+// management, stop, pondering and progress reports.
+package search
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mesb/mchess/socrates"
+)
+
+// Info reports one line of a finished or bounded iteration.
+type Info struct {
+	Depth    int
+	SelDepth int
+	MultiPV  int // line number, from 1
+	Score    int
+	Bound    socrates.Bound
+	Nodes    int
+	TBHits   int
+	Hashfull int // permille
+	Time     time.Duration
+	PV       []socrates.SimpleMove
+}
+
+// NPS is the search speed in nodes per second.
+func (i Info) NPS() int64 {
+	return int64(i.Nodes) * 1000 / max(i.Time.Milliseconds(), 1)
+}
+
+// Result is the outcome of a search.
+type Result struct {
+	socrates.SearchResult
+	// Ponder is the expected reply; its From equals its To when unknown.
+	Ponder socrates.SimpleMove
+}
+
+// Move returns the move to play and whether there is one.
+func (r Result) Move() (socrates.SimpleMove, bool) {
+	return socrates.SimpleMove{From: r.From, To: r.To, Promo: r.Promo}, r.From != r.To
+}
+
+// Reporter receives the progress of a search on the searching goroutine.
+type Reporter interface {
+	Iteration(Info)
+	CurrMove(depth int, m socrates.SimpleMove, number int)
+	BookMove(m socrates.SimpleMove)
+	// BestMove ends every search, once a pondering or infinite search has
+	// been released by PonderHit or Stop.
+	BestMove(Result)
+}
+
+// Searcher runs one search at a time on its own goroutine so that the
+// input loop of a protocol stays responsive. The zero value is ready.
+type Searcher struct {
+	mu   sync.Mutex
+	eng  *socrates.RuleEngine
+	gen  int           // counts searches, so stale timers are ignored
+	done chan struct{} // closed once BestMove returns; nil when idle
+
+	// hold withholds BestMove while pondering or searching infinitely,
+	// until Stop or PonderHit.
+	hold chan struct{}
+	held bool
+
+	hard, soft time.Duration
+	clock      time.Time // when the engine's clock started; zero while pondering
+	timer      *time.Timer
+}
+
+// Start searches eng in the background, first stopping any running
+// search. Until BestMove returns, only Stop may be called on eng.
+func (s *Searcher) Start(eng *socrates.RuleEngine, l Limits, r Reporter) {
+	s.Stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	eng.ClearStop()
+	s.eng = eng
+	s.gen++
+	s.done = make(chan struct{})
+	s.hold = make(chan struct{})
+	s.held = true
+	if !l.Infinite && !l.Ponder {
+		s.releaseLocked()
+	}
+	s.hard, s.soft = l.Budget(eng.Turn)
+	s.clock = time.Time{}
+	if !l.Ponder {
+		s.startClockLocked()
+	}
+	go s.run(eng, l, r, s.hold, s.done)
+}
+
+func (s *Searcher) run(eng *socrates.RuleEngine, l Limits, r Reporter, hold, done chan struct{}) {
+	// Analysis searches the position even where the book knows a move.
+	eng.SetNodeLimit(l.Nodes)
+	res := Result{SearchResult: think(eng, l.MaxDepth(), !l.Infinite, s.deepen, r)}
+	eng.SetNodeLimit(0)
+	<-hold
+	if best, ok := res.Move(); ok {
+		if pv := eng.PV(best, 2); len(pv) == 2 {
+			res.Ponder = pv[1]
+		}
+	}
+	r.BestMove(res)
+
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.done = nil
+	s.mu.Unlock()
+	close(done)
+}
+
+// Stop ends the running search, if any, and waits for its BestMove.
+func (s *Searcher) Stop() {
+	s.mu.Lock()
+	done := s.done
+	if done != nil {
+		s.eng.Stop()
+		s.releaseLocked()
+	}
+	s.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// PonderHit turns a pondering search into a normal one: the opponent
+// played the expected move, so the engine's clock starts now.
+func (s *Searcher) PonderHit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done == nil || !s.clock.IsZero() {
+		return
+	}
+	s.startClockLocked()
+	s.releaseLocked()
+}
+
+// Busy reports whether a search is running or holding its BestMove.
+func (s *Searcher) Busy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done != nil
+}
+
+func (s *Searcher) releaseLocked() {
+	if s.held {
+		close(s.hold)
+		s.held = false
+	}
+}
+
+func (s *Searcher) startClockLocked() {
+	s.clock = time.Now()
+	if s.hard <= 0 {
+		return
+	}
+	eng, gen := s.eng, s.gen
+	s.timer = time.AfterFunc(s.hard, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.gen == gen {
+			eng.Stop()
+		}
+	})
+}
+
+// deepen reports whether there is time left to start another iteration.
+func (s *Searcher) deepen() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clock.IsZero() || s.soft <= 0 || time.Since(s.clock) < s.soft
+}
+
+// think deepens the search one ply at a time up to depth, reporting each
+// iteration. Unless book is false it plays from the book when it can;
+// otherwise it returns the best move of the last iteration, even a stopped
+// one: the previous best move is searched first (each finished iteration
+// leaves it in the hash table, with one line or many), so a stopped
+// iteration only replaces it with a better one. A stopped first iteration
+// still yields a legal move.
+func think(eng *socrates.RuleEngine, depth int, book bool, deepen func() bool, r Reporter) socrates.SearchResult {
+	if book {
+		if bm := eng.BookMove(); bm != nil {
+			r.BookMove(*bm)
+			return socrates.SearchResult{From: bm.From, To: bm.To, Promo: bm.Promo, Book: true}
+		}
+	}
+	t := &thinker{r: r, eng: eng, start: time.Now()}
+	eng.OnRootMove(t.currMove)
+	defer eng.OnRootMove(nil)
+
+	// A weakened engine deepens within its own limits.
+	first := 1
+	if eng.SkillLevel() < socrates.MaxSkillLevel {
+		first = depth
+	}
+
+	var best socrates.SearchResult
+	for d := first; d <= depth; d++ {
+		res, done := t.iterate(d, best, d > first)
+		if res.From != res.To && res.Bound != socrates.BoundUpper {
+			best = res
+		}
+		if !done || !deepen() {
+			break
+		}
+	}
+	if best.From == best.To {
+		if moves := eng.GenerateLegalMoves(); len(moves) > 0 {
+			best.From, best.To, best.Promo = moves[0].From, moves[0].To, moves[0].Promo
+		}
+	}
+	best.Nodes = t.nodes
+	best.TBHits = t.tbHits
+	return best
+}
+
+// Aspiration windows: iterations from aspirationDepth on first search a
+// window of aspirationWindow centipawns around the previous score,
+// doubling it on every fail high or low.
+const (
+	aspirationDepth  = 4
+	aspirationWindow = 40
+	// currMoveDelay holds back currmove updates, which only matter once
+	// an iteration takes a while.
+	currMoveDelay = time.Second
+)
+
+// thinker reports the progress of one search.
+type thinker struct {
+	r      Reporter
+	eng    *socrates.RuleEngine
+	start  time.Time
+	depth  int
+	nodes  int
+	tbHits int
+}
+
+// iterate searches one depth, re-searching with a wider window after each
+// fail high or low; it reports false when the search was stopped.
+func (t *thinker) iterate(depth int, prev socrates.SearchResult, aspirate bool) (socrates.SearchResult, bool) {
+	t.depth = depth
+	alpha, beta := socrates.MinScore, socrates.MaxScore
+	delta := aspirationWindow
+	if aspirate && depth >= aspirationDepth && t.eng.MultiPV() == 1 && prev.From != prev.To {
+		alpha, beta = max(prev.Score-delta, socrates.MinScore), min(prev.Score+delta, socrates.MaxScore)
+	}
+	for {
+		res := t.eng.AnalyzeWindow(depth, alpha, beta)
+		t.nodes += res.Nodes
+		t.tbHits += res.TBHits
+		if t.eng.Stopped() {
+			if res.From != res.To {
+				res.Bound = socrates.BoundLower
+				t.report(res)
+			}
+			return res, false
+		}
+		t.report(res)
+		delta *= 2
+		switch res.Bound {
+		case socrates.BoundLower:
+			beta = min(res.Score+delta, socrates.MaxScore)
+		case socrates.BoundUpper:
+			alpha = max(res.Score-delta, socrates.MinScore)
+		default:
+			return res, true
+		}
+	}
+}
+
+// report passes on a search result, one Info per multi-PV line.
+func (t *thinker) report(res socrates.SearchResult) {
+	lines := res.Lines
+	if len(lines) == 0 {
+		lines = []socrates.RootLine{{Move: socrates.SimpleMove{From: res.From, To: res.To, Promo: res.Promo}, Score: res.Score}}
+	}
+	elapsed := time.Since(t.start)
+	for i, l := range lines {
+		bound := socrates.BoundExact
+		if i == 0 {
+			bound = res.Bound
+		}
+		t.r.Iteration(Info{
+			Depth:    t.depth,
+			SelDepth: max(res.SelDepth, t.depth),
+			MultiPV:  i + 1,
+			Score:    l.Score,
+			Bound:    bound,
+			Nodes:    t.nodes,
+			TBHits:   t.tbHits,
+			Hashfull: t.eng.Hashfull(),
+			Time:     elapsed,
+			PV:       t.eng.PV(l.Move, t.depth),
+		})
+	}
+}
+
+// currMove reports the root move being searched once the search has run
+// for a while.
+func (t *thinker) currMove(m socrates.SimpleMove, number int) {
+	if time.Since(t.start) >= currMoveDelay {
+		t.r.CurrMove(t.depth, m, number)
+	}
+}
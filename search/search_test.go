@@ -0,0 +1,166 @@
+package search
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/socrates"
+)
+
+func TestBudget(t *testing.T) {
+	overhead := 10 * time.Millisecond
+	l := Limits{MoveTime: 500 * time.Millisecond, Overhead: overhead}
+	if hard, soft := l.Budget(0); hard != 490*time.Millisecond || soft != hard {
+		t.Fatalf("movetime: hard %v soft %v", hard, soft)
+	}
+	l = Limits{Time: [2]time.Duration{time.Minute, 3010 * time.Millisecond}, MovesToGo: 1, Overhead: overhead}
+	if hard, soft := l.Budget(1); hard != 3*time.Second || soft != 3*time.Second {
+		t.Fatalf("last move before the control: hard %v soft %v", hard, soft)
+	}
+	l = Limits{Time: [2]time.Duration{time.Minute, time.Minute}, Inc: [2]time.Duration{time.Second, time.Second}, Overhead: overhead}
+	if hard, soft := l.Budget(0); soft >= hard || hard > 20*time.Second {
+		t.Fatalf("white: hard %v soft %v", hard, soft)
+	}
+	if hard, _ := (Limits{Depth: 3}).Budget(0); hard != 0 {
+		t.Fatalf("a depth search has no clock, got %v", hard)
+	}
+	if d := (Limits{}).MaxDepth(); d != DefaultDepth {
+		t.Fatalf("no limits searches to %d", d)
+	}
+}
+
+// recorder keeps what a search reports.
+type recorder struct {
+	mu    sync.Mutex
+	infos []Info
+	best  []Result
+}
+
+func (r *recorder) Iteration(i Info) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.infos = append(r.infos, i)
+}
+
+func (r *recorder) CurrMove(int, socrates.SimpleMove, int) {}
+func (r *recorder) BookMove(socrates.SimpleMove)           {}
+
+func (r *recorder) BestMove(res Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.best = append(r.best, res)
+}
+
+func newEngine() *socrates.RuleEngine {
+	eng := socrates.New(board.InitStandard())
+	eng.SetBookOptions(socrates.BookOptions{Disabled: true})
+	return eng
+}
+
+func waitIdle(t *testing.T, s *Searcher) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); s.Busy(); {
+		if time.Now().After(deadline) {
+			t.Fatal("the search did not answer")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSearcherStops(t *testing.T) {
+	eng := newEngine()
+	var s Searcher
+	var r recorder
+	s.Start(eng, Limits{Infinite: true}, &r)
+	time.Sleep(20 * time.Millisecond)
+	if !s.Busy() {
+		t.Fatal("an infinite search answered before stop")
+	}
+	start := time.Now()
+	s.Stop()
+	if s.Busy() || time.Since(start) > time.Second || len(r.best) != 1 {
+		t.Fatalf("stop took %v, %d answers", time.Since(start), len(r.best))
+	}
+	if _, ok := r.best[0].Move(); !ok {
+		t.Fatal("a stopped search found no move")
+	}
+
+	// A finished ponder search keeps its answer until PonderHit.
+	s.Start(eng, Limits{Ponder: true, Depth: 1, Time: [2]time.Duration{time.Second, time.Second}}, &r)
+	time.Sleep(50 * time.Millisecond)
+	if !s.Busy() {
+		t.Fatal("answered while pondering")
+	}
+	s.PonderHit()
+	waitIdle(t, &s)
+}
+
+func TestSearcherKeepsTime(t *testing.T) {
+	var s Searcher
+	var r recorder
+	start := time.Now()
+	s.Start(newEngine(), Limits{MoveTime: 100 * time.Millisecond}, &r)
+	waitIdle(t, &s)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("movetime 100ms took %v", elapsed)
+	}
+}
+
+func TestSearcherReportsIterations(t *testing.T) {
+	var s Searcher
+	var r recorder
+	s.Start(newEngine(), Limits{Depth: 3}, &r)
+	waitIdle(t, &s)
+	if len(r.infos) < 3 || r.infos[len(r.infos)-1].Depth != 3 {
+		t.Fatalf("iterations %+v", r.infos)
+	}
+	last := r.infos[len(r.infos)-1]
+	best, _ := r.best[0].Move()
+	if len(last.PV) == 0 || last.PV[0] != best || last.Nodes != r.best[0].Nodes {
+		t.Fatalf("last iteration %+v does not match the answer %+v", last, r.best[0])
+	}
+}
+
+func TestStoppedMultiPVKeepsTheLastIteration(t *testing.T) {
+	eng := newEngine()
+	eng.SetMultiPV(3)
+	var r recorder
+	// Stop as the second iteration starts, before it scores any line.
+	stopped := false
+	deepen := func() bool {
+		eng.Stop()
+		stopped = true
+		return true
+	}
+	res := think(eng, 3, false, deepen, &r)
+	eng.ClearStop()
+	if !stopped || len(r.infos) == 0 {
+		t.Fatalf("the first iteration reported nothing: %+v", r.infos)
+	}
+	for _, i := range r.infos {
+		if i.Depth != 1 || i.Score <= socrates.MinScore {
+			t.Fatalf("reported a line of the stopped iteration: %+v", i)
+		}
+	}
+	if m := (socrates.SimpleMove{From: res.From, To: res.To, Promo: res.Promo}); m != r.infos[0].PV[0] {
+		t.Fatalf("answered %v, the first iteration's best was %v", m, r.infos[0].PV[0])
+	}
+}
+
+func TestSearcherKeepsToNodes(t *testing.T) {
+	var s Searcher
+	var r recorder
+	s.Start(newEngine(), Limits{Nodes: 3000}, &r)
+	waitIdle(t, &s)
+	if len(r.best) != 1 || r.best[0].Nodes > 3000*11/10 { // quiescence may finish past the limit
+		t.Fatalf("answers %+v", r.best)
+	}
+	if _, ok := r.best[0].Move(); !ok {
+		t.Fatal("a node-limited search found no move")
+	}
+	if last := r.infos[len(r.infos)-1]; last.Depth >= MaxDepth {
+		t.Fatalf("searched to depth %d", last.Depth)
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/eco"
+	"github.com/mesb/mchess/notation"
 	"github.com/mesb/mchess/pieces"
 	"github.com/mesb/mchess/socrates"
 )
@@ -44,7 +48,11 @@ func showWelcome() {
 	fmt.Println("Enter 'q' to quit")
 	fmt.Println("Enter 'u' to undo last move")
 	fmt.Println("Enter 'h' to view move history")
-	fmt.Println("Enter moves like: m e2e4 or simply e2e4")
+	fmt.Println("Enter 'o' to name the opening")
+	fmt.Println("Enter moves like: m e2e4, e2e4 or Nf3")
+	fmt.Println("Enter 'go' to let the engine play the side to move")
+	fmt.Println("Enter 'level 0-20', 'elo 800-2400' or 'style <name>' to adjust the engine")
+	fmt.Println("Enter 'book off|weighted|best|uniform' to choose how the engine uses its book")
 	fmt.Println()
 }
 
@@ -74,14 +82,25 @@ func handleInput(input string, session *GameSession) bool {
 	}
 
 	if input == "h" {
-		session.Log.PrintLog()
+		showHistory(session)
+		return false
+	}
+
+	if input == "o" {
+		session.Renderer.Message(openingName(session))
 		return false
 	}
 
 	if input == "analyze" {
 		session.Renderer.Message("Thinking...")
-		result := session.Engine.Search(4) // depth 4 for quick response
-		msg := fmt.Sprintf("Best Move: %s -> %s (Score: %d, Nodes: %d)", result.From, result.To, result.Score, result.Nodes)
+		result := session.Engine.Analyze(4) // depth 4 for quick response
+		best := socrates.SimpleMove{From: result.From, To: result.To, Promo: result.Promo}
+		san, err := notation.SAN(session.Engine, best)
+		if err != nil {
+			session.Renderer.Message("No legal moves.")
+			return false
+		}
+		msg := fmt.Sprintf("Best Move: %s (Score: %d, Nodes: %d)", san, result.Score, result.Nodes)
 		session.Renderer.Message(msg)
 		return false
 	}
@@ -96,43 +115,167 @@ func handleInput(input string, session *GameSession) bool {
 		return false
 	}
 
-	if strings.HasPrefix(input, "m ") {
-		err := socrates.Dialog(input, session.Engine)
-		if err != nil {
+	if input == "go" {
+		result := session.Engine.Search(4)
+		best := socrates.SimpleMove{From: result.From, To: result.To, Promo: result.Promo}
+		if result.From == result.To {
+			session.Renderer.Message("No legal moves.")
+			return false
+		}
+		san, err := notation.SAN(session.Engine, best)
+		if err != nil || !session.Engine.MakeMove(best.From, best.To, best.Promo) {
+			session.Renderer.Message("Engine produced an illegal move.")
+			return false
+		}
+		session.Renderer.Message("Engine plays " + san)
+		return afterMove(session)
+	}
+
+	if fields := strings.Fields(input); len(fields) == 2 {
+		if msg, ok := configureEngine(session.Engine, fields[0], fields[1]); ok {
+			session.Renderer.Message(msg)
+			return false
+		}
+	}
+
+	if move, ok := strings.CutPrefix(input, "m "); ok {
+		if err := playMove(session.Engine, move); err != nil {
 			session.Renderer.Message(err.Error())
 			return false
 		}
+		return afterMove(session)
+	}
 
-		session.UpdateCaptured()
-		showBoard(session)
+	// Anything else that reads as SAN ("Nf3", "exd5", "O-O") is a move too.
+	if playMove(session.Engine, input) == nil {
+		return afterMove(session)
+	}
 
-		// Check Game End States
-		if session.Engine.IsCheckmate() {
-			session.Renderer.Message("🏁 CHECKMATE! " + colorName(session.Engine.GetTurn()) + " loses.")
-			return true
+	session.Renderer.Message("Unknown command. Try 'm e2e4', 'Nf3', 'go', 'u', 'h', or 'q'")
+	return false
+}
+
+// playMove plays a move given in SAN or coordinate notation.
+func playMove(engine *socrates.RuleEngine, move string) error {
+	m, err := notation.ParseSAN(engine, strings.TrimSpace(move))
+	if err != nil {
+		return err
+	}
+	if !engine.MakeMove(m.From, m.To, m.Promo) {
+		return fmt.Errorf("illegal move %s", move)
+	}
+	return nil
+}
+
+// showHistory prints the moves played so far in SAN, a move pair a line.
+func showHistory(session *GameSession) {
+	fmt.Println("\nMove History:")
+	fmt.Print(historyText(session.Engine))
+}
+
+// historyText numbers the moves played on engine, e.g. " 1. e4 e5\n".
+func historyText(engine *socrates.RuleEngine) string {
+	fen, moves := notation.History(engine)
+	number, black := 1, false
+	if _, s, err := board.FromFEN(fen); err == nil {
+		number, black = s.FullmoveNumber, s.Turn == pieces.BLACK
+	}
+	var b strings.Builder
+	if black && len(moves) > 0 {
+		fmt.Fprintf(&b, "%2d. ...", number)
+	}
+	for _, san := range moves {
+		if black {
+			fmt.Fprintf(&b, " %s\n", san)
+			number++
+		} else {
+			fmt.Fprintf(&b, "%2d. %s", number, san)
+		}
+		black = !black
+	}
+	if black {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// afterMove redraws the board and reports the game state after either side
+// moved. It returns true when the game is over.
+func afterMove(session *GameSession) bool {
+	session.UpdateCaptured()
+	showBoard(session)
+
+	// Check Game End States
+	if session.Engine.IsCheckmate() {
+		session.Renderer.Message("🏁 CHECKMATE! " + colorName(session.Engine.GetTurn()) + " loses.")
+		return true
+	}
+	if session.Engine.IsStalemate() {
+		session.Renderer.Message("⛔ STALEMATE. Draw.")
+		return true
+	}
+	if session.Engine.IsFiftyMoveRule() {
+		session.Renderer.Message("⏳ DRAW by 50-move rule.")
+		return true
+	}
+	if session.Engine.IsInsufficientMaterial() {
+		session.Renderer.Message("⚖️ DRAW by insufficient material.")
+		return true
+	}
+
+	if session.Engine.IsInCheck(session.Engine.Turn) {
+		session.Renderer.Message("⚠️  CHECK!")
+	}
+
+	return false
+}
+
+// configureEngine handles the strength and personality commands. It
+// returns a message for the user and false if cmd is not one of them.
+func configureEngine(engine *socrates.RuleEngine, cmd, arg string) (string, bool) {
+	switch cmd {
+	case "level", "elo":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Sprintf("%s needs a number", cmd), true
 		}
-		if session.Engine.IsStalemate() {
-			session.Renderer.Message("⛔ STALEMATE. Draw.")
-			return true
+		if cmd == "elo" {
+			n = socrates.EloToSkillLevel(n)
 		}
-		if session.Engine.IsFiftyMoveRule() {
-			session.Renderer.Message("⏳ DRAW by 50-move rule.")
-			return true
+		engine.SetSkillLevel(n)
+		return fmt.Sprintf("Engine skill level %d/%d", engine.SkillLevel(), socrates.MaxSkillLevel), true
+	case "style":
+		p, ok := socrates.PersonalityByName(arg)
+		if !ok {
+			return "Styles: " + strings.Join(socrates.PersonalityNames(), ", "), true
 		}
-		if session.Engine.IsInsufficientMaterial() {
-			session.Renderer.Message("⚖️ DRAW by insufficient material.")
-			return true
+		engine.SetPersonality(p)
+		return "Engine plays a " + p.Name + " game", true
+	case "book":
+		o := engine.BookOptions()
+		if p, ok := socrates.BookPolicyByName(arg); ok {
+			o.Disabled, o.Policy = false, p
+		} else if arg == "off" {
+			o.Disabled = true
+		} else {
+			return "Book: off, " + strings.Join(socrates.BookPolicyNames(), ", "), true
 		}
-
-		if session.Engine.IsInCheck(session.Engine.Turn) {
-			session.Renderer.Message("⚠️  CHECK!")
+		engine.SetBookOptions(o)
+		if o.Disabled {
+			return "Opening book off", true
 		}
-
-		return false
+		return "Opening book on (" + o.Policy.String() + ")", true
 	}
+	return "", false
+}
 
-	session.Renderer.Message("Unknown command. Try 'm e2e4', 'u', 'h', or 'q'")
-	return false
+// openingName describes the opening played so far.
+func openingName(session *GameSession) string {
+	o, ok := eco.ClassifyGame(session.Engine)
+	if !ok {
+		return "Opening: unknown"
+	}
+	return fmt.Sprintf("Opening: %s %s", o.ECO, o)
 }
 
 // normalizeInput auto-corrects inputs like 'e2e4' to 'm e2e4'
@@ -1,6 +1,10 @@
 package shell
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/mesb/mchess/socrates"
+)
 
 func TestNormalizeInput(t *testing.T) {
 	if got := normalizeInput("e2e4"); got != "m e2e4" {
@@ -19,3 +23,55 @@ func TestColorName(t *testing.T) {
 		t.Fatalf("unexpected color names")
 	}
 }
+
+func TestConfigureEngine(t *testing.T) {
+	s := NewSession(nil)
+	if _, ok := configureEngine(s.Engine, "level", "3"); !ok || s.Engine.SkillLevel() != 3 {
+		t.Fatalf("level command not applied, skill %d", s.Engine.SkillLevel())
+	}
+	if _, ok := configureEngine(s.Engine, "elo", "2400"); !ok || s.Engine.SkillLevel() != 20 {
+		t.Fatalf("elo command not applied, skill %d", s.Engine.SkillLevel())
+	}
+	if _, ok := configureEngine(s.Engine, "style", "aggressive"); !ok || s.Engine.Personality().Name != "aggressive" {
+		t.Fatalf("style command not applied: %+v", s.Engine.Personality())
+	}
+	if _, ok := configureEngine(s.Engine, "book", "best"); !ok || s.Engine.BookOptions().Policy != socrates.BookBest {
+		t.Fatalf("book command not applied: %+v", s.Engine.BookOptions())
+	}
+	if _, ok := configureEngine(s.Engine, "book", "off"); !ok || !s.Engine.BookOptions().Disabled {
+		t.Fatal("book off not applied")
+	}
+	if _, ok := configureEngine(s.Engine, "m", "e2e4"); ok {
+		t.Fatal("moves must not be taken as engine settings")
+	}
+}
+
+func TestOpeningName(t *testing.T) {
+	s := NewSession(nil)
+	if got := openingName(s); got != "Opening: unknown" {
+		t.Fatalf("start position: %s", got)
+	}
+	for _, mv := range []string{"e2e4", "c7c5"} {
+		from, to, _, _ := socrates.ParseMove(mv)
+		s.Engine.MakeMove(*from, *to, 0)
+	}
+	if got := openingName(s); got != "Opening: B20 Sicilian Defense" {
+		t.Fatalf("after 1. e4 c5: %s", got)
+	}
+}
+
+func TestSANMovesAndHistory(t *testing.T) {
+	s := NewSession(nil)
+	for _, mv := range []string{"e4", "e7e5", "Nf3", "Nc6", "Bb5", "a6", "O-O"} {
+		if err := playMove(s.Engine, mv); err != nil {
+			t.Fatalf("%s: %v", mv, err)
+		}
+	}
+	if err := playMove(s.Engine, "Nf3"); err == nil {
+		t.Fatal("Nf3 is not Black's move")
+	}
+	want := " 1. e4 e5\n 2. Nf3 Nc6\n 3. Bb5 a6\n 4. O-O\n"
+	if got := historyText(s.Engine); got != want {
+		t.Fatalf("history:\n%s\nwant:\n%s", got, want)
+	}
+}
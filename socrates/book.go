@@ -1,10 +1,7 @@
 package socrates
 
 import (
-	"math/rand"
-	"time"
-
-	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/polyglot"
 )
 
 // Minimal opening book keyed by FEN (piece placement + turn only for simplicity).
@@ -22,35 +19,105 @@ var miniBook = map[string][]string{
 	"rnbqkbnr/pp1ppppp/2p5/8/2P5/8/PP1PPPPP/RNBQKBNR w":   {"d2d4", "g1f3"},
 }
 
-// BookMove returns a legal book move if available.
+// UseBook installs a Polyglot opening book; nil falls back to the built-in
+// book.
+func (r *RuleEngine) UseBook(b *polyglot.Book) {
+	r.book = b
+}
+
+// LoadBook opens a Polyglot book file. An empty path restores the built-in
+// book.
+func (r *RuleEngine) LoadBook(path string) error {
+	if path == "" {
+		r.UseBook(nil)
+		return nil
+	}
+	b, err := polyglot.Open(path)
+	if err != nil {
+		return err
+	}
+	r.UseBook(b)
+	return nil
+}
+
+// Book returns the active Polyglot book, or nil.
+func (r *RuleEngine) Book() *polyglot.Book {
+	return r.book
+}
+
+// BookMove returns a legal book move if available. A configured Polyglot
+// book replaces the built-in one entirely.
 func (r *RuleEngine) BookMove() *SimpleMove {
-	fen := r.Board.ToFEN(r.State)
-	key := keyFromFEN(fen)
-	cands, ok := miniBook[key]
-	if !ok || len(cands) == 0 {
+	o := r.bookOpts
+	if o.Disabled || (o.MaxPly > 0 && r.gamePly() >= o.MaxPly) {
+		return nil
+	}
+	key := polyglot.Key(r.Board, r.State)
+	moves, weights := r.bookCandidates(key)
+	i := o.choose(weights, key)
+	if i < 0 {
 		return nil
 	}
-	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
-	rnd.Shuffle(len(cands), func(i, j int) { cands[i], cands[j] = cands[j], cands[i] })
+	return &moves[i]
+}
 
-	for _, mv := range cands {
-		if len(mv) < 4 {
-			continue
+// bookCandidates lists the legal book moves for the position with their
+// weights, from the Polyglot book or else the built-in one, adjusted by
+// what has been learned from past games.
+func (r *RuleEngine) bookCandidates(key uint64) ([]SimpleMove, []int) {
+	var moves []SimpleMove
+	var weights []int
+	add := func(m SimpleMove, w int) {
+		// Illegal entries come from key collisions or broken books.
+		if !r.IsLegalMove(m.From, m.To) {
+			return
 		}
-		from := parseCoord(mv[:2])
-		to := parseCoord(mv[2:4])
-		if from == nil || to == nil {
-			continue
+		if r.learn != nil {
+			w = r.learn.Adjust(key, r.encodeBookMove(m), w)
 		}
-		promo := rune(0)
-		if len(mv) == 5 {
-			promo = rune(mv[4])
+		if w > 0 {
+			moves = append(moves, m)
+			weights = append(weights, w)
 		}
-		if r.IsLegalMove(*from, *to) {
-			return &SimpleMove{From: *from, To: *to, Promo: promo}
+	}
+	if r.book != nil {
+		pm, pw := r.book.Probe(r.Board, r.State)
+		for i, m := range pm {
+			add(SimpleMove{From: m.From, To: m.To, Promo: m.Promo}, int(pw[i]))
 		}
+		return moves, weights
 	}
-	return nil
+	for _, mv := range miniBook[keyFromFEN(r.Board.ToFEN(r.State))] {
+		if from, to, promo, err := ParseMove(mv); err == nil {
+			add(SimpleMove{From: *from, To: *to, Promo: promo}, 1)
+		}
+	}
+	return moves, weights
+}
+
+func (r *RuleEngine) encodeBookMove(m SimpleMove) uint16 {
+	return polyglot.EncodeMove(r.Board, polyglot.Move{From: m.From, To: m.To, Promo: m.Promo})
+}
+
+// UseLearning installs the record of past book games; nil plays the book
+// weights unchanged.
+func (r *RuleEngine) UseLearning(l *polyglot.Learning) {
+	r.learn = l
+}
+
+// Learning returns the active learning record, or nil.
+func (r *RuleEngine) Learning() *polyglot.Learning {
+	return r.learn
+}
+
+// BookStep identifies move m, about to be played here, for book learning.
+func (r *RuleEngine) BookStep(m SimpleMove) polyglot.Step {
+	return polyglot.Step{Key: polyglot.Key(r.Board, r.State), Move: r.encodeBookMove(m), Color: r.Turn}
+}
+
+// gamePly counts half-moves since the start of the game.
+func (r *RuleEngine) gamePly() int {
+	return 2*(r.State.FullmoveNumber-1) + r.Turn
 }
 
 func keyFromFEN(f string) string {
@@ -66,16 +133,3 @@ func keyFromFEN(f string) string {
 	}
 	return f
 }
-
-func parseCoord(s string) *address.Addr {
-	if len(s) != 2 {
-		return nil
-	}
-	file := int(s[0] - 'a')
-	rank := int(s[1] - '1')
-	if file < 0 || file > 7 || rank < 0 || rank > 7 {
-		return nil
-	}
-	a := address.MakeAddr(address.Rank(rank), address.File(file))
-	return &a
-}
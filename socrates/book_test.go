@@ -0,0 +1,146 @@
+package socrates
+
+import (
+	"testing"
+
+	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/polyglot"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+func TestPolyglotBookReplacesBuiltInBook(t *testing.T) {
+	e := engineAt(t, startFEN)
+	b3 := polyglot.Move{From: address.MakeAddr(1, 1), To: address.MakeAddr(2, 1)}
+	e.UseBook(polyglot.New([]polyglot.Entry{{
+		Key:    polyglot.Key(e.Board, e.State),
+		Move:   polyglot.EncodeMove(e.Board, b3),
+		Weight: 1,
+	}}))
+
+	res := e.Search(3)
+	if res.From != b3.From || res.To != b3.To || res.Nodes != 0 {
+		t.Fatalf("expected book move b2b3, got %+v", res)
+	}
+
+	// Out of the file's book the engine searches; the built-in book stays off.
+	e.MakeMove(b3.From, b3.To, 0)
+	if bm := e.BookMove(); bm != nil {
+		t.Fatalf("built-in book used despite a book file: %+v", bm)
+	}
+
+	e.UseBook(nil)
+	e.UndoMove()
+	if bm := e.BookMove(); bm == nil {
+		t.Fatal("built-in book not restored")
+	}
+}
+
+// weightedBook puts e4 (weight 6), d4 (3) and c4 (1) in the start position.
+func weightedBook(t *testing.T) *RuleEngine {
+	t.Helper()
+	e := engineAt(t, startFEN)
+	key := polyglot.Key(e.Board, e.State)
+	var entries []polyglot.Entry
+	for i, w := range []uint16{6, 3, 1} {
+		m := polyglot.Move{From: address.MakeAddr(1, address.File(4-i)), To: address.MakeAddr(3, address.File(4-i))}
+		entries = append(entries, polyglot.Entry{Key: key, Move: polyglot.EncodeMove(e.Board, m), Weight: w})
+	}
+	e.UseBook(polyglot.New(entries))
+	return e
+}
+
+func bookFiles(e *RuleEngine, o BookOptions, games int) map[address.File]int {
+	seen := map[address.File]int{}
+	for g := 0; g < games; g++ {
+		o.Game = g
+		e.SetBookOptions(o)
+		if bm := e.BookMove(); bm != nil {
+			seen[bm.From.File]++
+		}
+	}
+	return seen
+}
+
+func TestBookPolicies(t *testing.T) {
+	e := weightedBook(t)
+	if seen := bookFiles(e, BookOptions{Policy: BookBest}, 50); seen[4] != 50 {
+		t.Fatalf("best-only played %v", seen)
+	}
+	weighted := bookFiles(e, BookOptions{Policy: BookWeighted}, 1000)
+	if weighted[4] < weighted[3] || weighted[3] < weighted[2] || weighted[2] == 0 {
+		t.Fatalf("weighted picks %v do not follow weights 6:3:1", weighted)
+	}
+	uniform := bookFiles(e, BookOptions{Policy: BookUniform}, 1000)
+	if uniform[2] < 250 {
+		t.Fatalf("uniform picks %v", uniform)
+	}
+	if seen := bookFiles(e, BookOptions{MinWeight: 50}, 200); seen[2] != 0 || seen[3] == 0 {
+		t.Fatalf("MinWeight 50 should keep e4 and d4 only: %v", seen)
+	}
+}
+
+func TestBookIsReproducible(t *testing.T) {
+	e := weightedBook(t)
+	for _, seed := range []int64{0, 7} {
+		e.SetBookOptions(BookOptions{Seed: seed, Game: 3})
+		first := *e.BookMove()
+		for i := 0; i < 20; i++ {
+			if got := *e.BookMove(); got != first {
+				t.Fatalf("seed %d: %v then %v", seed, first, got)
+			}
+		}
+	}
+
+	// The built-in book must not be reordered by use.
+	key := keyFromFEN(e.Board.ToFEN(e.State))
+	before := append([]string(nil), miniBook[key]...)
+	e.UseBook(nil)
+	bookFiles(e, BookOptions{}, 20)
+	for i, mv := range miniBook[key] {
+		if mv != before[i] {
+			t.Fatalf("built-in book mutated: %v", miniBook[key])
+		}
+	}
+}
+
+func TestBookLimits(t *testing.T) {
+	e := weightedBook(t)
+	e.SetBookOptions(BookOptions{Disabled: true})
+	if e.BookMove() != nil {
+		t.Fatal("disabled book played a move")
+	}
+	e.SetBookOptions(BookOptions{})
+	if res := e.Analyze(1); res.Nodes == 0 {
+		t.Fatal("Analyze took a book move")
+	}
+
+	// Black to move after 1. e4: ply 1 is past a one-ply book.
+	e = engineAt(t, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	if e.BookMove() == nil {
+		t.Fatal("built-in book has replies to 1. e4")
+	}
+	e.SetBookOptions(BookOptions{MaxPly: 1})
+	if e.BookMove() != nil {
+		t.Fatal("book used beyond MaxPly")
+	}
+}
+
+func TestBookLearningRetiresLosingLines(t *testing.T) {
+	e := weightedBook(t)
+	e.SetBookOptions(BookOptions{Policy: BookBest})
+	l := polyglot.NewLearning()
+	e.UseLearning(l)
+
+	bm := e.BookMove()
+	if bm == nil || bm.From.File != 4 {
+		t.Fatalf("expected e4 first, got %+v", bm)
+	}
+	step := e.BookStep(*bm)
+	for i := 0; i < polyglot.DropLosses; i++ {
+		l.Record([]polyglot.Step{step}, 0)
+	}
+	if bm := e.BookMove(); bm == nil || bm.From.File != 3 {
+		t.Fatalf("e4 lost %d games but is still played: %+v", polyglot.DropLosses, bm)
+	}
+}
@@ -0,0 +1,120 @@
+// --- socrates/bookpolicy.go ---
+
+package socrates
+
+import "strings"
+
+// BookPolicy decides how a move is chosen among the book candidates.
+type BookPolicy int
+
+const (
+	// BookWeighted picks at random in proportion to the book weights.
+	BookWeighted BookPolicy = iota
+	// BookBest always plays the highest-weighted move.
+	BookBest
+	// BookUniform picks any candidate with equal chance.
+	BookUniform
+)
+
+var bookPolicyNames = []string{"weighted", "best", "uniform"}
+
+func (p BookPolicy) String() string {
+	if p < 0 || int(p) >= len(bookPolicyNames) {
+		return "unknown"
+	}
+	return bookPolicyNames[p]
+}
+
+// BookPolicyNames lists the policies in declaration order.
+func BookPolicyNames() []string {
+	return append([]string(nil), bookPolicyNames...)
+}
+
+// BookPolicyByName looks up a policy, ignoring case.
+func BookPolicyByName(name string) (BookPolicy, bool) {
+	for i, n := range bookPolicyNames {
+		if strings.EqualFold(n, strings.TrimSpace(name)) {
+			return BookPolicy(i), true
+		}
+	}
+	return 0, false
+}
+
+// BookOptions configures book play. The zero value plays weighted moves
+// for the whole book with seed 0.
+//
+// Random choices are a pure function of Seed, Game and the position, so a
+// replayed game repeats its book moves exactly while a new Game number
+// walks a different path through the same book.
+type BookOptions struct {
+	Disabled bool // never play from the book
+	Policy   BookPolicy
+	MaxPly   int // leave the book after this many half-moves (0 = no limit)
+	Seed     int64
+	Game     int // varies the random choices from one game to the next
+
+	// MinWeight drops candidates weighing less than this percentage of the
+	// best move, narrowing the repertoire; 0 keeps every move.
+	MinWeight int
+}
+
+// SetBookOptions configures how book moves are chosen.
+func (r *RuleEngine) SetBookOptions(o BookOptions) {
+	r.bookOpts = o
+}
+
+// BookOptions returns the book configuration.
+func (r *RuleEngine) BookOptions() BookOptions {
+	return r.bookOpts
+}
+
+// choose returns the index of the chosen candidate, or -1 when there is none.
+func (o BookOptions) choose(weights []int, key uint64) int {
+	top := -1
+	for i, w := range weights {
+		if top < 0 || w > weights[top] {
+			top = i
+		}
+	}
+	if top < 0 || o.Policy == BookBest {
+		return top
+	}
+
+	cut := weights[top] * o.MinWeight / 100
+	total := 0
+	for _, w := range weights {
+		if w >= cut {
+			total += o.share(w)
+		}
+	}
+	if total == 0 {
+		return top
+	}
+	n := int(o.random(key) % uint64(total))
+	for i, w := range weights {
+		if w < cut {
+			continue
+		}
+		if n < o.share(w) {
+			return i
+		}
+		n -= o.share(w)
+	}
+	return top
+}
+
+// share is a candidate's slice of the random draw.
+func (o BookOptions) share(w int) int {
+	if o.Policy == BookUniform {
+		return 1
+	}
+	return w
+}
+
+// random derives the draw for one position (splitmix64 finaliser).
+func (o BookOptions) random(key uint64) uint64 {
+	x := key ^ uint64(o.Seed) ^ uint64(o.Game)*0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
@@ -0,0 +1,78 @@
+// --- socrates/contempt.go ---
+
+package socrates
+
+// MaxContempt bounds the contempt setting and its dynamic adjustments.
+const MaxContempt = 100
+
+// contempt makes the engine score draws below zero for itself. The zero
+// value scores every draw as exactly 0.
+type contempt struct {
+	base        int  // centipawns; positive avoids draws, negative seeks them
+	dynamic     bool // scale with the root evaluation
+	opponentElo int  // 0 when unknown
+
+	// Resolved at the root of each search.
+	score    int
+	rootSide int
+}
+
+// SetContempt sets the centipawn value the engine gives up by drawing.
+func (r *RuleEngine) SetContempt(cp int) {
+	r.contempt.base = max(-MaxContempt, min(MaxContempt, cp))
+}
+
+// Contempt returns the static contempt set by SetContempt.
+func (r *RuleEngine) Contempt() int {
+	return r.contempt.base
+}
+
+// SetDynamicContempt lets the root evaluation adjust the contempt: more
+// when ahead, less (down to welcoming a draw) when behind.
+func (r *RuleEngine) SetDynamicContempt(on bool) {
+	r.contempt.dynamic = on
+}
+
+// DynamicContempt reports whether SetDynamicContempt is on.
+func (r *RuleEngine) DynamicContempt() bool {
+	return r.contempt.dynamic
+}
+
+// SetOpponentElo adjusts the contempt to the rating gap between the
+// engine's playing strength and its opponent; 0 means unknown.
+func (r *RuleEngine) SetOpponentElo(elo int) {
+	r.contempt.opponentElo = elo
+}
+
+// OpponentElo returns the rating set by SetOpponentElo, or 0.
+func (r *RuleEngine) OpponentElo() int {
+	return r.contempt.opponentElo
+}
+
+// EngineElo is the nominal rating of the current skill level.
+func (r *RuleEngine) EngineElo() int {
+	return MinElo + r.SkillLevel()*(MaxElo-MinElo)/MaxSkillLevel
+}
+
+// resolveContempt fixes the draw score for a search from the side to move.
+func (r *RuleEngine) resolveContempt() {
+	c := &r.contempt
+	c.rootSide = r.Turn
+	c.score = c.base
+	if c.opponentElo > 0 {
+		c.score += (r.EngineElo() - c.opponentElo) / 10
+	}
+	if c.dynamic {
+		eval := r.evaluateRelative()
+		c.score += MaxContempt / 2 * eval / (abs(eval) + 200)
+	}
+	c.score = max(-MaxContempt, min(MaxContempt, c.score))
+}
+
+// drawScore is the value of a draw for the side to move.
+func (r *RuleEngine) drawScore() int {
+	if r.Turn == r.contempt.rootSide {
+		return -r.contempt.score
+	}
+	return r.contempt.score
+}
@@ -0,0 +1,61 @@
+package socrates
+
+import "testing"
+
+// With the fifty-move counter at 99 every rook or king move draws, so the
+// root score is the draw score itself.
+const fiftyMoveFEN = "4k3/8/8/8/8/8/8/R3K3 w - - 99 80"
+
+func TestDrawsScoreZeroWithoutContempt(t *testing.T) {
+	e := engineAt(t, fiftyMoveFEN)
+	if res := e.Search(2); res.Score != 0 {
+		t.Fatalf("draw scored %d without contempt", res.Score)
+	}
+}
+
+func TestContemptScoresDrawsAgainstTheEngine(t *testing.T) {
+	e := engineAt(t, fiftyMoveFEN)
+	e.SetContempt(40)
+	if res := e.Search(2); res.Score != -40 {
+		t.Fatalf("draw scored %d with contempt 40", res.Score)
+	}
+
+	// The sign follows the side to move, not the colour.
+	e.resolveContempt()
+	if got := e.drawScore(); got != -40 {
+		t.Fatalf("root side draw score %d", got)
+	}
+	e.Turn = 1 - e.Turn
+	if got := e.drawScore(); got != 40 {
+		t.Fatalf("opponent draw score %d", got)
+	}
+}
+
+func TestDynamicContempt(t *testing.T) {
+	e := engineAt(t, fiftyMoveFEN)
+	e.SetContempt(40)
+	e.SetDynamicContempt(true)
+	// A rook up, the engine wants the draw even less.
+	if res := e.Search(2); res.Score >= -40 {
+		t.Fatalf("winning side should raise contempt, draw scored %d", res.Score)
+	}
+
+	e = engineAt(t, "4k3/8/8/8/8/8/8/r3K3 w - - 99 80")
+	e.SetContempt(40)
+	e.SetDynamicContempt(true)
+	if res := e.Search(2); res.Score <= -40 {
+		t.Fatalf("losing side should lower contempt, draw scored %d", res.Score)
+	}
+}
+
+func TestContemptFromOpponentRating(t *testing.T) {
+	e := engineAt(t, fiftyMoveFEN)
+	e.SetOpponentElo(e.EngineElo() - 500)
+	if res := e.Search(2); res.Score != -50 {
+		t.Fatalf("a weaker opponent should cost a draw 50cp, got %d", res.Score)
+	}
+	e.SetOpponentElo(e.EngineElo() + 5000)
+	if res := e.Search(2); res.Score != MaxContempt {
+		t.Fatalf("contempt should clamp at %d, got %d", MaxContempt, res.Score)
+	}
+}
@@ -69,3 +69,18 @@ func (r *RuleEngine) hasAnyLegalMove() bool {
 	})
 	return found
 }
+
+// GameResult scores a finished game from White's side: 1, 0.5 or 0.
+// over is false while the game is still going.
+func (r *RuleEngine) GameResult() (score float64, over bool) {
+	switch {
+	case r.IsCheckmate():
+		if r.Turn == pieces.WHITE {
+			return 0, true
+		}
+		return 1, true
+	case r.IsStalemate(), r.IsDraw(), r.IsInsufficientMaterial():
+		return 0.5, true
+	}
+	return 0, false
+}
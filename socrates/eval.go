@@ -5,10 +5,12 @@ package socrates
 import (
 	"github.com/mesb/mchess/address"
 	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/endgame"
 	"github.com/mesb/mchess/pieces"
 )
 
-// Score constants (centipawns)
+// Score constants (centipawns). These are the compiled-in defaults; the
+// active values live in EvalParams and may be replaced by a tuned file.
 const (
 	ValuePawn   = 100
 	ValueKnight = 320
@@ -28,41 +30,134 @@ func Evaluate(b *board.Board) int {
 
 // EvaluatePosition includes optional state for mobility-aware scoring.
 func EvaluatePosition(b *board.Board, state *board.GameState) int {
-	score := 0
-	b.ForEachPiece(func(sq address.Addr, p pieces.Piece) {
-		val := 0
-		idx := sq.Index() // 0 = a1, 63 = h8
-
-		// 1. Material & Position Score
-		switch p.(type) {
-		case *pieces.Pawn:
-			val = ValuePawn + pstPawn[mirror(p.Color(), idx)]
-		case *pieces.Knight:
-			val = ValueKnight + pstKnight[mirror(p.Color(), idx)]
-		case *pieces.Bishop:
-			val = ValueBishop + pstBishop[mirror(p.Color(), idx)]
-		case *pieces.Rook:
-			val = ValueRook + pstRook[mirror(p.Color(), idx)]
-		case *pieces.Queen:
-			val = ValueQueen + pstQueen[mirror(p.Color(), idx)]
-		case *pieces.King:
-			val = ValueKing + pstKingMid[mirror(p.Color(), idx)]
-		}
+	return evaluateWith(b, state, scanPSQT(b), nil)
+}
 
-		// Mobility bonus encourages development and activity.
-		if state != nil {
-			m := p.ValidMoves(sq, b, state)
-			val += len(m) * MobilityWeight
+// evaluateWith scores a board given its material/PST sums, which the engine
+// keeps incrementally and EvaluatePosition computes from scratch. A non-nil
+// style reweights the terms.
+func evaluateWith(b *board.Board, state *board.GameState, s psqt, style *Personality) int {
+	turn := pieces.WHITE
+	if state != nil {
+		turn = state.Turn
+	}
+	// Known endgames bypass the general terms entirely. The piece counts kept
+	// with the sums tell whether one is possible, so the board is only
+	// scanned into piece lists when it is.
+	var eg *endgame.Position
+	if s.material.Known() {
+		eg = endgame.NewPosition(b, turn)
+		if score, ok := eg.Evaluate(); ok {
+			return score
 		}
+	}
 
-		// 2. Accumulate
-		if p.Color() == pieces.WHITE {
-			score += val
-		} else {
-			score -= val
-		}
+	// Mobility bonus encourages development and activity.
+	mobility := 0
+	if state != nil {
+		weight := evalParams.Mobility
+		b.ForEachPiece(func(sq address.Addr, p pieces.Piece) {
+			n := len(p.ValidMoves(sq, b, state)) * weight
+			if p.Color() == pieces.WHITE {
+				mobility += n
+			} else {
+				mobility -= n
+			}
+		})
+	}
+	score := style.weigh(s.mat, s.blend()-s.mat, mobility)
+	if eg == nil {
+		return score
+	}
+	return eg.Scale(score)
+}
+
+// phaseTotal is the game phase with every minor and major piece on the
+// board; it falls to 0 with bare kings and pawns.
+const phaseTotal = 24
+
+func phaseOf(p pieces.Piece) int {
+	switch p.(type) {
+	case *pieces.Knight, *pieces.Bishop:
+		return 1
+	case *pieces.Rook:
+		return 2
+	case *pieces.Queen:
+		return 4
+	}
+	return 0
+}
+
+// psqt holds the material plus piece-square sums from White's view for the
+// midgame and the endgame, and the phase used to blend them. mat is the
+// material part alone, so personalities can weigh it apart from placement;
+// material counts the pieces for the endgame dispatch. gen records the
+// parameter set the sums were built from.
+type psqt struct {
+	mg, eg, mat, phase int
+	material           endgame.Material
+	gen                int
+}
+
+// pieceTerms returns the midgame and endgame value of a piece on idx, and
+// the material value included in both.
+func pieceTerms(params *EvalParams, p pieces.Piece, idx int) (mg, eg, mat int) {
+	i := mirror(p.Color(), idx)
+	var pst int
+	switch p.(type) {
+	case *pieces.Pawn:
+		mat, pst = params.Pawn, params.PSTPawn[i]
+	case *pieces.Knight:
+		mat, pst = params.Knight, params.PSTKnight[i]
+	case *pieces.Bishop:
+		mat, pst = params.Bishop, params.PSTBishop[i]
+	case *pieces.Rook:
+		mat, pst = params.Rook, params.PSTRook[i]
+	case *pieces.Queen:
+		mat, pst = params.Queen, params.PSTQueen[i]
+	case *pieces.King:
+		return ValueKing + params.PSTKing[i], ValueKing + params.PSTKingEnd[i], ValueKing
+	}
+	return mat + pst, mat + pst, mat
+}
+
+func (s *psqt) add(p pieces.Piece, idx int) {
+	mg, eg, mat := pieceTerms(&evalParams, p, idx)
+	if p.Color() == pieces.BLACK {
+		mg, eg, mat = -mg, -eg, -mat
+	}
+	s.mg += mg
+	s.eg += eg
+	s.mat += mat
+	s.phase += phaseOf(p)
+	s.material[p.Color()][pieceIndex(p)]++
+}
+
+func (s *psqt) remove(p pieces.Piece, idx int) {
+	mg, eg, mat := pieceTerms(&evalParams, p, idx)
+	if p.Color() == pieces.BLACK {
+		mg, eg, mat = -mg, -eg, -mat
+	}
+	s.mg -= mg
+	s.eg -= eg
+	s.mat -= mat
+	s.phase -= phaseOf(p)
+	s.material[p.Color()][pieceIndex(p)]--
+}
+
+// blend tapers from the midgame to the endgame score as pieces come off.
+func (s psqt) blend() int {
+	ph := min(s.phase, phaseTotal) // promotions can exceed the start phase
+	return (s.mg*ph + s.eg*(phaseTotal-ph)) / phaseTotal
+}
+
+// scanPSQT computes the material/PST sums of a board from scratch.
+func scanPSQT(b *board.Board) psqt {
+	s := psqt{gen: evalParamsGen}
+	b.ForEachPiece(func(sq address.Addr, p pieces.Piece) {
+		s.add(p, sq.Index())
 	})
-	return score
+	return s
 }
 
 // mirror flips the index for Black so we can use the same PST array.
@@ -145,3 +240,14 @@ var pstKingMid = [64]int{
 	20, 20, 0, 0, 0, 0, 20, 20,
 	20, 30, 10, 0, 0, 10, 30, 20,
 }
+
+var pstKingEnd = [64]int{
+	-50, -40, -30, -20, -20, -30, -40, -50,
+	-30, -20, -10, 0, 0, -10, -20, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -30, 0, 0, 0, 0, -30, -30,
+	-50, -30, -30, -30, -30, -30, -30, -50,
+}
@@ -1,10 +1,12 @@
 package socrates
 
 import (
+	"os"
 	"testing"
 
 	"github.com/mesb/mchess/address"
 	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/endgame"
 	"github.com/mesb/mchess/pieces"
 )
 
@@ -35,3 +37,117 @@ func TestEvaluateSymmetry(t *testing.T) {
 		t.Fatalf("expected symmetrical knights to cancel, got %d", got)
 	}
 }
+
+func TestLoadEvalParamsKeepsDefaultsForMissingFields(t *testing.T) {
+	path := t.TempDir() + "/params.json"
+	if err := os.WriteFile(path, []byte(`{"queen": 1000}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer SetEvalParams(DefaultEvalParams())
+
+	if err := LoadEvalParams(path); err != nil {
+		t.Fatalf("LoadEvalParams: %v", err)
+	}
+	p := CurrentEvalParams()
+	if p.Queen != 1000 || p.Rook != ValueRook || p.PSTKnight != pstKnight {
+		t.Fatalf("unexpected params after load: queen=%d rook=%d", p.Queen, p.Rook)
+	}
+
+	b := board.NewBoard()
+	b.SetPiece(address.MakeAddr(3, 3), pieces.NewQueen(pieces.WHITE))
+	if got := Evaluate(b); got != 1000+pstQueen[27] {
+		t.Fatalf("eval should use loaded queen value, got %d", got)
+	}
+}
+
+func TestEvaluateUsesEndgameKnowledge(t *testing.T) {
+	b, state, err := board.FromFEN("k7/8/8/8/8/8/P7/K1B5 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := EvaluatePosition(b, state); got != 0 {
+		t.Fatalf("wrong rook pawn should evaluate as a draw, got %d", got)
+	}
+
+	b, state, err = board.FromFEN("8/8/8/3k4/8/8/8/R3K3 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := EvaluatePosition(b, state); got < endgame.KnownWin {
+		t.Fatalf("KRK should be a known win, got %d", got)
+	}
+}
+
+// engineAt sets fen up on a new engine.
+func engineAt(t *testing.T, fen string) *RuleEngine {
+	t.Helper()
+	b, state, err := board.FromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(b)
+	e.SetPosition(b, state)
+	return e
+}
+
+func TestIncrementalEvalTracksMakeAndUndo(t *testing.T) {
+	AssertIncrementalEval = true
+	defer func() { AssertIncrementalEval = false }()
+
+	// Covers capture, en passant, promotion and both castling rook shifts.
+	e := engineAt(t, "r2nk2r/1P6/8/8/5p2/8/4P3/R3K2R w KQkq - 0 1")
+	line := []string{"e2e4", "f4e3", "b7a8q", "e8g8", "e1c1"}
+	for _, mv := range line {
+		from, to, promo, _ := ParseMove(mv)
+		if !e.MakeMove(*from, *to, promo) {
+			t.Fatalf("move %s rejected", mv)
+		}
+		if e.hash != computeHash(e.Board, e.State, e.Turn) {
+			t.Fatalf("after %s: incremental hash differs from a rescan", mv)
+		}
+		want := EvaluatePosition(e.Board, e.State)
+		if e.Turn == pieces.BLACK {
+			want = -want
+		}
+		if got := e.evaluateRelative(); got != want {
+			t.Fatalf("after %s: incremental eval %d != scratch %d", mv, got, want)
+		}
+	}
+	for range line {
+		e.UndoMove()
+	}
+	if e.psqt != scanPSQT(e.Board) {
+		t.Fatal("undo did not restore the starting sums")
+	}
+
+	// The search makes and unmakes thousands of moves under the assertion.
+	e = engineAt(t, "r3k2r/pPppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	e.Search(3)
+}
+
+func TestIncrementalEvalFollowsParamChanges(t *testing.T) {
+	defer SetEvalParams(DefaultEvalParams())
+	e := engineAt(t, "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1")
+	from, to, _, _ := ParseMove("d1d5")
+	e.MakeMove(*from, *to, 0)
+
+	p := DefaultEvalParams()
+	p.Rook += 50
+	SetEvalParams(p)
+	if got, want := e.evaluateRelative(), -EvaluatePosition(e.Board, e.State); got != want {
+		t.Fatalf("after a parameter change: %d, want %d", got, want)
+	}
+	e.UndoMove()
+	if got, want := e.evaluateRelative(), EvaluatePosition(e.Board, e.State); got != want {
+		t.Fatalf("after undo across a parameter change: %d, want %d", got, want)
+	}
+}
+
+func TestEvaluationTapersKingTowardsTheCentre(t *testing.T) {
+	// With only pawns left the king is scored by the endgame table.
+	centre, _, _ := board.FromFEN("4k3/pppppppp/8/8/3K4/8/PPPPPPPP/8 w - - 0 1")
+	corner, _, _ := board.FromFEN("4k3/pppppppp/8/8/8/8/PPPPPPPP/K7 w - - 0 1")
+	if Evaluate(centre) <= Evaluate(corner) {
+		t.Fatalf("endgame king should prefer the centre: %d vs %d", Evaluate(centre), Evaluate(corner))
+	}
+}
@@ -0,0 +1,53 @@
+// --- socrates/incremental.go ---
+
+package socrates
+
+import (
+	"fmt"
+
+	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/pieces"
+)
+
+// AssertIncrementalEval makes every MakeMove, UndoMove and evaluation check
+// the incrementally maintained material/PST sums against a full rescan and
+// panic on a mismatch. It is meant for tests and debugging sessions.
+var AssertIncrementalEval = false
+
+// evalRefresh rebuilds the incremental evaluation terms from the board.
+func (r *RuleEngine) evalRefresh() {
+	r.psqt = scanPSQT(r.Board)
+	r.nnRefresh()
+}
+
+// evalAdd and evalRemove mirror a board edit made by MakeMove in every
+// incremental evaluation term.
+func (r *RuleEngine) evalAdd(p pieces.Piece, sq address.Addr) {
+	r.psqt.add(p, sq.Index())
+	r.nnAdd(p, sq)
+}
+
+func (r *RuleEngine) evalRemove(p pieces.Piece, sq address.Addr) {
+	r.psqt.remove(p, sq.Index())
+	r.nnRemove(p, sq)
+}
+
+// currentPSQT returns the incremental sums, rebuilding them first if the
+// evaluation parameters changed since they were computed.
+func (r *RuleEngine) currentPSQT() psqt {
+	if r.psqt.gen != evalParamsGen {
+		r.psqt = scanPSQT(r.Board)
+	}
+	r.assertEval("evaluate")
+	return r.psqt
+}
+
+func (r *RuleEngine) assertEval(where string) {
+	if !AssertIncrementalEval || r.psqt.gen != evalParamsGen {
+		return
+	}
+	if want := scanPSQT(r.Board); r.psqt != want {
+		panic(fmt.Sprintf("incremental eval mismatch after %s in %s: have %+v, want %+v",
+			where, r.Board.ToFEN(r.State), r.psqt, want))
+	}
+}
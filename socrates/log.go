@@ -4,8 +4,6 @@
 package socrates
 
 import (
-	"fmt"
-
 	"github.com/mesb/mchess/address"
 	"github.com/mesb/mchess/board"
 	"github.com/mesb/mchess/pieces"
@@ -26,6 +24,9 @@ type Move struct {
 
 	// PrevState restores the full game state (turn, clocks, EP, castling).
 	PrevState StateSnapshot
+
+	// prevEval restores the incremental evaluation sums.
+	prevEval psqt
 }
 
 // CastleMove describes the rook motion in a castle.
@@ -103,15 +104,18 @@ func (r *RuleEngine) UndoMove() bool {
 
 	r.Board.SetPiece(latest.From, latest.Piece)
 
+	// Clear the destination first: after en passant the victim is restored elsewhere.
+	r.Board.Clear(latest.To)
 	if latest.Target != nil {
 		restoreTo := latest.To
 		if latest.TargetPos != nil {
 			restoreTo = *latest.TargetPos
 		}
 		r.Board.SetPiece(restoreTo, latest.Target)
-	} else {
-		r.Board.Clear(latest.To)
 	}
+	r.psqt = latest.prevEval
+	r.nnPop()
+	r.assertEval("UndoMove")
 
 	if len(r.hashHistory) > 1 {
 		r.hashHistory = r.hashHistory[:len(r.hashHistory)-1]
@@ -123,14 +127,6 @@ func (r *RuleEngine) UndoMove() bool {
 	return true
 }
 
-// PrintLog prints the move history in human-readable format.
-func (l *Log) PrintLog() {
-	fmt.Println("\nMove History:")
-	for i, m := range l.moves {
-		fmt.Printf("%2d. %s → %s (%s)\n", i+1, m.From, m.To, m.Piece.String())
-	}
-}
-
 // Moves returns the recorded move list (read-only access).
 func (l *Log) Moves() []Move {
 	return l.moves
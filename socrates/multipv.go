@@ -0,0 +1,67 @@
+// --- socrates/multipv.go ---
+
+package socrates
+
+import "sort"
+
+// MaxMultiPV caps the number of root lines reported by a search.
+const MaxMultiPV = 64
+
+// RootLine is one of the best root moves of a multi-PV search.
+type RootLine struct {
+	Move  SimpleMove
+	Score int
+}
+
+// SetMultiPV sets how many of the best root moves Analyze scores exactly.
+// One, the default, searches only for the best move.
+func (r *RuleEngine) SetMultiPV(n int) {
+	r.multiPV = max(1, min(MaxMultiPV, n))
+}
+
+// MultiPV returns the number of lines set by SetMultiPV.
+func (r *RuleEngine) MultiPV() int {
+	return max(1, r.multiPV)
+}
+
+// analyzeMulti searches every root move against the score of the n-th best
+// line so far, so the n best moves get exact scores and the rest fail low.
+func (r *RuleEngine) analyzeMulti(depth int, moves []SimpleMove) SearchResult {
+	n := min(r.MultiPV(), len(moves))
+	lines := make([]RootLine, 0, n+1)
+	totalNodes := 0
+	for i, m := range moves {
+		r.rootMove(m, i+1)
+		alpha := MinScore
+		if len(lines) == n {
+			alpha = lines[n-1].Score
+		}
+		r.MakeMove(m.From, m.To, m.Promo)
+		score, visited := r.negamax(depth-1, 1, -MaxScore, -alpha)
+		r.UndoMove()
+		totalNodes += visited + 1
+		if r.stopped {
+			break
+		}
+		if score = -score; score <= alpha && len(lines) == n {
+			continue
+		}
+		lines = append(lines, RootLine{Move: m, Score: score})
+		sort.SliceStable(lines, func(i, j int) bool { return lines[i].Score > lines[j].Score })
+		lines = lines[:min(len(lines), n)]
+	}
+
+	// Stopped before any line was scored, there is no move to offer; a
+	// finished search leaves its best move for the next one to try first.
+	if len(lines) == 0 {
+		return SearchResult{Score: MinScore, Nodes: totalNodes, TBHits: r.tbHits}
+	}
+	best := lines[0]
+	if !r.stopped {
+		r.storeTT(r.hash, depth, toTTScore(best.Score, 0), ttExact, best.Move)
+	}
+	return SearchResult{
+		From: best.Move.From, To: best.Move.To, Promo: best.Move.Promo,
+		Score: best.Score, Nodes: totalNodes, TBHits: r.tbHits, Lines: lines,
+	}
+}
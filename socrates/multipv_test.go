@@ -0,0 +1,62 @@
+package socrates
+
+import "testing"
+
+func TestMultiPVScoresTheBestLines(t *testing.T) {
+	// Rxd5 wins the queen; every other move leaves White a queen down.
+	fen := "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1"
+	single := engineAt(t, fen).Analyze(3)
+
+	e := engineAt(t, fen)
+	e.SetMultiPV(3)
+	res := e.Analyze(3)
+	if len(res.Lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(res.Lines))
+	}
+	if res.From != single.From || res.To != single.To || res.Score != single.Score {
+		t.Fatalf("multi-PV best %v%v %d, single %v%v %d", res.From, res.To, res.Score, single.From, single.To, single.Score)
+	}
+	for i := 1; i < len(res.Lines); i++ {
+		if res.Lines[i].Score > res.Lines[i-1].Score {
+			t.Fatalf("lines out of order: %+v", res.Lines)
+		}
+	}
+	if res.Lines[1].Score >= res.Score {
+		t.Fatalf("second line %d should trail the capture %d", res.Lines[1].Score, res.Score)
+	}
+}
+
+func TestHashSize(t *testing.T) {
+	e := engineAt(t, "4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if e.HashSize() != DefaultHashMB {
+		t.Fatalf("default hash %d MB, want %d", e.HashSize(), DefaultHashMB)
+	}
+	e.SetHashSize(3)
+	if n := len(e.tt); n&(n-1) != 0 || n*ttEntrySize > 3<<20 || e.HashSize() > 3 {
+		t.Fatalf("3 MB table has %d entries", n)
+	}
+	e.Analyze(2)
+	e.ClearHash()
+	for _, entry := range e.tt {
+		if entry != (ttEntry{}) {
+			t.Fatal("ClearHash left entries behind")
+		}
+	}
+}
+
+func TestMultiPVStoppedEarly(t *testing.T) {
+	e := engineAt(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	e.SetMultiPV(3)
+	full := e.Analyze(2)
+
+	// A finished search leaves its best move to be searched first.
+	if m := e.orderMoves(e.GenerateLegalMoves(), 0)[0]; m != full.Lines[0].Move {
+		t.Fatalf("root ordering starts with %v, best was %v", m, full.Lines[0].Move)
+	}
+
+	e.Stop()
+	defer e.ClearStop()
+	if res := e.AnalyzeWindow(3, MinScore, MaxScore); res.From != res.To || len(res.Lines) != 0 {
+		t.Fatalf("a search stopped before any line returned %v%v %d", res.From, res.To, res.Score)
+	}
+}
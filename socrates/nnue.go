@@ -0,0 +1,104 @@
+// --- socrates/nnue.go ---
+
+package socrates
+
+import (
+	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/endgame"
+	"github.com/mesb/mchess/nnue"
+	"github.com/mesb/mchess/pieces"
+)
+
+// UseNetwork switches the engine to neural network evaluation.
+// Passing nil restores the handcrafted evaluation.
+func (r *RuleEngine) UseNetwork(n *nnue.Network) {
+	r.net = n
+	r.accs = nil
+	if n != nil {
+		r.accs = n.NewStack()
+		r.nnRefresh()
+	}
+}
+
+// LoadEvalFile loads a network weight file and evaluates with it.
+// An empty path restores the handcrafted evaluation.
+func (r *RuleEngine) LoadEvalFile(path string) error {
+	if path == "" {
+		r.UseNetwork(nil)
+		return nil
+	}
+	n, err := nnue.Load(path)
+	if err != nil {
+		return err
+	}
+	r.UseNetwork(n)
+	return nil
+}
+
+// Network returns the active evaluation network, or nil for the handcrafted eval.
+func (r *RuleEngine) Network() *nnue.Network {
+	return r.net
+}
+
+// nnRefresh rebuilds the accumulator from scratch for the current board.
+func (r *RuleEngine) nnRefresh() {
+	if r.net == nil {
+		return
+	}
+	r.accs.Clear()
+	acc := r.accs.Current()
+	r.net.Reset(acc)
+	r.Board.ForEachPiece(func(sq address.Addr, p pieces.Piece) {
+		r.net.AddPiece(acc, p.Color(), pieceIndex(p), sq.Index())
+	})
+}
+
+func (r *RuleEngine) nnPush() {
+	if r.net != nil {
+		r.accs.Push()
+	}
+}
+
+// nnPop restores the accumulator of the previous position. Moves made before
+// the network was loaded have no saved accumulator, so those are recomputed.
+func (r *RuleEngine) nnPop() {
+	if r.net != nil && !r.accs.Pop() {
+		r.nnRefresh()
+	}
+}
+
+func (r *RuleEngine) nnAdd(p pieces.Piece, sq address.Addr) {
+	if r.net != nil {
+		r.net.AddPiece(r.accs.Current(), p.Color(), pieceIndex(p), sq.Index())
+	}
+}
+
+func (r *RuleEngine) nnRemove(p pieces.Piece, sq address.Addr) {
+	if r.net != nil {
+		r.net.RemovePiece(r.accs.Current(), p.Color(), pieceIndex(p), sq.Index())
+	}
+}
+
+// nnEvaluate scores the position with the network from the side to move's view.
+// Endgame knowledge still overrides or scales the network output where the
+// incrementally kept piece counts say it may apply.
+func (r *RuleEngine) nnEvaluate() int {
+	var eg *endgame.Position
+	if r.psqt.material.Known() {
+		eg = endgame.NewPosition(r.Board, r.Turn)
+		if score, ok := eg.Evaluate(); ok {
+			if r.Turn == pieces.BLACK {
+				return -score
+			}
+			return score
+		}
+	}
+	score := r.net.Evaluate(r.accs.Current(), r.Turn)
+	if eg == nil {
+		return score
+	}
+	if r.Turn == pieces.BLACK {
+		return -eg.Scale(-score)
+	}
+	return eg.Scale(score)
+}
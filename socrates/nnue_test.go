@@ -0,0 +1,81 @@
+package socrates
+
+import (
+	"testing"
+
+	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/nnue"
+)
+
+// scratchEval evaluates the current board with a freshly built accumulator.
+func scratchEval(e *RuleEngine) int {
+	acc := e.net.NewAccumulator()
+	e.net.Reset(acc)
+	for i := 0; i < 64; i++ {
+		if p := e.Board.PieceAt(address.TranslateIndex(i)); p != nil {
+			e.net.AddPiece(acc, p.Color(), pieceIndex(p), i)
+		}
+	}
+	return e.net.Evaluate(acc, e.Turn)
+}
+
+func TestNNUEIncrementalMatchesScratch(t *testing.T) {
+	// Covers capture, en passant, castling and promotion deltas.
+	e := engineAt(t, "r2nk2r/1P6/8/8/5p2/8/4P3/R3K2R w KQkq - 0 1")
+	if err := e.LoadEvalFile("../nnue/testdata/tiny.nnue"); err != nil {
+		t.Fatalf("load network: %v", err)
+	}
+	line := []string{"e2e4", "f4e3", "b7a8q", "e8g8", "e1c1"}
+	for _, mv := range line {
+		from, to, promo, _ := ParseMove(mv)
+		if !e.MakeMove(*from, *to, promo) {
+			t.Fatalf("move %s rejected", mv)
+		}
+		if got, want := e.evaluateRelative(), scratchEval(e); got != want {
+			t.Fatalf("after %s: incremental %d != scratch %d", mv, got, want)
+		}
+	}
+	for range line {
+		e.UndoMove()
+		if got, want := e.evaluateRelative(), scratchEval(e); got != want {
+			t.Fatalf("after undo: incremental %d != scratch %d", got, want)
+		}
+	}
+	if e.accs.Depth() != 0 {
+		t.Fatalf("accumulator stack not unwound: depth %d", e.accs.Depth())
+	}
+}
+
+func TestNNUEUndoBeforeLoad(t *testing.T) {
+	e := New(board.InitStandard())
+	from, to, _, _ := ParseMove("e2e4")
+	e.MakeMove(*from, *to, 0)
+	n, err := nnue.Load("../nnue/testdata/tiny.nnue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.UseNetwork(n)
+	e.UndoMove()
+	if got := e.evaluateRelative(); got != 0 {
+		t.Fatalf("start position should be balanced, got %d", got)
+	}
+}
+
+func TestNNUESearchFindsFreeQueen(t *testing.T) {
+	e := engineAt(t, "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1")
+	if err := e.LoadEvalFile("../nnue/testdata/tiny.nnue"); err != nil {
+		t.Fatalf("load network: %v", err)
+	}
+	res := e.Search(2)
+	if e.accs.Depth() != 0 || e.evaluateRelative() != scratchEval(e) {
+		t.Fatal("search left the accumulator out of sync")
+	}
+	if got := res.From.String()[:2] + res.To.String()[:2]; got != "d1d5" {
+		t.Fatalf("expected d1d5, got %s", got)
+	}
+	e.UseNetwork(nil)
+	if e.Network() != nil {
+		t.Fatal("expected handcrafted eval after UseNetwork(nil)")
+	}
+}
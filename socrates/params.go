@@ -0,0 +1,111 @@
+// --- socrates/params.go ---
+
+package socrates
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// EvalParams holds every tunable term of the handcrafted evaluation.
+// Piece-square tables are laid out like the pst* defaults: a1 = 0 .. h8 = 63,
+// from White's point of view.
+type EvalParams struct {
+	Pawn     int `json:"pawn"`
+	Knight   int `json:"knight"`
+	Bishop   int `json:"bishop"`
+	Rook     int `json:"rook"`
+	Queen    int `json:"queen"`
+	Mobility int `json:"mobility"`
+
+	PSTPawn   [64]int `json:"pst_pawn"`
+	PSTKnight [64]int `json:"pst_knight"`
+	PSTBishop [64]int `json:"pst_bishop"`
+	PSTRook   [64]int `json:"pst_rook"`
+	PSTQueen  [64]int `json:"pst_queen"`
+	PSTKing   [64]int `json:"pst_king"`
+	// PSTKingEnd replaces PSTKing as the board empties.
+	PSTKingEnd [64]int `json:"pst_king_end"`
+}
+
+// DefaultEvalParams returns the compiled-in evaluation constants.
+func DefaultEvalParams() EvalParams {
+	return EvalParams{
+		Pawn:       ValuePawn,
+		Knight:     ValueKnight,
+		Bishop:     ValueBishop,
+		Rook:       ValueRook,
+		Queen:      ValueQueen,
+		Mobility:   MobilityWeight,
+		PSTPawn:    pstPawn,
+		PSTKnight:  pstKnight,
+		PSTBishop:  pstBishop,
+		PSTRook:    pstRook,
+		PSTQueen:   pstQueen,
+		PSTKing:    pstKingMid,
+		PSTKingEnd: pstKingEnd,
+	}
+}
+
+// evalParams is the parameter set used by EvaluatePosition. evalParamsGen
+// changes with it so engines know their incremental sums are stale.
+var (
+	evalParams    = DefaultEvalParams()
+	evalParamsGen int
+)
+
+// SetEvalParams replaces the active evaluation parameters.
+// It must not be called while a search is running.
+func SetEvalParams(p EvalParams) {
+	evalParams = p
+	evalParamsGen++
+}
+
+// CurrentEvalParams returns a copy of the active evaluation parameters.
+func CurrentEvalParams() EvalParams {
+	return evalParams
+}
+
+// LoadEvalParams reads a JSON parameter file and makes it active.
+// Fields missing from the file keep their compiled-in defaults.
+func LoadEvalParams(path string) error {
+	p, err := ReadEvalParams(path)
+	if err != nil {
+		return err
+	}
+	SetEvalParams(p)
+	return nil
+}
+
+// ReadEvalParams reads a JSON parameter file without activating it.
+func ReadEvalParams(path string) (EvalParams, error) {
+	p := DefaultEvalParams()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// Save writes the parameters as indented JSON.
+func (p EvalParams) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Fields exposes every parameter in a fixed order so tuners can walk them.
+func (p *EvalParams) Fields() []*int {
+	fields := []*int{&p.Pawn, &p.Knight, &p.Bishop, &p.Rook, &p.Queen, &p.Mobility}
+	for _, table := range []*[64]int{&p.PSTPawn, &p.PSTKnight, &p.PSTBishop, &p.PSTRook, &p.PSTQueen, &p.PSTKing, &p.PSTKingEnd} {
+		for i := range table {
+			fields = append(fields, &table[i])
+		}
+	}
+	return fields
+}
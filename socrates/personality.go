@@ -0,0 +1,84 @@
+// --- socrates/personality.go ---
+
+package socrates
+
+import (
+	"sort"
+	"strings"
+)
+
+// Personality reweights the handcrafted evaluation terms to give the engine
+// a recognisable style. Weights are percentages of the normal value.
+// A loaded NNUE network is not affected.
+type Personality struct {
+	Name       string
+	Material   int // piece values
+	Positional int // piece-square placement
+	Mobility   int // legal move counts
+}
+
+// Built-in personality presets.
+var (
+	Balanced = Personality{Name: "balanced", Material: 100, Positional: 100, Mobility: 100}
+	// Aggressive trades material for activity and advanced pieces.
+	Aggressive = Personality{Name: "aggressive", Material: 90, Positional: 130, Mobility: 200}
+	// Solid keeps its pieces on good squares and takes few risks for activity.
+	Solid = Personality{Name: "solid", Material: 100, Positional: 120, Mobility: 50}
+	// Materialistic grabs whatever is offered.
+	Materialistic = Personality{Name: "materialistic", Material: 125, Positional: 70, Mobility: 50}
+)
+
+var personalities = map[string]Personality{
+	Balanced.Name:      Balanced,
+	Aggressive.Name:    Aggressive,
+	Solid.Name:         Solid,
+	Materialistic.Name: Materialistic,
+}
+
+// PersonalityByName looks up a preset, ignoring case.
+func PersonalityByName(name string) (Personality, bool) {
+	p, ok := personalities[strings.ToLower(strings.TrimSpace(name))]
+	return p, ok
+}
+
+// PersonalityNames lists the presets with "balanced" first.
+func PersonalityNames() []string {
+	names := make([]string, 0, len(personalities))
+	for name := range personalities {
+		if name != Balanced.Name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{Balanced.Name}, names...)
+}
+
+// weigh combines the evaluation terms; a nil personality adds them unchanged.
+func (p *Personality) weigh(material, positional, mobility int) int {
+	if p == nil {
+		return material + positional + mobility
+	}
+	return (material*p.Material + positional*p.Positional + mobility*p.Mobility) / 100
+}
+
+// SetPersonality changes the evaluation style. Scores stored under the old
+// style are dropped from the transposition table.
+func (r *RuleEngine) SetPersonality(p Personality) {
+	var style *Personality
+	if p != Balanced {
+		style = &p
+	}
+	if (style == nil) == (r.style == nil) && (style == nil || *style == *r.style) {
+		return
+	}
+	r.style = style
+	clear(r.tt)
+}
+
+// Personality returns the active evaluation style.
+func (r *RuleEngine) Personality() Personality {
+	if r.style == nil {
+		return Balanced
+	}
+	return *r.style
+}
@@ -0,0 +1,31 @@
+// --- socrates/pv.go ---
+
+package socrates
+
+// PV returns the principal variation starting with m, at most n moves long,
+// by following the best moves stored in the transposition table.
+func (r *RuleEngine) PV(m SimpleMove, n int) []SimpleMove {
+	var pv []SimpleMove
+	for len(pv) < n && r.isLegal(m) {
+		r.MakeMove(m.From, m.To, m.Promo)
+		pv = append(pv, m)
+		entry, ok := r.ttProbe(r.hash)
+		if !ok || r.isRepetition() {
+			break
+		}
+		m = entry.move
+	}
+	for range pv {
+		r.UndoMove()
+	}
+	return pv
+}
+
+func (r *RuleEngine) isLegal(m SimpleMove) bool {
+	for _, legal := range r.GenerateLegalMoves() {
+		if legal == m {
+			return true
+		}
+	}
+	return false
+}
@@ -4,10 +4,14 @@ package socrates
 
 import (
 	"strings"
+	"sync/atomic"
 
 	"github.com/mesb/mchess/address"
 	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/nnue"
 	"github.com/mesb/mchess/pieces"
+	"github.com/mesb/mchess/polyglot"
+	"github.com/mesb/mchess/syzygy"
 )
 
 type RuleEngine struct {
@@ -22,6 +26,33 @@ type RuleEngine struct {
 	tt  []ttEntry
 	gen int
 
+	net  *nnue.Network
+	accs *nnue.Stack
+
+	psqt psqt // incremental material and piece-square sums
+
+	tb     *syzygy.Tablebase
+	tbHits int
+
+	book     *polyglot.Book // nil uses the built-in book
+	bookOpts BookOptions
+	learn    *polyglot.Learning
+
+	style *Personality // nil evaluates with the default weights
+	skill skill
+
+	contempt contempt
+
+	multiPV int // root lines scored exactly; 0 means 1
+
+	// nodes counts negamax nodes; a search past nodeLimit (when set) stops.
+	nodes     int
+	nodeLimit int
+	stopped   bool
+	halt      atomic.Bool // set by Stop from another goroutine
+	selDepth  int
+	onRoot    func(m SimpleMove, number int)
+
 	history [2][64][64]int // color, from, to
 	killers [128][2]SimpleMove
 }
@@ -46,6 +77,9 @@ func (r *RuleEngine) MakeMove(from, to address.Addr, promoChar rune) bool {
 		return false
 	}
 
+	r.nnPush()
+	evalBefore := r.psqt
+
 	moving := r.Board.PieceAt(from)
 	target := r.Board.PieceAt(to)
 	targetPos := to
@@ -73,6 +107,7 @@ func (r *RuleEngine) MakeMove(from, to address.Addr, promoChar rune) bool {
 			if victimPos, ok := to.Shift(captureRankDir, 0); ok {
 				target = r.Board.PieceAt(victimPos)
 				hash = HashTogglePiece(hash, target, victimPos)
+				r.evalRemove(target, victimPos)
 				r.Board.Clear(victimPos)
 				isCapture = true
 				targetPos = victimPos
@@ -98,6 +133,8 @@ func (r *RuleEngine) MakeMove(from, to address.Addr, promoChar rune) bool {
 			r.Board.Clear(rookFrom)
 			hash = HashTogglePiece(hash, rook, rookFrom)
 			hash = HashTogglePiece(hash, rook, rookTo)
+			r.evalRemove(rook, rookFrom)
+			r.evalAdd(rook, rookTo)
 			rookMove = &CastleMove{From: rookFrom, To: rookTo}
 		}
 	}
@@ -112,13 +149,16 @@ func (r *RuleEngine) MakeMove(from, to address.Addr, promoChar rune) bool {
 			TargetPos: &targetPos,
 			RookMove:  rookMove,
 			PrevState: stateBefore,
+			prevEval:  evalBefore,
 		})
 	}
 
 	// Hash out moving and captured pieces at original squares
 	hash = HashTogglePiece(hash, moving, from)
-	if target != nil {
+	r.evalRemove(moving, from)
+	if target != nil && targetPos.Equals(to) {
 		hash = HashTogglePiece(hash, target, to)
+		r.evalRemove(target, to)
 	}
 
 	// Apply Main Move
@@ -141,6 +181,7 @@ func (r *RuleEngine) MakeMove(from, to address.Addr, promoChar rune) bool {
 
 	// Hash in final piece at destination
 	hash = HashTogglePiece(hash, finalPiece, to)
+	r.evalAdd(finalPiece, to)
 
 	// --- State Updates ---
 	r.updateEnPassantState(moving, from, to)
@@ -162,6 +203,7 @@ func (r *RuleEngine) MakeMove(from, to address.Addr, promoChar rune) bool {
 
 	r.hash = hash
 	r.hashHistory = append(r.hashHistory, hash)
+	r.assertEval("MakeMove")
 
 	return true
 }
@@ -379,9 +421,9 @@ func (r *RuleEngine) WouldBeInCheck(from, to address.Addr) bool {
 				captureRankDir = 1
 			}
 			if victimPos, ok := to.Shift(captureRankDir, 0); ok {
-				captured = r.Board.PieceAt(victimPos)
+				victim := r.Board.PieceAt(victimPos)
 				r.Board.Clear(victimPos)
-				defer r.Board.SetPiece(victimPos, captured)
+				defer r.Board.SetPiece(victimPos, victim)
 			}
 		}
 	}
@@ -401,7 +443,8 @@ func findKing(b *board.Board, color int) *address.Addr {
 func (r *RuleEngine) resetHashHistory() {
 	r.hash = computeHash(r.Board, r.State, r.Turn)
 	r.hashHistory = []uint64{r.hash}
-	if r.tt == nil || len(r.tt) != TTSize {
+	r.evalRefresh()
+	if r.tt == nil {
 		r.tt = make([]ttEntry, TTSize)
 	}
 	r.gen = 0
@@ -422,6 +465,14 @@ func (r *RuleEngine) ResetHashHistory() {
 	r.resetHashHistory()
 }
 
+// SetPosition sets up a new position with an empty move log, keeping the
+// hash table.
+func (r *RuleEngine) SetPosition(b *board.Board, s *board.GameState) {
+	r.Board, r.State, r.Turn = b, s, s.Turn
+	r.Log = &Log{}
+	r.ResetHashHistory()
+}
+
 func (r *RuleEngine) refreshHashHistory() {
 	r.hash = computeHash(r.Board, r.State, r.Turn)
 	r.hashHistory = append(r.hashHistory, r.hash)
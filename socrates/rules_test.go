@@ -209,3 +209,27 @@ func TestKingCannotApproachKing(t *testing.T) {
 		t.Fatal("Engine allowed King to move adjacent to enemy King (f5->g6)")
 	}
 }
+
+func TestEnPassantProbesLeaveBoardIntact(t *testing.T) {
+	fen := "rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3"
+	b, state, err := board.FromFEN(fen)
+	if err != nil {
+		t.Fatalf("Invalid FEN: %v", err)
+	}
+	e := New(b)
+	e.State = state
+	e.Turn = state.Turn
+	e.ResetHashHistory()
+
+	e.WouldBeInCheck(address.MakeAddr(4, 4), address.MakeAddr(5, 3)) // e5xd6 e.p.
+	if got := b.ToFEN(state); got != fen {
+		t.Fatalf("board changed by check probe:\n got: %s\nwant: %s", got, fen)
+	}
+
+	if !e.MakeMove(address.MakeAddr(4, 4), address.MakeAddr(5, 3), 0) || !e.UndoMove() {
+		t.Fatal("en passant make/undo failed")
+	}
+	if got := b.ToFEN(e.State); got != fen {
+		t.Fatalf("board changed by en passant undo:\n got: %s\nwant: %s", got, fen)
+	}
+}
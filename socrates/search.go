@@ -6,6 +6,7 @@ import (
 	"github.com/mesb/mchess/address"
 	"github.com/mesb/mchess/pieces"
 	"sort"
+	"strings"
 )
 
 const (
@@ -16,6 +17,11 @@ const (
 	MateScore = 30000
 	// EvalClamp keeps quiescence from hallucinating mates.
 	EvalClamp = 29000
+	// TBWinScore scores tablebase wins below any mate; like mates, a win
+	// found ply plies from the root is TBWinScore - ply.
+	TBWinScore = MateScore - 2000
+	// maxExtensionPly stops check extensions from growing a line forever.
+	maxExtensionPly = 64
 )
 
 // SearchResult holds the best move found and its evaluation.
@@ -25,18 +31,54 @@ type SearchResult struct {
 	Score int
 	Nodes int // how many positions were analyzed
 	Promo rune
+	// TBHits counts successful tablebase probes.
+	TBHits int
+	// Book reports a move taken from the opening book without searching.
+	Book bool
+	// Lines holds the best root moves, best first, when MultiPV is above one.
+	Lines []RootLine
+	// SelDepth is the deepest ply reached, quiescence included.
+	SelDepth int
+	// Bound tells whether Score is exact or only a bound of an aspiration
+	// window search.
+	Bound Bound
 }
 
-// Search runs the Alpha-Beta Negamax algorithm to a fixed depth.
+// Bound qualifies a search score.
+type Bound int
+
+const (
+	BoundExact Bound = iota
+	BoundLower       // the true score is at least Score
+	BoundUpper       // the true score is at most Score
+)
+
+// Search runs the Alpha-Beta Negamax algorithm to a fixed depth, playing
+// from the opening book while it has a move.
 func (r *RuleEngine) Search(depth int) SearchResult {
-	r.gen++
-	// Opening book try
 	if bm := r.BookMove(); bm != nil {
-		return SearchResult{From: bm.From, To: bm.To, Score: 0, Nodes: 0}
+		return SearchResult{From: bm.From, To: bm.To, Promo: bm.Promo, Book: true}
 	}
+	return r.Analyze(depth)
+}
+
+// Analyze searches the position to a fixed depth without consulting the
+// opening book.
+func (r *RuleEngine) Analyze(depth int) SearchResult {
+	return r.AnalyzeWindow(depth, MinScore, MaxScore)
+}
+
+// AnalyzeWindow is Analyze with an aspiration window: a score at or above
+// beta is reported as a lower bound as soon as it is found, and when no
+// move beats alpha the first move is returned with alpha as an upper bound.
+// Multi-PV and weakened searches always use the full window.
+func (r *RuleEngine) AnalyzeWindow(depth, alpha, beta int) SearchResult {
+	r.gen++
+	r.tbHits = 0
+	r.stopped = false
+	r.selDepth = 0
 
-	alpha := MinScore
-	beta := MaxScore
+	r.resolveContempt()
 
 	bestMove := SearchResult{Score: MinScore}
 
@@ -51,9 +93,31 @@ func (r *RuleEngine) Search(depth int) SearchResult {
 		return SearchResult{Score: score, Nodes: 1}
 	}
 
+	// Tablebase positions are decided at the root when DTZ is available.
+	moves, tbResult := r.probeRoot(moves)
+	if tbResult != nil {
+		return *tbResult
+	}
+
+	if r.skill.weakness > 0 {
+		res := r.searchWeakened(depth, moves)
+		res.TBHits = r.tbHits
+		res.SelDepth = r.selDepth
+		return res
+	}
+
+	if r.MultiPV() > 1 {
+		res := r.analyzeMulti(depth, moves)
+		res.SelDepth = r.selDepth
+		return res
+	}
+
 	totalNodes := 0
+	alphaOrig := alpha
+	found := false
 
-	for _, m := range moves {
+	for i, m := range moves {
+		r.rootMove(m, i+1)
 		r.MakeMove(m.From, m.To, m.Promo)
 
 		score, visited := r.negamax(depth-1, 1, -beta, -alpha)
@@ -62,16 +126,38 @@ func (r *RuleEngine) Search(depth int) SearchResult {
 
 		r.UndoMove()
 
+		if r.stopped {
+			break
+		}
 		if score > alpha {
 			alpha = score
+			found = true
 			bestMove.From = m.From
 			bestMove.To = m.To
 			bestMove.Score = score
 			bestMove.Promo = m.Promo
 		}
+		if score >= beta {
+			bestMove.Bound = BoundLower
+			break
+		}
+	}
+	if !found && !r.stopped && alphaOrig > MinScore {
+		bestMove = SearchResult{From: moves[0].From, To: moves[0].To, Promo: moves[0].Promo,
+			Score: alphaOrig, Bound: BoundUpper}
+	}
+	if found && !r.stopped {
+		m := SimpleMove{From: bestMove.From, To: bestMove.To, Promo: bestMove.Promo}
+		flag := ttExact
+		if bestMove.Bound == BoundLower {
+			flag = ttLower
+		}
+		r.storeTT(r.hash, depth, toTTScore(bestMove.Score, 0), flag, m)
 	}
 
 	bestMove.Nodes = totalNodes
+	bestMove.TBHits = r.tbHits
+	bestMove.SelDepth = r.selDepth
 	return bestMove
 }
 
@@ -79,11 +165,20 @@ func (r *RuleEngine) Search(depth int) SearchResult {
 func (r *RuleEngine) negamax(depth, ply, alpha, beta int) (int, int) {
 	nodes := 1 // count this node
 	alphaOrig := alpha
+	r.selDepth = max(r.selDepth, ply)
 
-	if r.IsDraw() {
+	r.nodes++
+	if (r.nodeLimit > 0 && r.nodes >= r.nodeLimit) || r.halt.Load() {
+		r.stopped = true
+	}
+	if r.stopped {
 		return 0, nodes
 	}
 
+	if r.IsDraw() {
+		return r.drawScore(), nodes
+	}
+
 	if entry, ok := r.ttProbe(r.hash); ok && entry.depth >= depth {
 		score := fromTTScore(entry.score, ply)
 		switch entry.flag {
@@ -101,55 +196,135 @@ func (r *RuleEngine) negamax(depth, ply, alpha, beta int) (int, int) {
 	}
 
 	if r.isRepetition() {
-		return 0, nodes
+		return r.drawScore(), nodes
+	}
+
+	// Tablebase cut: after a capture or pawn move into a covered ending the
+	// WDL value is exact.
+	if r.State.HalfmoveClock == 0 && r.tbCovered() {
+		if wdl, _, err := r.tbSearch(false); err == nil {
+			r.tbHits++
+			score := tbScore(wdl, ply, r.drawScore())
+			r.storeTT(r.hash, depth, toTTScore(score, ply), ttExact, SimpleMove{})
+			return score, nodes
+		}
+	}
+
+	sp := &searchParams
+	inCheck := r.IsInCheck(r.Turn)
+
+	// Check extension: look one ply further when the king is attacked, so
+	// checks at the horizon are resolved by the full search.
+	if inCheck && ply < maxExtensionPly {
+		depth += sp.CheckExtension
 	}
 
 	// 1. Leaf Node: Return Static Evaluation
-	if depth == 0 {
+	if depth <= 0 {
 		score, qNodes := r.quiesce(ply, alpha, beta)
 		return score, nodes + qNodes
 	}
 
+	// Static pruning needs a null window and a trustworthy static score;
+	// near mate scores every margin is meaningless.
+	pvNode := beta-alpha > 1
+	prunable := !pvNode && !inCheck && abs(beta) < TBWinScore-1000
+	staticEval := 0
+	if !inCheck {
+		staticEval = r.evaluateRelative()
+	}
+
+	// Reverse futility: far enough above beta that no reply will matter.
+	if prunable && depth <= sp.RFPMaxDepth && staticEval-sp.RFPMargin*depth >= beta {
+		return beta, nodes
+	}
+
+	// Razoring: hopelessly below alpha, so only tactics can save the node.
+	if prunable && depth <= sp.RazorMaxDepth && staticEval+sp.RazorMargin*depth < alpha {
+		score, qNodes := r.quiesce(ply, alpha, alpha+1)
+		nodes += qNodes
+		if score <= alpha {
+			return score, nodes
+		}
+	}
+
 	// 2. Generate Moves
 	moves := r.orderMoves(r.GenerateLegalMoves(), ply)
 
 	// 3. Game Over Detection
 	if len(moves) == 0 {
-		if r.IsInCheck(r.Turn) {
+		if inCheck {
 			return -MateScore + ply, nodes
 		}
-		return 0, nodes // Stalemate
+		return r.drawScore(), nodes // Stalemate
 	}
 
-	// 4. Null-move pruning
-	if depth >= 3 && !r.IsInCheck(r.Turn) {
+	// 4. Null-move pruning, skipped in pawn endings where zugzwang is the
+	// rule and passing would be the best move.
+	if depth >= sp.NullMoveMinDepth && !inCheck && staticEval >= beta && r.hasNonPawnMaterial(r.Turn) {
+		reduction := sp.NullMoveReduction
+		if sp.NullMoveDepthDiv > 0 {
+			reduction += depth / sp.NullMoveDepthDiv
+		}
 		snap := r.nullMove()
-		scoreNM, nmNodes := r.negamax(depth-1-2, ply+1, -beta, -beta+1)
+		scoreNM, nmNodes := r.negamax(depth-1-reduction, ply+1, -beta, -beta+1)
 		nodes += nmNodes
 		scoreNM = -scoreNM
 		r.undoNullMove(snap)
+		if r.stopped {
+			return 0, nodes
+		}
 		if scoreNM >= beta {
 			return beta, nodes
 		}
 	}
 
-	// 5. Recursion with LMR
+	// Futility: at frontier nodes quiet moves cannot lift a score this far
+	// below alpha.
+	futile := prunable && depth <= sp.FutilityMaxDepth && staticEval+sp.FutilityMargin*depth <= alpha
+	lateMoves := sp.LMPBase + depth*depth
+
+	// 5. Recursion with late move pruning and reductions
 	moveIndex := 0
+	quiets := 0
+	var bestMove SimpleMove // kept in the TT for move ordering and the PV
 	for _, m := range moves {
+		quiet := !r.isCapture(m) && m.Promo == 0
 		r.MakeMove(m.From, m.To, m.Promo)
+		givesCheck := r.IsInCheck(r.Turn)
+
+		if quiet && !givesCheck && moveIndex > 0 {
+			if futile || (prunable && depth <= sp.LMPMaxDepth && quiets >= lateMoves) {
+				r.UndoMove()
+				continue
+			}
+		}
+
 		reduction := 0
-		if depth >= 3 && moveIndex >= 4 && !r.isCapture(m) && m.Promo == 0 {
-			reduction = 1
+		if depth >= sp.LMRMinDepth && moveIndex >= sp.LMRMinMove && quiet && !givesCheck && !inCheck {
+			reduction = lateMoveReduction(depth, moveIndex)
+			if pvNode {
+				reduction--
+			}
+			reduction = max(0, min(reduction, depth-2))
 		}
 		childDepth := depth - 1 - reduction
 		score, childNodes := r.negamax(childDepth, ply+1, -beta, -alpha)
 		// If reduced search raises alpha, re-search at full depth to confirm.
-		if reduction > 0 && score > alpha {
-			score, childNodes = r.negamax(depth-1, ply+1, -beta, -alpha)
+		if reduction > 0 && -score > alpha {
+			var more int
+			score, more = r.negamax(depth-1, ply+1, -beta, -alpha)
+			childNodes += more
 		}
 		nodes += childNodes
 		r.UndoMove()
 		moveIndex++
+		if quiet {
+			quiets++
+		}
+		if r.stopped {
+			return 0, nodes // unfinished: nothing may reach the TT
+		}
 
 		score = -score
 
@@ -162,20 +337,25 @@ func (r *RuleEngine) negamax(depth, ply, alpha, beta int) (int, int) {
 		}
 		if score > alpha {
 			alpha = score
+			bestMove = m
 			if !r.isCapture(m) {
 				r.bumpHistory(m)
 			}
 		}
 	}
-	r.storeTT(r.hash, depth, toTTScore(alpha, ply), flagFrom(alpha, beta, alphaOrig), SimpleMove{})
+	r.storeTT(r.hash, depth, toTTScore(alpha, ply), flagFrom(alpha, beta, alphaOrig), bestMove)
 	return alpha, nodes
 }
 
 // evaluateRelative adapts the static evaluation to the current turn.
+// A loaded network already scores from the side to move.
 // Evaluate() returns White - Black.
 // If it's Black's turn, we want Black - White (which is -(White - Black)).
 func (r *RuleEngine) evaluateRelative() int {
-	score := EvaluatePosition(r.Board, r.State)
+	if r.net != nil {
+		return r.nnEvaluate()
+	}
+	score := evaluateWith(r.Board, r.State, r.currentPSQT(), r.style)
 	if r.Turn == pieces.BLACK {
 		return -score
 	}
@@ -185,6 +365,8 @@ func (r *RuleEngine) evaluateRelative() int {
 // quiesce searches capture sequences to reduce horizon effects.
 func (r *RuleEngine) quiesce(ply, alpha, beta int) (int, int) {
 	nodes := 1
+	r.nodes++ // counts toward the node limit, checked in negamax
+	r.selDepth = max(r.selDepth, ply)
 	score := r.evaluateRelative()
 	inCheck := r.IsInCheck(r.Turn)
 
@@ -244,6 +426,36 @@ type SimpleMove struct {
 	Promo    rune
 }
 
+// String formats the move in coordinate notation, e.g. "e7e8q".
+func (m SimpleMove) String() string {
+	s := string([]rune{m.From.File.Char(), rune('1' + m.From.Rank), m.To.File.Char(), rune('1' + m.To.Rank)})
+	if m.Promo != 0 {
+		s += string(m.Promo)
+	}
+	return s
+}
+
+// MateIn reads score as a mate in moves, counted for the side to move
+// and negative when it is being mated. ok is false for any other score.
+func MateIn(score int) (moves int, ok bool) {
+	switch {
+	case score > EvalClamp:
+		return (MateScore - score + 1) / 2, true
+	case score < -EvalClamp:
+		return -(MateScore + score) / 2, true
+	}
+	return 0, false
+}
+
+// PVString formats a line as space-separated coordinate moves.
+func PVString(pv []SimpleMove) string {
+	moves := make([]string, len(pv))
+	for i, m := range pv {
+		moves[i] = m.String()
+	}
+	return strings.Join(moves, " ")
+}
+
 // GenerateLegalMoves aggregates all valid moves for the current turn.
 func (r *RuleEngine) GenerateLegalMoves() []SimpleMove {
 	moves := make([]SimpleMove, 0, 40)
@@ -317,6 +529,13 @@ func isPromo(p pieces.Piece, to address.Addr) bool {
 	return to.Rank == 0 || to.Rank == 7
 }
 
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
 func (r *RuleEngine) isCapture(m SimpleMove) bool {
 	if !r.Board.IsEmpty(m.To) {
 		return true
@@ -331,7 +550,7 @@ func (r *RuleEngine) isCapture(m SimpleMove) bool {
 }
 
 func (r *RuleEngine) storeTT(hash uint64, depth int, score int, flag int, move SimpleMove) {
-	idx := hash & TTMask
+	idx := r.ttIndex(hash)
 	old := r.tt[idx]
 	if old.hash == hash {
 		if old.gen == r.gen && old.depth > depth {
@@ -0,0 +1,75 @@
+package socrates
+
+import "testing"
+
+func TestAnalyzeWindowReportsBounds(t *testing.T) {
+	// Rxd5 wins the queen: far above any small window around zero.
+	fen := "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1"
+	exact := engineAt(t, fen).Analyze(3)
+
+	high := engineAt(t, fen).AnalyzeWindow(3, -50, 50)
+	if high.Bound != BoundLower || high.Score < 50 {
+		t.Fatalf("fail high: %+v", high)
+	}
+	low := engineAt(t, fen).AnalyzeWindow(3, exact.Score+100, exact.Score+200)
+	if low.Bound != BoundUpper || low.Score != exact.Score+100 {
+		t.Fatalf("fail low: %+v", low)
+	}
+	if exact.Bound != BoundExact || exact.SelDepth < 3 {
+		t.Fatalf("full window: %+v", exact)
+	}
+}
+
+func TestPVFollowsTheTable(t *testing.T) {
+	e := engineAt(t, "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	e.SetHashSize(1) // small enough for Hashfull's sample to see the search
+	res := e.Analyze(4)
+	pv := e.PV(SimpleMove{From: res.From, To: res.To, Promo: res.Promo}, 4)
+	if len(pv) == 0 || pv[0].From != res.From || pv[0].To != res.To {
+		t.Fatalf("PV %v does not start with the best move", pv)
+	}
+	if len(e.Log.Moves()) != 0 {
+		t.Fatal("PV left moves on the board")
+	}
+	if e.Hashfull() == 0 {
+		t.Fatal("a search left the table empty")
+	}
+}
+
+func TestStopCutsTheSearchShort(t *testing.T) {
+	e := engineAt(t, "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	searched := 0
+	e.OnRootMove(func(SimpleMove, int) {
+		if searched++; searched == 2 {
+			e.Stop()
+		}
+	})
+	e.Analyze(6)
+	if !e.Stopped() || searched != 2 {
+		t.Fatalf("stopped %v after %d root moves", e.Stopped(), searched)
+	}
+	e.ClearStop()
+	e.OnRootMove(nil)
+	if e.Analyze(2); e.Stopped() {
+		t.Fatal("ClearStop did not let the next search run")
+	}
+}
+
+func TestMateIn(t *testing.T) {
+	tests := []struct {
+		score, moves int
+		ok           bool
+	}{
+		{MateScore - 1, 1, true},
+		{MateScore - 5, 3, true},
+		{-MateScore + 2, -1, true},
+		{-MateScore + 6, -3, true},
+		{TBWinScore, 0, false},
+		{35, 0, false},
+	}
+	for _, tt := range tests {
+		if moves, ok := MateIn(tt.score); moves != tt.moves || ok != tt.ok {
+			t.Errorf("MateIn(%d) = %d, %v; want %d, %v", tt.score, moves, ok, tt.moves, tt.ok)
+		}
+	}
+}
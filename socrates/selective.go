@@ -0,0 +1,153 @@
+// --- socrates/selective.go ---
+
+package socrates
+
+import (
+	"math"
+	"strings"
+
+	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/pieces"
+)
+
+// SearchParams holds every margin and reduction of the selective search.
+// Margins are centipawns; a MaxDepth of 0 switches the technique off.
+type SearchParams struct {
+	CheckExtension int // plies added when the side to move is in check
+
+	NullMoveMinDepth  int
+	NullMoveReduction int
+	NullMoveDepthDiv  int // one more ply of reduction per this much depth
+
+	RFPMargin   int // reverse futility: per ply of depth
+	RFPMaxDepth int
+
+	RazorMargin   int // per ply of depth
+	RazorMaxDepth int
+
+	FutilityMargin   int // per ply of depth
+	FutilityMaxDepth int
+
+	LMPBase     int // quiet moves searched before pruning: base + depth²
+	LMPMaxDepth int
+
+	LMRMinDepth int
+	LMRMinMove  int // moves searched at full depth first
+	LMRBase     int // reduction = base/100 + ln(depth)·ln(move)·100/divisor
+	LMRDivisor  int
+}
+
+// DefaultSearchParams returns the compiled-in search parameters.
+func DefaultSearchParams() SearchParams {
+	return SearchParams{
+		CheckExtension:    1,
+		NullMoveMinDepth:  3,
+		NullMoveReduction: 2,
+		NullMoveDepthDiv:  6,
+		RFPMargin:         90,
+		RFPMaxDepth:       5,
+		RazorMargin:       300,
+		RazorMaxDepth:     2,
+		FutilityMargin:    120,
+		FutilityMaxDepth:  3,
+		LMPBase:           4,
+		LMPMaxDepth:       3,
+		LMRMinDepth:       3,
+		LMRMinMove:        4,
+		LMRBase:           75,
+		LMRDivisor:        225,
+	}
+}
+
+// SearchParam describes one tunable search parameter.
+type SearchParam struct {
+	Name     string
+	Value    *int
+	Min, Max int
+}
+
+// Tunables lists the parameters with their names and sensible ranges, in a
+// fixed order, for option menus and tuners.
+func (p *SearchParams) Tunables() []SearchParam {
+	return []SearchParam{
+		{"CheckExtension", &p.CheckExtension, 0, 1},
+		{"NullMoveMinDepth", &p.NullMoveMinDepth, 1, 10},
+		{"NullMoveReduction", &p.NullMoveReduction, 1, 5},
+		{"NullMoveDepthDiv", &p.NullMoveDepthDiv, 0, 20},
+		{"RFPMargin", &p.RFPMargin, 0, 400},
+		{"RFPMaxDepth", &p.RFPMaxDepth, 0, 12},
+		{"RazorMargin", &p.RazorMargin, 0, 1000},
+		{"RazorMaxDepth", &p.RazorMaxDepth, 0, 6},
+		{"FutilityMargin", &p.FutilityMargin, 0, 500},
+		{"FutilityMaxDepth", &p.FutilityMaxDepth, 0, 8},
+		{"LMPBase", &p.LMPBase, 1, 20},
+		{"LMPMaxDepth", &p.LMPMaxDepth, 0, 8},
+		{"LMRMinDepth", &p.LMRMinDepth, 1, 10},
+		{"LMRMinMove", &p.LMRMinMove, 1, 20},
+		{"LMRBase", &p.LMRBase, 0, 200},
+		{"LMRDivisor", &p.LMRDivisor, 100, 600},
+	}
+}
+
+// Tunable finds a parameter by name, ignoring case.
+func (p *SearchParams) Tunable(name string) (SearchParam, bool) {
+	for _, t := range p.Tunables() {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return SearchParam{}, false
+}
+
+// searchParams is the parameter set used by negamax, with its LMR table.
+var (
+	searchParams = DefaultSearchParams()
+	lmrTable     = buildLMRTable(searchParams)
+)
+
+// SetSearchParams replaces the active search parameters.
+// It must not be called while a search is running.
+func SetSearchParams(p SearchParams) {
+	searchParams = p
+	lmrTable = buildLMRTable(p)
+}
+
+// CurrentSearchParams returns a copy of the active search parameters.
+func CurrentSearchParams() SearchParams {
+	return searchParams
+}
+
+// buildLMRTable precomputes the late move reductions by depth and move number.
+func buildLMRTable(p SearchParams) [64][64]int {
+	var t [64][64]int
+	divisor := float64(max(p.LMRDivisor, 1))
+	for d := 1; d < 64; d++ {
+		for m := 1; m < 64; m++ {
+			r := float64(p.LMRBase)/100 + math.Log(float64(d))*math.Log(float64(m))*100/divisor
+			t[d][m] = int(r)
+		}
+	}
+	return t
+}
+
+// lateMoveReduction returns how many plies to cut from a late quiet move.
+func lateMoveReduction(depth, moveIndex int) int {
+	return lmrTable[min(depth, 63)][min(moveIndex, 63)]
+}
+
+// hasNonPawnMaterial reports whether color has a piece besides king and
+// pawns. Without one, zugzwang is common and null-move pruning unsound.
+func (r *RuleEngine) hasNonPawnMaterial(color int) bool {
+	found := false
+	r.Board.ForEachPiece(func(_ address.Addr, p pieces.Piece) {
+		if found || p.Color() != color {
+			return
+		}
+		switch p.(type) {
+		case *pieces.Pawn, *pieces.King:
+		default:
+			found = true
+		}
+	})
+	return found
+}
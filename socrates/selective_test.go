@@ -0,0 +1,70 @@
+package socrates
+
+import "testing"
+
+func TestLateMoveReductionsGrowWithDepthAndMoveNumber(t *testing.T) {
+	if got := lateMoveReduction(3, 4); got != 1 {
+		t.Fatalf("depth 3 move 4 reduction %d, want 1", got)
+	}
+	if lateMoveReduction(10, 30) <= lateMoveReduction(3, 4) {
+		t.Fatal("late moves at high depth should be reduced more")
+	}
+	if got := lateMoveReduction(1, 63); got != 0 {
+		t.Fatalf("depth 1 reduction %d, want 0", got)
+	}
+}
+
+func TestSearchParamTunables(t *testing.T) {
+	defer SetSearchParams(DefaultSearchParams())
+	p := DefaultSearchParams()
+	opt, ok := p.Tunable("lmrdivisor")
+	if !ok || *opt.Value != 225 {
+		t.Fatalf("LMRDivisor lookup: %+v %v", opt, ok)
+	}
+	*opt.Value = 100
+	SetSearchParams(p)
+	if CurrentSearchParams().LMRDivisor != 100 || lateMoveReduction(10, 30) <= 3 {
+		t.Fatal("LMR table not rebuilt from the new divisor")
+	}
+	for _, tp := range p.Tunables() {
+		if *tp.Value < tp.Min || *tp.Value > tp.Max {
+			t.Errorf("%s = %d outside [%d, %d]", tp.Name, *tp.Value, tp.Min, tp.Max)
+		}
+	}
+}
+
+func TestNullMoveZugzwangGuard(t *testing.T) {
+	e := engineAt(t, "8/8/4k3/4p3/4P3/4K3/8/8 w - - 0 1")
+	if e.hasNonPawnMaterial(e.Turn) {
+		t.Fatal("a pawn ending has no piece to pass with")
+	}
+	e = engineAt(t, "8/8/4k3/4p3/4P3/4K3/8/6N1 w - - 0 1")
+	if !e.hasNonPawnMaterial(e.Turn) || e.hasNonPawnMaterial(1-e.Turn) {
+		t.Fatal("only White has a knight")
+	}
+}
+
+func TestSelectivityPrunesNodes(t *testing.T) {
+	defer SetSearchParams(DefaultSearchParams())
+	const fen = "r1bq1rk1/pp2bppp/2n1pn2/3p4/3P4/2NBPN2/PP3PPP/R2QK2R w KQ - 0 1"
+	selective := engineAt(t, fen).Search(4)
+
+	p := DefaultSearchParams()
+	p.RFPMaxDepth, p.RazorMaxDepth, p.FutilityMaxDepth, p.LMPMaxDepth = 0, 0, 0, 0
+	p.LMRMinDepth = 64
+	SetSearchParams(p)
+	plain := engineAt(t, fen).Search(4)
+	if selective.Nodes >= plain.Nodes {
+		t.Fatalf("selective search visited %d nodes, plain %d", selective.Nodes, plain.Nodes)
+	}
+}
+
+func TestCheckExtensionFindsMateBehindTheHorizon(t *testing.T) {
+	// Rb7+ K-any Ra8# ends in a quiet move at depth 2, which only the
+	// extended reply to the check leaves room for.
+	e := engineAt(t, "8/7k/R7/1R6/8/8/8/K7 w - - 0 1")
+	res := e.Search(2)
+	if res.Score < MateScore-10 {
+		t.Fatalf("no mate found, score %d", res.Score)
+	}
+}
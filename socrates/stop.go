@@ -0,0 +1,39 @@
+// --- socrates/stop.go ---
+
+package socrates
+
+// Stop asks a running search to return as soon as possible. It is the only
+// method that may be called while another goroutine searches; the search
+// keeps stopping until ClearStop.
+func (r *RuleEngine) Stop() {
+	r.halt.Store(true)
+}
+
+// ClearStop lets the next search run.
+func (r *RuleEngine) ClearStop() {
+	r.halt.Store(false)
+}
+
+// SetNodeLimit stops the searches that follow once they have visited n
+// nodes between them, counting from this call; 0 removes the limit.
+func (r *RuleEngine) SetNodeLimit(n int) {
+	r.nodes, r.nodeLimit = 0, n
+}
+
+// Stopped reports whether the last Analyze was cut short by Stop or the
+// node limit, leaving its result incomplete.
+func (r *RuleEngine) Stopped() bool {
+	return r.stopped
+}
+
+// OnRootMove registers f to be called, on the searching goroutine, as each
+// root move is searched; number counts from 1. Nil removes the hook.
+func (r *RuleEngine) OnRootMove(f func(m SimpleMove, number int)) {
+	r.onRoot = f
+}
+
+func (r *RuleEngine) rootMove(m SimpleMove, number int) {
+	if r.onRoot != nil {
+		r.onRoot(m, number)
+	}
+}
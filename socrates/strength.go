@@ -0,0 +1,132 @@
+// --- socrates/strength.go ---
+
+package socrates
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	// MaxSkillLevel is full strength; lower levels play weaker.
+	MaxSkillLevel = 20
+	// MinElo and MaxElo bound the UCI_Elo range mapped onto skill levels.
+	MinElo = 800
+	MaxElo = 2400
+	// skillMultiPV is how many of the best root moves a weakened engine
+	// considers playing.
+	skillMultiPV = 4
+)
+
+// skill weakens play for training games. The zero value is full strength.
+type skill struct {
+	weakness int // MaxSkillLevel - level
+	rng      *rand.Rand
+}
+
+// EloToSkillLevel maps a UCI_Elo rating onto the nearest skill level.
+func EloToSkillLevel(elo int) int {
+	elo = max(MinElo, min(MaxElo, elo))
+	return ((elo-MinElo)*MaxSkillLevel + (MaxElo-MinElo)/2) / (MaxElo - MinElo)
+}
+
+// SetSkillLevel sets the playing strength from 0 (weakest) to MaxSkillLevel.
+func (r *RuleEngine) SetSkillLevel(level int) {
+	level = max(0, min(MaxSkillLevel, level))
+	r.skill.weakness = MaxSkillLevel - level
+	if r.skill.rng == nil {
+		r.skill.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+}
+
+// SkillLevel returns the playing strength set by SetSkillLevel.
+func (r *RuleEngine) SkillLevel() int {
+	return MaxSkillLevel - r.skill.weakness
+}
+
+// SeedSkill makes the weakened move choice repeatable.
+func (r *RuleEngine) SeedSkill(seed int64) {
+	r.skill.rng = rand.New(rand.NewSource(seed))
+}
+
+// maxDepth caps the search depth: 1 ply at level 0, 7 at level 19.
+func (s *skill) maxDepth() int {
+	return 1 + (MaxSkillLevel-s.weakness)/3
+}
+
+// maxNodes caps the work per move, doubling every two levels.
+func (s *skill) maxNodes() int {
+	return 200 << ((MaxSkillLevel - s.weakness) / 2)
+}
+
+// margin is how far below the best score a chosen move may fall.
+func (s *skill) margin() int {
+	return 12 * s.weakness
+}
+
+type rootScore struct {
+	move  SimpleMove
+	score int
+}
+
+// pick chooses among the best candidates by adding random noise of at most
+// margin to each score, so no move worse than best-margin can be played.
+func (s *skill) pick(scored []rootScore) rootScore {
+	best := scored[0]
+	choice, top := best, -MaxScore
+	for i, c := range scored {
+		if i == skillMultiPV || c.score < best.score-s.margin() {
+			break
+		}
+		if noisy := c.score + s.rng.Intn(s.margin()+1); noisy > top {
+			choice, top = c, noisy
+		}
+	}
+	return choice
+}
+
+// searchWeakened scores every root move with a full window, deepening one
+// ply at a time within the skill's depth and node limits, then picks from
+// the candidates of the last completed iteration.
+func (r *RuleEngine) searchWeakened(depth int, moves []SimpleMove) SearchResult {
+	depth = max(1, min(depth, r.skill.maxDepth()))
+	// The skill caps each search on top of any limit set by SetNodeLimit.
+	limit := r.nodeLimit
+	if capped := r.nodes + r.skill.maxNodes(); limit == 0 || capped < limit {
+		r.nodeLimit = capped
+	}
+	r.stopped = false
+	defer func() { r.nodeLimit, r.stopped = limit, false }()
+
+	var scored []rootScore
+	totalNodes := 0
+	for d := 1; d <= depth && !r.stopped; d++ {
+		iter := make([]rootScore, 0, len(moves))
+		for _, m := range moves {
+			r.MakeMove(m.From, m.To, m.Promo)
+			score, visited := r.negamax(d-1, 1, MinScore, MaxScore)
+			r.UndoMove()
+			totalNodes += visited + 1
+			if r.stopped {
+				break
+			}
+			iter = append(iter, rootScore{move: m, score: -score})
+		}
+		sort.SliceStable(iter, func(i, j int) bool { return iter[i].score > iter[j].score })
+		if len(iter) == len(moves) {
+			scored = iter
+			for i, s := range scored {
+				moves[i] = s.move // best first for the next iteration
+			}
+		} else if len(scored) == 0 {
+			scored = iter // a partial first iteration beats nothing
+		}
+	}
+	if len(scored) == 0 {
+		scored = []rootScore{{move: moves[0]}}
+	}
+
+	c := r.skill.pick(scored)
+	return SearchResult{From: c.move.From, To: c.move.To, Promo: c.move.Promo, Score: c.score, Nodes: totalNodes}
+}
@@ -0,0 +1,84 @@
+package socrates
+
+import "testing"
+
+func TestEloToSkillLevel(t *testing.T) {
+	cases := map[int]int{0: 0, MinElo: 0, 1600: 10, MaxElo: MaxSkillLevel, 3000: MaxSkillLevel}
+	for elo, want := range cases {
+		if got := EloToSkillLevel(elo); got != want {
+			t.Errorf("EloToSkillLevel(%d) = %d, want %d", elo, got, want)
+		}
+	}
+}
+
+func TestWeakenedSearchStaysWithinMargin(t *testing.T) {
+	// Rxd5 wins a queen; even the weakest level may not give that up.
+	from, to, _, _ := ParseMove("d1d5")
+	for seed := int64(0); seed < 20; seed++ {
+		e := engineAt(t, "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1")
+		e.SetSkillLevel(0)
+		e.SeedSkill(seed)
+		res := e.Search(5)
+		if res.From != *from || res.To != *to {
+			t.Fatalf("seed %d: played %v%v instead of winning the queen", seed, res.From, res.To)
+		}
+	}
+}
+
+func TestWeakenedSearchVariesItsMoves(t *testing.T) {
+	seen := map[SimpleMove]bool{}
+	for seed := int64(0); seed < 20; seed++ {
+		e := engineAt(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		e.SetSkillLevel(2)
+		e.SeedSkill(seed)
+		res := e.Analyze(5) // the start position is in the book
+		if res.Nodes > 2*e.skill.maxNodes() {
+			t.Fatalf("searched %d nodes, limit %d", res.Nodes, e.skill.maxNodes())
+		}
+		seen[SimpleMove{From: res.From, To: res.To}] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("a weakened engine always played the same opening move")
+	}
+}
+
+func TestFullStrengthIgnoresSkill(t *testing.T) {
+	e := engineAt(t, "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1")
+	e.SetSkillLevel(MaxSkillLevel)
+	if e.skill.weakness != 0 {
+		t.Fatal("level 20 should play at full strength")
+	}
+	e.SetSkillLevel(-5)
+	if e.SkillLevel() != 0 {
+		t.Fatalf("level should clamp to 0, got %d", e.SkillLevel())
+	}
+}
+
+func TestPersonalityReweightsEvaluation(t *testing.T) {
+	// White is a pawn up with the pieces on their home squares.
+	e := engineAt(t, "rnbqkbnr/ppppppp1/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	balanced := e.evaluateRelative()
+	if want := EvaluatePosition(e.Board, e.State); balanced != want {
+		t.Fatalf("no personality should match the plain evaluation: %d vs %d", balanced, want)
+	}
+
+	p, ok := PersonalityByName("Materialistic")
+	if !ok {
+		t.Fatal("materialistic preset missing")
+	}
+	e.SetPersonality(p)
+	if got := e.evaluateRelative(); got <= balanced {
+		t.Fatalf("materialistic should value the extra pawn more: %d vs %d", got, balanced)
+	}
+
+	e.SetPersonality(Balanced)
+	if e.style != nil || e.evaluateRelative() != balanced {
+		t.Fatal("returning to balanced should restore the default weights")
+	}
+	if _, ok := PersonalityByName("reckless"); ok {
+		t.Fatal("unknown personality accepted")
+	}
+	if names := PersonalityNames(); names[0] != "balanced" || len(names) != 4 {
+		t.Fatalf("unexpected presets %v", names)
+	}
+}
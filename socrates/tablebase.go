@@ -0,0 +1,287 @@
+// --- socrates/tablebase.go ---
+
+package socrates
+
+import (
+	"github.com/mesb/mchess/address"
+	"github.com/mesb/mchess/pieces"
+	"github.com/mesb/mchess/syzygy"
+)
+
+// UseTablebase installs Syzygy tables for the search; nil disables probing.
+func (r *RuleEngine) UseTablebase(tb *syzygy.Tablebase) {
+	r.tb = tb
+}
+
+// LoadTablebases opens the Syzygy tables found on path (directories joined
+// by the OS list separator). An empty path disables probing.
+func (r *RuleEngine) LoadTablebases(path string) error {
+	if path == "" {
+		r.UseTablebase(nil)
+		return nil
+	}
+	tb, err := syzygy.Open(path)
+	if err != nil {
+		return err
+	}
+	r.UseTablebase(tb)
+	return nil
+}
+
+// Tablebase returns the active tablebases, or nil.
+func (r *RuleEngine) Tablebase() *syzygy.Tablebase {
+	return r.tb
+}
+
+// tbScore converts a WDL result found ply plies from the root into a search
+// score. Wins rank below mates; draws score draw, with fifty-move draws
+// leaning slightly to their side.
+func tbScore(wdl syzygy.WDL, ply, draw int) int {
+	switch {
+	case wdl == syzygy.Win:
+		return TBWinScore - ply
+	case wdl == syzygy.Loss:
+		return -TBWinScore + ply
+	}
+	return draw + 2*int(wdl)
+}
+
+// tbPosition converts the board for a table lookup.
+func (r *RuleEngine) tbPosition() *syzygy.Position {
+	pos := &syzygy.Position{Turn: r.Turn}
+	r.Board.ForEachPiece(func(sq address.Addr, p pieces.Piece) {
+		pos.Board[sq.Index()] = syzygy.Piece(pieceIndex(p)+1) + syzygy.Piece(8*p.Color())
+	})
+	return pos
+}
+
+// tbCovered reports whether the position can be looked up at all: tables
+// hold no castling rights and only positions up to their piece count.
+func (r *RuleEngine) tbCovered() bool {
+	if r.tb == nil || (r.State.CastlingRights != "-" && r.State.CastlingRights != "") {
+		return false
+	}
+	count := 0
+	r.Board.ForEachPiece(func(address.Addr, pieces.Piece) { count++ })
+	return count <= r.tb.MaxPieces()
+}
+
+func (r *RuleEngine) isPawnMove(m SimpleMove) bool {
+	_, ok := r.Board.PieceAt(m.From).(*pieces.Pawn)
+	return ok
+}
+
+// tbSearch resolves captures (and with zeroing, pawn moves) before trusting
+// the WDL table, which knows nothing of en passant and may store "don't
+// care" values where a capture wins. zeroingBest reports that the best
+// result is reached by a zeroing move, so DTZ must not be probed.
+func (r *RuleEngine) tbSearch(zeroing bool) (wdl syzygy.WDL, zeroingBest bool, err error) {
+	moves := r.GenerateLegalMoves()
+	best := syzygy.Loss
+	searched := 0
+	for _, m := range moves {
+		if !r.isCapture(m) && (!zeroing || !r.isPawnMove(m)) {
+			continue
+		}
+		searched++
+		r.MakeMove(m.From, m.To, m.Promo)
+		v, _, err := r.tbSearch(false)
+		r.UndoMove()
+		if err != nil {
+			return syzygy.Draw, false, err
+		}
+		if v = -v; v > best {
+			best = v
+			if v >= syzygy.Win {
+				return v, true, nil
+			}
+		}
+	}
+
+	// With every legal move searched the table is not needed (and would be
+	// wrong if the only moves were en passant captures).
+	allSearched := searched > 0 && searched == len(moves)
+	v := best
+	if !allSearched {
+		if v, err = r.tb.ProbeWDLTable(r.tbPosition()); err != nil {
+			return syzygy.Draw, false, err
+		}
+	}
+	if best >= v {
+		return best, best > syzygy.Draw || allSearched, nil
+	}
+	return v, false, nil
+}
+
+// dtzBeforeZeroing is the DTZ of a position whose best move zeroes the
+// fifty-move counter.
+func dtzBeforeZeroing(wdl syzygy.WDL) int {
+	switch wdl {
+	case syzygy.Win:
+		return 1
+	case syzygy.CursedWin:
+		return 101
+	case syzygy.BlessedLoss:
+		return -101
+	case syzygy.Loss:
+		return -1
+	}
+	return 0
+}
+
+func signOf(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+// probeDTZ returns the distance to zeroing in plies, positive when the side
+// to move wins. Tables hold one side to move only; for the other side the
+// answer comes from a one-ply search.
+func (r *RuleEngine) probeDTZ() (int, error) {
+	wdl, zeroingBest, err := r.tbSearch(true)
+	if err != nil || wdl == syzygy.Draw {
+		return 0, err
+	}
+	if zeroingBest {
+		return dtzBeforeZeroing(wdl), nil
+	}
+	dtz, stored, err := r.tb.ProbeDTZTable(r.tbPosition(), wdl)
+	if err != nil || stored {
+		return dtz, err
+	}
+
+	minDTZ := 0xFFFF
+	for _, m := range r.GenerateLegalMoves() {
+		zeroing := r.isCapture(m) || r.isPawnMove(m)
+		r.MakeMove(m.From, m.To, m.Promo)
+		var d int
+		if zeroing {
+			var v syzygy.WDL
+			v, _, err = r.tbSearch(false)
+			d = -dtzBeforeZeroing(v)
+		} else {
+			d, err = r.probeDTZ()
+			d = -d
+		}
+		if d == 1 && r.IsCheckmate() {
+			minDTZ = 1
+		}
+		r.UndoMove()
+		if err != nil {
+			return 0, err
+		}
+		if !zeroing {
+			d += signOf(d)
+		}
+		if d < minDTZ && signOf(d) == signOf(int(wdl)) {
+			minDTZ = d
+		}
+	}
+	if minDTZ == 0xFFFF {
+		return -1, nil // mated
+	}
+	return minDTZ, nil
+}
+
+// probeRoot ranks the root moves with the tablebases. In a won or lost
+// position the DTZ-optimal move is returned directly, taking the fifty-move
+// counter into account. Otherwise, or without DTZ tables, the moves are
+// narrowed to those keeping the best WDL result and left to the search.
+func (r *RuleEngine) probeRoot(moves []SimpleMove) ([]SimpleMove, *SearchResult) {
+	if !r.tbCovered() {
+		return moves, nil
+	}
+	const maxDTZ = 1000
+	cnt50 := r.State.HalfmoveClock
+	ranks := make([]int, len(moves))
+	dtzs := make([]int, len(moves))
+	useDTZ := true
+	for i, m := range moves {
+		r.MakeMove(m.From, m.To, m.Promo)
+		var dtz int
+		var err error
+		switch {
+		case r.State.HalfmoveClock == 0:
+			var v syzygy.WDL
+			v, _, err = r.tbSearch(false)
+			dtz = dtzBeforeZeroing(-v)
+		case r.IsDraw():
+			dtz = 0
+		default:
+			dtz, err = r.probeDTZ()
+			dtz = -dtz
+			dtz += signOf(dtz)
+		}
+		if dtz == 2 && r.IsCheckmate() {
+			dtz = 1
+		}
+		r.UndoMove()
+		if err != nil {
+			useDTZ = false
+			break
+		}
+		dtzs[i] = dtz
+		switch {
+		case dtz > 0 && dtz+cnt50 <= 99:
+			ranks[i] = maxDTZ
+		case dtz > 0:
+			ranks[i] = maxDTZ - (dtz + cnt50)
+		case dtz < 0 && -dtz*2+cnt50 < 100:
+			ranks[i] = -maxDTZ
+		case dtz < 0:
+			ranks[i] = -maxDTZ + (-dtz + cnt50)
+		}
+	}
+
+	if !useDTZ {
+		for i, m := range moves {
+			r.MakeMove(m.From, m.To, m.Promo)
+			v, _, err := r.tbSearch(false)
+			r.UndoMove()
+			if err != nil {
+				return moves, nil
+			}
+			ranks[i] = -int(v)
+		}
+	}
+	r.tbHits += len(moves)
+
+	best := ranks[0]
+	for _, rank := range ranks {
+		best = max(best, rank)
+	}
+	if useDTZ && best != 0 {
+		pick := -1
+		for i, rank := range ranks {
+			if rank != best {
+				continue
+			}
+			// The lowest dtz is the fastest win, or the slowest loss.
+			if pick < 0 || dtzs[i] < dtzs[pick] {
+				pick = i
+			}
+		}
+		m, dtz := moves[pick], dtzs[pick]
+		score := 0
+		switch {
+		case best >= maxDTZ:
+			score = TBWinScore - dtz
+		case best <= -maxDTZ:
+			score = -TBWinScore - dtz
+		}
+		return nil, &SearchResult{From: m.From, To: m.To, Promo: m.Promo, Score: score, Nodes: 1, TBHits: r.tbHits}
+	}
+
+	kept := moves[:0:0]
+	for i, m := range moves {
+		if ranks[i] == best {
+			kept = append(kept, m)
+		}
+	}
+	return kept, nil
+}
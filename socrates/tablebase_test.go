@@ -0,0 +1,72 @@
+package socrates
+
+import (
+	"testing"
+
+	"github.com/mesb/mchess/syzygy"
+)
+
+func TestTablebaseRootMatesWithinDTZ(t *testing.T) {
+	e := engineAt(t, "8/8/8/4k3/8/8/8/KR6 w - - 0 1")
+	if err := e.LoadTablebases("../syzygy/testdata"); err != nil {
+		t.Fatalf("load tablebases: %v", err)
+	}
+	dtz, err := e.probeDTZ()
+	if err != nil || dtz <= 0 {
+		t.Fatalf("start dtz %d, err %v", dtz, err)
+	}
+	for ply := 0; ; ply++ {
+		if e.IsCheckmate() {
+			if e.Turn != 1 {
+				t.Fatal("White was mated")
+			}
+			if ply > dtz {
+				t.Fatalf("mate took %d plies, tablebase promised %d", ply, dtz)
+			}
+			return
+		}
+		if ply > dtz {
+			t.Fatalf("no mate after %d plies (dtz %d)", ply, dtz)
+		}
+		res := e.Search(1)
+		if res.TBHits == 0 {
+			t.Fatalf("ply %d: root move not taken from the tablebase", ply)
+		}
+		if !e.MakeMove(res.From, res.To, res.Promo) {
+			t.Fatalf("ply %d: illegal move %v%v", ply, res.From, res.To)
+		}
+	}
+}
+
+func TestTablebaseRootKeepsWinWithWDLOnly(t *testing.T) {
+	// Only Kc6, Kd6 and Ke6 keep the win; every other king move draws.
+	// There is no KPvK DTZ file, so the root falls back to WDL and lets
+	// the search choose among the winning moves.
+	e := engineAt(t, "3k4/8/8/3K4/3P4/8/8/8 w - - 0 1")
+	if err := e.LoadTablebases("../syzygy/testdata"); err != nil {
+		t.Fatalf("load tablebases: %v", err)
+	}
+	res := e.Search(2)
+	if !e.MakeMove(res.From, res.To, res.Promo) {
+		t.Fatalf("illegal move %v%v", res.From, res.To)
+	}
+	wdl, _, err := e.tbSearch(false)
+	if err != nil || wdl != syzygy.Loss {
+		t.Fatalf("after %v%v Black has %d (err %v), want a loss", res.From, res.To, wdl, err)
+	}
+}
+
+func TestTablebaseProbedInsideSearch(t *testing.T) {
+	// Rxb2 or Kxb2 reaches a won KRvK; the search sees it through the tables.
+	e := engineAt(t, "8/8/8/4k3/8/8/1n6/KR6 w - - 0 1")
+	if err := e.LoadTablebases("../syzygy/testdata"); err != nil {
+		t.Fatalf("load tablebases: %v", err)
+	}
+	res := e.Search(2)
+	if res.TBHits == 0 {
+		t.Fatal("expected tablebase hits")
+	}
+	if res.Score < TBWinScore-10 {
+		t.Fatalf("score %d, want a tablebase win", res.Score)
+	}
+}
@@ -1,5 +1,7 @@
 package socrates
 
+import "unsafe"
+
 // Transposition table entry.
 type ttEntry struct {
 	hash  uint64
@@ -17,25 +19,72 @@ const (
 )
 
 const (
-	TTSize = 1 << 20 // 1M entries; ~32-40MB
-	TTMask = TTSize - 1
+	TTSize = 1 << 20 // default entries
+
+	ttEntrySize = int(unsafe.Sizeof(ttEntry{}))
+
+	// DefaultHashMB is the size of the default table; MaxHashMB caps
+	// SetHashSize.
+	DefaultHashMB = TTSize * ttEntrySize >> 20
+	MaxHashMB     = 1 << 14
 )
 
+// SetHashSize resizes the transposition table to the largest power-of-two
+// number of entries that fits in mb megabytes. Resizing clears the table.
+func (r *RuleEngine) SetHashSize(mb int) {
+	mb = min(max(mb, 1), MaxHashMB)
+	n := 1
+	for 2*n*ttEntrySize <= mb<<20 {
+		n *= 2
+	}
+	if n != len(r.tt) {
+		r.tt = make([]ttEntry, n)
+	}
+}
+
+// HashSize returns the size of the transposition table in megabytes.
+func (r *RuleEngine) HashSize() int {
+	return len(r.tt) * ttEntrySize >> 20
+}
+
+// ClearHash empties the transposition table.
+func (r *RuleEngine) ClearHash() {
+	clear(r.tt)
+	r.gen = 0
+}
+
+// Hashfull estimates, in permille, how much of the transposition table is
+// in use, from a sample of its first entries.
+func (r *RuleEngine) Hashfull() int {
+	n := min(len(r.tt), 1000)
+	used := 0
+	for _, e := range r.tt[:n] {
+		if e.hash != 0 {
+			used++
+		}
+	}
+	return used * 1000 / n
+}
+
+func (r *RuleEngine) ttIndex(hash uint64) uint64 {
+	return hash & uint64(len(r.tt)-1)
+}
+
 func toTTScore(score, ply int) int {
-	if score > MateScore-1000 {
+	if score > TBWinScore-1000 {
 		return score + ply
 	}
-	if score < -MateScore+1000 {
+	if score < -TBWinScore+1000 {
 		return score - ply
 	}
 	return score
 }
 
 func fromTTScore(score, ply int) int {
-	if score > MateScore-1000 {
+	if score > TBWinScore-1000 {
 		return score - ply
 	}
-	if score < -MateScore+1000 {
+	if score < -TBWinScore+1000 {
 		return score + ply
 	}
 	return score
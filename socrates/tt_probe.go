@@ -1,7 +1,7 @@
 package socrates
 
 func (r *RuleEngine) ttProbe(hash uint64) (ttEntry, bool) {
-	entry := r.tt[hash&TTMask]
+	entry := r.tt[r.ttIndex(hash)]
 	if entry.hash == hash {
 		return entry, true
 	}
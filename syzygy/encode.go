@@ -0,0 +1,343 @@
+// --- syzygy/encode.go ---
+
+package syzygy
+
+import "sort"
+
+// Lookup tables for mapping a position to its table index. They mirror the
+// ones built by the reference prober, so indices agree with the generator.
+var (
+	mapB1H1H7     [64]int       // squares below the a1-h8 diagonal -> 0..27
+	mapA1D1D4     [64]int       // a1-d1-d4 triangle -> 0..9, diagonal last
+	mapKK         [10][64]int   // legal king pairs with the first in the triangle -> 0..461
+	mapPawns      [64]int       // a2-h7 -> 0..47, leading pawn has the highest value
+	binomial      [7][64]uint64 // binomial[k][n] = n choose k
+	leadPawnIdx   [6][64]uint64 // index of leading pawn groups by lead square
+	leadPawnsSize [6][4]uint64  // number of leading pawn groups by file
+)
+
+func rankOf(sq int) int      { return sq >> 3 }
+func fileOf(sq int) int      { return sq & 7 }
+func offA1H8(sq int) int     { return rankOf(sq) - fileOf(sq) }
+func flipFile(sq int) int    { return sq ^ 7 }
+func flipRank(sq int) int    { return sq ^ 56 }
+func flipDiag(sq int) int    { return ((sq >> 3) | (sq << 3)) & 63 }
+func edgeDistance(f int) int { return min(f, 7-f) }
+
+func kingsTouch(a, b int) bool {
+	return max(abs(rankOf(a)-rankOf(b)), abs(fileOf(a)-fileOf(b))) <= 1
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func init() {
+	code := 0
+	for sq := 0; sq < 64; sq++ {
+		if offA1H8(sq) < 0 {
+			mapB1H1H7[sq] = code
+			code++
+		}
+	}
+
+	var diagonal []int
+	code = 0
+	for sq := 0; sq <= 27; sq++ { // a1..d4
+		switch {
+		case offA1H8(sq) < 0 && fileOf(sq) <= 3:
+			mapA1D1D4[sq] = code
+			code++
+		case offA1H8(sq) == 0 && fileOf(sq) <= 3:
+			diagonal = append(diagonal, sq)
+		}
+	}
+	for _, sq := range diagonal {
+		mapA1D1D4[sq] = code
+		code++
+	}
+
+	// Both kings on the diagonal are encoded last.
+	type pair struct{ idx, sq int }
+	var bothOnDiagonal []pair
+	code = 0
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 <= 27; s1++ {
+			if mapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) { // b1 maps to 0
+				continue
+			}
+			for s2 := 0; s2 < 64; s2++ {
+				switch {
+				case kingsTouch(s1, s2):
+				case offA1H8(s1) == 0 && offA1H8(s2) > 0:
+				case offA1H8(s1) == 0 && offA1H8(s2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, pair{idx, s2})
+				default:
+					mapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, p := range bothOnDiagonal {
+		mapKK[p.idx][p.sq] = code
+		code++
+	}
+
+	binomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < len(binomial) && k <= n; k++ {
+			if k > 0 {
+				binomial[k][n] += binomial[k-1][n-1]
+			}
+			if k < n {
+				binomial[k][n] += binomial[k][n-1]
+			}
+		}
+	}
+
+	available := 47
+	for lead := 1; lead <= 5; lead++ {
+		for f := 0; f < 4; f++ {
+			var idx uint64
+			for r := 1; r <= 6; r++ {
+				sq := r*8 + f
+				if lead == 1 {
+					mapPawns[sq] = available
+					available--
+					mapPawns[flipFile(sq)] = available
+					available--
+				}
+				leadPawnIdx[lead][sq] = idx
+				idx += binomial[lead-1][mapPawns[sq]]
+			}
+			leadPawnsSize[lead][f] = idx
+		}
+	}
+}
+
+// setGroups splits the table's piece sequence into groups of pieces encoded
+// together and computes the multiplier of each group in the final index.
+func setGroups(e *entry, d *pairsData, order [2]int, f int) {
+	n := 0
+	firstLen := 2
+	if e.hasPawns {
+		firstLen = 0
+	} else if e.hasUniquePieces {
+		firstLen = 3
+	}
+	d.groupLen[0] = 1
+	for i := 1; i < e.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	pp := e.hasPawns && e.pawnCount[1] > 0
+	next := 1
+	free := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		free -= d.groupLen[1]
+	}
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch {
+		case k == order[0]: // leading pawns or pieces
+			d.groupIdx[0] = idx
+			switch {
+			case e.hasPawns:
+				idx *= leadPawnsSize[d.groupLen[0]][f]
+			case e.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case k == order[1]: // remaining pawns
+			d.groupIdx[1] = idx
+			idx *= binomial[d.groupLen[1]][48-d.groupLen[0]]
+		default: // remaining pieces
+			d.groupIdx[next] = idx
+			idx *= binomial[d.groupLen[next]][free]
+			free -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// size is the number of indices in the table, i.e. the last group multiplier.
+func (d *pairsData) size() uint64 {
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	return d.groupIdx[n]
+}
+
+// encode maps a position to its table index. It returns the pairs data to
+// decode from and the pawn file, or ok = false when a one-sided DTZ table
+// does not store the position's side to move.
+func (t *table) encode(e *entry, pos *Position) (d *pairsData, idx uint64, file int, ok bool) {
+	var squares [tbPieces]int
+	var pcs [tbPieces]Piece
+	var used [64]bool
+
+	// Tables are stored with the stronger side as White; symmetric material
+	// stores White to move only. Otherwise swap colours and mirror ranks.
+	symmetricBlackToMove := e.key == e.key2 && pos.Turn == 1
+	flip := symmetricBlackToMove || pos.MaterialKey() != e.key
+	var flipColor Piece
+	flipSquares, stm := 0, pos.Turn
+	if flip {
+		flipColor, flipSquares, stm = 8, 56, 1-pos.Turn
+	}
+
+	size, leadCount := 0, 0
+	if e.hasPawns {
+		// The leading pawns come first, in the colour stored in the table.
+		pc := t.get(0, 0).pieces[0] ^ flipColor
+		for sq, p := range pos.Board {
+			if p == pc {
+				squares[size] = sq ^ flipSquares
+				used[sq] = true
+				size++
+			}
+		}
+		leadCount = size
+		best := 0
+		for i := 1; i < leadCount; i++ {
+			if mapPawns[squares[i]] > mapPawns[squares[best]] {
+				best = i
+			}
+		}
+		squares[0], squares[best] = squares[best], squares[0]
+		file = edgeDistance(fileOf(squares[0]))
+	}
+
+	if t.dtz && !t.storesSide(e, stm, file) {
+		return nil, 0, file, false
+	}
+
+	for sq, p := range pos.Board {
+		if p != NoPiece && !used[sq] {
+			squares[size] = sq ^ flipSquares
+			pcs[size] = p ^ flipColor
+			size++
+		}
+	}
+
+	d = t.get(stm, file)
+
+	// Reorder the pieces into the sequence the table was compressed with.
+	for i := leadCount; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pcs[j] {
+				pcs[i], pcs[j] = pcs[j], pcs[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// Bring the leading piece into the a1-d1-d4 triangle.
+	if fileOf(squares[0]) > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] = flipFile(squares[i])
+		}
+	}
+
+	if e.hasPawns {
+		idx = leadPawnIdx[leadCount][squares[0]]
+		lead := squares[1:leadCount]
+		sort.SliceStable(lead, func(a, b int) bool { return mapPawns[lead[a]] < mapPawns[lead[b]] })
+		for i := 1; i < leadCount; i++ {
+			idx += binomial[i][mapPawns[squares[i]]]
+		}
+	} else {
+		if rankOf(squares[0]) > 3 {
+			for i := 0; i < size; i++ {
+				squares[i] = flipRank(squares[i])
+			}
+		}
+		// The first leading piece off the a1-h8 diagonal goes below it.
+		for i := 0; i < d.groupLen[0]; i++ {
+			off := offA1H8(squares[i])
+			if off == 0 {
+				continue
+			}
+			if off > 0 {
+				for j := i; j < size; j++ {
+					squares[j] = flipDiag(squares[j])
+				}
+			}
+			break
+		}
+		idx = leadingPieces(e, squares[:size])
+	}
+
+	idx *= d.groupIdx[0]
+	group := d.groupLen[0]
+	remainingPawns := e.hasPawns && e.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		sq := squares[group : group+d.groupLen[next]]
+		sort.Ints(sq)
+		var n uint64
+		for i, s := range sq {
+			adjust := 0
+			for _, prev := range squares[:group] {
+				if s > prev {
+					adjust++
+				}
+			}
+			if remainingPawns {
+				adjust += 8
+			}
+			n += binomial[i+1][s-adjust]
+		}
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		group += d.groupLen[next]
+	}
+	return d, idx, file, true
+}
+
+// leadingPieces encodes the leading group of a pawnless table: three unique
+// pieces when available, otherwise just the two kings.
+func leadingPieces(e *entry, sq []int) uint64 {
+	if !e.hasUniquePieces {
+		return uint64(mapKK[mapA1D1D4[sq[0]]][sq[1]])
+	}
+	adjust1 := b2i(sq[1] > sq[0])
+	adjust2 := b2i(sq[2] > sq[0]) + b2i(sq[2] > sq[1])
+	var idx int
+	switch {
+	case offA1H8(sq[0]) != 0:
+		idx = (mapA1D1D4[sq[0]]*63+(sq[1]-adjust1))*62 + sq[2] - adjust2
+	case offA1H8(sq[1]) != 0:
+		idx = (6*63+rankOf(sq[0])*28+mapB1H1H7[sq[1]])*62 + sq[2] - adjust2
+	case offA1H8(sq[2]) != 0:
+		idx = 6*63*62 + 4*28*62 + rankOf(sq[0])*7*28 +
+			(rankOf(sq[1])-adjust1)*28 + mapB1H1H7[sq[2]]
+	default:
+		idx = 6*63*62 + 4*28*62 + 4*7*28 + rankOf(sq[0])*7*6 +
+			(rankOf(sq[1])-adjust1)*6 + rankOf(sq[2]) - adjust2
+	}
+	return uint64(idx)
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,259 @@
+// --- syzygy/syzygy.go ---
+
+// Package syzygy probes Syzygy endgame tablebases (.rtbw WDL and .rtbz DTZ
+// files) in pure Go.
+//
+// The package answers questions about a static position: the WDL value
+// stored for it, or its distance to the next zeroing move. Tables do not
+// encode en passant rights and assume the side to move has no winning
+// capture, so callers with a move generator (see socrates) wrap these probes
+// in a small capture search before trusting them.
+//
+// The decoder follows the reference probing code by Ronald de Man as found in
+// Stockfish: positions are mapped to an index by removing symmetries and
+// grouping identical pieces, and values are stored in blocks of canonical
+// Huffman codes over a "recursive pairing" dictionary.
+package syzygy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Piece codes follow the Syzygy file format: White pawn..king are 1..6 and
+// Black pieces add 8.
+type Piece uint8
+
+const (
+	NoPiece Piece = 0
+	WPawn   Piece = 1
+	WKnight Piece = 2
+	WBishop Piece = 3
+	WRook   Piece = 4
+	WQueen  Piece = 5
+	WKing   Piece = 6
+	BPawn   Piece = 9
+	BKnight Piece = 10
+	BBishop Piece = 11
+	BRook   Piece = 12
+	BQueen  Piece = 13
+	BKing   Piece = 14
+)
+
+// Color returns 0 for White and 1 for Black.
+func (p Piece) Color() int { return int(p >> 3) }
+
+// Type returns 1 (pawn) .. 6 (king).
+func (p Piece) Type() int { return int(p & 7) }
+
+// Position is the static view of a board needed for a table lookup.
+type Position struct {
+	Board [64]Piece // a1 = 0 .. h8 = 63
+	Turn  int       // 0 = White to move
+}
+
+// WDL is a win/draw/loss value from the side to move's point of view.
+// Cursed wins and blessed losses are decided by the fifty-move rule.
+type WDL int
+
+const (
+	Loss        WDL = -2
+	BlessedLoss WDL = -1
+	Draw        WDL = 0
+	CursedWin   WDL = 1
+	Win         WDL = 2
+)
+
+var (
+	// ErrMissing reports that no table covers the position's material.
+	ErrMissing = errors.New("syzygy: table not available")
+	// ErrCorrupt reports a table file that does not match the format.
+	ErrCorrupt = errors.New("syzygy: corrupt table file")
+)
+
+const (
+	wdlSuffix = ".rtbw"
+	dtzSuffix = ".rtbz"
+)
+
+var (
+	wdlMagic = [4]byte{0x71, 0xE8, 0x23, 0x5D}
+	dtzMagic = [4]byte{0xD7, 0x66, 0x0C, 0xA5}
+)
+
+// Tablebase is a set of table files found on a search path.
+type Tablebase struct {
+	mu        sync.Mutex
+	entries   map[string]*entry // by material key, both colour orders
+	maxPieces int
+}
+
+// Open scans the directories in path (separated by os.PathListSeparator)
+// for table files. Tables are loaded lazily on first probe.
+func Open(path string) (*Tablebase, error) {
+	tb := &Tablebase{entries: make(map[string]*entry)}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			name := f.Name()
+			var isWDL bool
+			switch {
+			case strings.HasSuffix(name, wdlSuffix):
+				isWDL = true
+			case strings.HasSuffix(name, dtzSuffix):
+			default:
+				continue
+			}
+			key := name[:len(name)-len(wdlSuffix)]
+			e := tb.entries[key]
+			if e == nil {
+				if e = newEntry(key); e == nil {
+					continue // not a material signature
+				}
+				tb.entries[e.key] = e
+				tb.entries[e.key2] = e
+			}
+			if isWDL {
+				e.wdl.path = filepath.Join(dir, name)
+			} else {
+				e.dtz.path = filepath.Join(dir, name)
+			}
+			tb.maxPieces = max(tb.maxPieces, e.pieceCount)
+		}
+	}
+	return tb, nil
+}
+
+// MaxPieces is the largest piece count (kings included) with a table.
+func (tb *Tablebase) MaxPieces() int {
+	if tb == nil {
+		return 0
+	}
+	return tb.maxPieces
+}
+
+// Count returns the number of distinct material signatures available.
+func (tb *Tablebase) Count() int {
+	seen := make(map[*entry]bool)
+	for _, e := range tb.entries {
+		seen[e] = true
+	}
+	return len(seen)
+}
+
+// MaterialKey returns the Syzygy-style signature of a position, e.g. "KRvK".
+func (pos *Position) MaterialKey() string {
+	var counts [2][7]int
+	for _, p := range pos.Board {
+		if p != NoPiece {
+			counts[p.Color()][p.Type()]++
+		}
+	}
+	return sideCode(counts[0]) + "v" + sideCode(counts[1])
+}
+
+func sideCode(counts [7]int) string {
+	const letters = " PNBRQK"
+	var sb strings.Builder
+	for t := 6; t >= 1; t-- {
+		for i := 0; i < counts[t]; i++ {
+			sb.WriteByte(letters[t])
+		}
+	}
+	return sb.String()
+}
+
+func (pos *Position) pieceCount() int {
+	n := 0
+	for _, p := range pos.Board {
+		if p != NoPiece {
+			n++
+		}
+	}
+	return n
+}
+
+// ProbeWDLTable returns the stored WDL value of a position. The result is
+// only meaningful if the position has no en passant rights and the side to
+// move has no capture that does better; see the package comment.
+func (tb *Tablebase) ProbeWDLTable(pos *Position) (WDL, error) {
+	if pos.pieceCount() == 2 {
+		return Draw, nil // KvK
+	}
+	e := tb.lookup(pos)
+	if e == nil {
+		return Draw, ErrMissing
+	}
+	t, err := tb.load(e, false)
+	if err != nil {
+		return Draw, err
+	}
+	v, _, err := t.probe(e, pos, Draw)
+	return WDL(v), err
+}
+
+// ProbeDTZTable returns the stored distance to zeroing in plies, signed like
+// wdl, for a position whose WDL value is already known and non-zero.
+// DTZ files hold only one side to move; ok is false when the position's side
+// to move is not stored and the caller must search one ply deeper.
+func (tb *Tablebase) ProbeDTZTable(pos *Position, wdl WDL) (dtz int, ok bool, err error) {
+	e := tb.lookup(pos)
+	if e == nil {
+		return 0, false, ErrMissing
+	}
+	t, err := tb.load(e, true)
+	if err != nil {
+		return 0, false, err
+	}
+	v, stored, err := t.probe(e, pos, wdl)
+	if err != nil || !stored {
+		return 0, stored, err
+	}
+	if wdl == CursedWin || wdl == BlessedLoss {
+		v += 100
+	}
+	if wdl < 0 {
+		v = -v
+	}
+	return v, true, nil
+}
+
+func (tb *Tablebase) lookup(pos *Position) *entry {
+	if tb == nil {
+		return nil
+	}
+	return tb.entries[pos.MaterialKey()]
+}
+
+// load reads and parses a table file on first use.
+func (tb *Tablebase) load(e *entry, dtz bool) (*table, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	slot := &e.wdl
+	if dtz {
+		slot = &e.dtz
+	}
+	if slot.table != nil || slot.err != nil {
+		return slot.table, slot.err
+	}
+	if slot.path == "" {
+		slot.err = ErrMissing
+		return nil, slot.err
+	}
+	data, err := os.ReadFile(slot.path)
+	if err != nil {
+		slot.err = err
+		return nil, err
+	}
+	slot.table, slot.err = parseTable(e, data, dtz)
+	return slot.table, slot.err
+}
@@ -0,0 +1,396 @@
+// --- syzygy/syzygy_test.go ---
+
+package syzygy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTestdata(t *testing.T) *Tablebase {
+	t.Helper()
+	tb, err := Open("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tb
+}
+
+// place builds a position from square/piece pairs such as "e1", WKing.
+func place(turn int, pcs map[string]Piece) *Position {
+	pos := &Position{Turn: turn}
+	for s, p := range pcs {
+		pos.Board[int(s[1]-'1')*8+int(s[0]-'a')] = p
+	}
+	return pos
+}
+
+func TestOpenScansDirectory(t *testing.T) {
+	tb := openTestdata(t)
+	if tb.MaxPieces() != 3 {
+		t.Fatalf("MaxPieces = %d, want 3", tb.MaxPieces())
+	}
+	if tb.Count() != 3 {
+		t.Fatalf("Count = %d, want 3 (KPvK, KRvK, KNvK)", tb.Count())
+	}
+	var empty *Tablebase
+	if empty.MaxPieces() != 0 {
+		t.Fatal("nil tablebase should report no pieces")
+	}
+}
+
+func TestMaterialKey(t *testing.T) {
+	pos := place(0, map[string]Piece{"e1": WKing, "a1": WRook, "e8": BKing, "d7": BPawn})
+	if got := pos.MaterialKey(); got != "KRvKP" {
+		t.Fatalf("MaterialKey = %q", got)
+	}
+}
+
+func TestKPvKMatchesBitbase(t *testing.T) {
+	tb := openTestdata(t)
+	for psq := 8; psq < 56; psq++ {
+		for wk := 0; wk < 64; wk++ {
+			for bk := 0; bk < 64; bk++ {
+				if wk == psq || bk == psq || wk == bk || kingsTouch(wk, bk) {
+					continue
+				}
+				for turn := 0; turn < 2; turn++ {
+					pos := &Position{Turn: turn}
+					pos.Board[psq], pos.Board[wk], pos.Board[bk] = WPawn, WKing, BKing
+					want, ok := kpkValue(pos)
+					if !ok {
+						continue
+					}
+					got, err := tb.ProbeWDLTable(pos)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if int(got)+2 != want {
+						t.Fatalf("pawn %d wk %d bk %d stm %d: got %d, want %d", psq, wk, bk, turn, got, want-2)
+					}
+
+					// The same ending with colours reversed reads the table flipped.
+					flipped := &Position{Turn: 1 - turn}
+					flipped.Board[flipRank(psq)] = BPawn
+					flipped.Board[flipRank(wk)] = BKing
+					flipped.Board[flipRank(bk)] = WKing
+					if got2, _ := tb.ProbeWDLTable(flipped); got2 != got {
+						t.Fatalf("colour-flipped probe %d differs from %d", got2, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestKRvKWDL(t *testing.T) {
+	tb := openTestdata(t)
+	cases := []struct {
+		name string
+		pos  *Position
+		want WDL
+	}{
+		{"white to move wins", place(0, map[string]Piece{"e1": WKing, "a1": WRook, "e8": BKing}), Win},
+		{"black takes the rook", place(1, map[string]Piece{"e1": WKing, "d7": WRook, "e8": BKing}), Draw},
+		{"black to move loses", place(1, map[string]Piece{"e1": WKing, "a2": WRook, "e8": BKing}), Loss},
+		{"stalemate", place(1, map[string]Piece{"c2": WKing, "b2": WRook, "a1": BKing}), Draw},
+		{"checkmate", place(1, map[string]Piece{"e6": WKing, "a8": WRook, "e8": BKing}), Loss},
+		{"black has the rook", place(0, map[string]Piece{"e1": WKing, "a2": BRook, "e8": BKing}), Loss},
+	}
+	for _, c := range cases {
+		got, err := tb.ProbeWDLTable(c.pos)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestKRvKDTZ(t *testing.T) {
+	tb := openTestdata(t)
+
+	// Ra1-a8 mates at once.
+	mateIn1 := place(0, map[string]Piece{"e6": WKing, "a1": WRook, "e8": BKing})
+	dtz, ok, err := tb.ProbeDTZTable(mateIn1, Win)
+	if err != nil || !ok || dtz != 1 {
+		t.Fatalf("mate in one: dtz %d ok %v err %v, want 1", dtz, ok, err)
+	}
+
+	// Distances grow as the defending king gets more room.
+	far := place(0, map[string]Piece{"a1": WKing, "b1": WRook, "e5": BKing})
+	dtz2, ok, err := tb.ProbeDTZTable(far, Win)
+	if err != nil || !ok || dtz2 <= 1 || dtz2%2 != 1 || dtz2 > 31 {
+		t.Fatalf("far position: dtz %d ok %v err %v", dtz2, ok, err)
+	}
+
+	// Seen from the losing side the sign flips.
+	flipped := place(1, map[string]Piece{"a8": BKing, "b8": BRook, "e4": WKing})
+	if d, ok, _ := tb.ProbeDTZTable(flipped, Loss); !ok || d != -dtz2 {
+		t.Fatalf("flipped dtz %d ok %v, want %d", d, ok, -dtz2)
+	}
+
+	// Only White to move is stored.
+	btm := place(1, map[string]Piece{"e1": WKing, "a2": WRook, "e8": BKing})
+	if _, ok, err := tb.ProbeDTZTable(btm, Loss); ok || err != nil {
+		t.Fatalf("black to move should not be stored: ok %v err %v", ok, err)
+	}
+}
+
+func TestKRvKLongestWin(t *testing.T) {
+	// The longest KRvK win takes 16 moves, and with no pawns to push DTZ is
+	// the distance to mate: 31 plies from the winning side.
+	tb := openTestdata(t)
+	longest := 0
+	for wk := 0; wk < 64; wk++ {
+		for wr := 0; wr < 64; wr++ {
+			for bk := 0; bk < 64; bk++ {
+				if wk == wr || wr == bk || wk == bk || kingsTouch(wk, bk) || rookSees(wr, bk, wk) {
+					continue
+				}
+				pos := &Position{}
+				pos.Board[wk], pos.Board[wr], pos.Board[bk] = WKing, WRook, BKing
+				if dtz, ok, err := tb.ProbeDTZTable(pos, Win); err != nil {
+					t.Fatal(err)
+				} else if ok {
+					longest = max(longest, dtz)
+				}
+			}
+		}
+	}
+	if longest != 31 {
+		t.Fatalf("longest KRvK win = %d plies, want 31", longest)
+	}
+}
+
+// kpkPosition places a white pawn and the two kings.
+func kpkPosition(turn, psq, wk, bk int) *Position {
+	pos := &Position{Turn: turn}
+	pos.Board[psq], pos.Board[wk], pos.Board[bk] = WPawn, WKing, BKing
+	return pos
+}
+
+func TestKPvKReferencePositions(t *testing.T) {
+	tb := openTestdata(t)
+	cases := []struct {
+		name string
+		pos  *Position
+		want WDL
+	}{
+		{"king in front on the sixth", place(0, map[string]Piece{"e6": WKing, "e5": WPawn, "e8": BKing}), Win},
+		{"king in front on the sixth, Black to move", place(1, map[string]Piece{"e6": WKing, "e5": WPawn, "e8": BKing}), Loss},
+		{"defender holds the opposition", place(0, map[string]Piece{"e5": WKing, "e4": WPawn, "e7": BKing}), Draw},
+		{"attacker holds the opposition", place(1, map[string]Piece{"e5": WKing, "e4": WPawn, "e7": BKing}), Loss},
+		{"rook pawn, defender in the corner", place(0, map[string]Piece{"b6": WKing, "a6": WPawn, "a8": BKing}), Draw},
+		{"outside the square", place(0, map[string]Piece{"h1": WKing, "h2": WPawn, "a3": BKing}), Win},
+		{"inside the square", place(1, map[string]Piece{"h1": WKing, "h4": WPawn, "d4": BKing}), Draw},
+	}
+	for _, c := range cases {
+		got, err := tb.ProbeWDLTable(c.pos)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+
+	// A pawn that simply runs wins with its first move.
+	runner := place(0, map[string]Piece{"h1": WKing, "h2": WPawn, "a3": BKing})
+	if dtz, ok, err := tb.ProbeDTZTable(runner, Win); err != nil || !ok || dtz != 1 {
+		t.Fatalf("runner: dtz %d ok %v err %v, want 1", dtz, ok, err)
+	}
+}
+
+// TestKPvKDTZ checks the pawn DTZ table against the retrograde solution and
+// against the WDL table: a win with DTZ 1 has a winning pawn move, and any
+// other win has a king move after which Black's best reply leaves exactly
+// two plies less.
+func TestKPvKDTZ(t *testing.T) {
+	tb := openTestdata(t)
+	krk := solveKRvK()
+	solved := solveKPvK(krk)
+	dtz := make(map[[3]int]int)
+	for psq := 8; psq < 56; psq++ {
+		for wk := 0; wk < 64; wk++ {
+			for bk := 0; bk < 64; bk++ {
+				if wk == psq || bk == psq || wk == bk {
+					continue
+				}
+				pos := kpkPosition(0, psq, wk, bk)
+				if _, legal := kpkValue(pos); !legal {
+					continue
+				}
+				wdl, err := tb.ProbeWDLTable(pos)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, _ := solved.lookup(pos)
+				if (wdl == Win) != (want > 0) {
+					t.Fatalf("pawn %d wk %d bk %d: WDL %d but solved DTZ %d", psq, wk, bk, wdl, want)
+				}
+				if wdl != Win {
+					continue
+				}
+				d, ok, err := tb.ProbeDTZTable(pos, Win)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !ok {
+					continue // Black to move stored instead
+				}
+				if d != want {
+					t.Fatalf("pawn %d wk %d bk %d: dtz %d, want %d", psq, wk, bk, d, want)
+				}
+				dtz[[3]int{psq, wk, bk}] = d
+			}
+		}
+	}
+	if len(dtz) == 0 {
+		t.Fatal("no winning positions found")
+	}
+
+	lost := func(psq, wk, bk int) bool {
+		wdl, err := tb.ProbeWDLTable(kpkPosition(1, psq, wk, bk))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return wdl == Loss
+	}
+	for key, d := range dtz {
+		psq, wk, bk := key[0], key[1], key[2]
+		pushWins := false
+		for _, to := range []int{psq + 8, psq + 16} {
+			if to == wk || to == bk || (to == psq+16 && rankOf(psq) != 1) {
+				break
+			}
+			if rankOf(to) == 7 {
+				pushWins = promotionWins(to, wk, bk, krk)
+			} else {
+				pushWins = pushWins || lost(to, wk, bk)
+			}
+		}
+		if pushWins != (d == 1) {
+			t.Fatalf("pawn %d wk %d bk %d: dtz %d but winning push %v", psq, wk, bk, d, pushWins)
+		}
+		if d == 1 {
+			continue
+		}
+		best := 0
+		for _, to := range kingTargets(wk) {
+			if to == psq || kingsTouch(to, bk) || !lost(psq, to, bk) {
+				continue
+			}
+			longest := 0
+			for _, reply := range kingTargets(bk) {
+				attacked := rankOf(reply) == rankOf(psq)+1 && abs(fileOf(reply)-fileOf(psq)) == 1
+				if kingsTouch(reply, to) || reply == psq || attacked {
+					continue
+				}
+				longest = max(longest, dtz[[3]int{psq, to, reply}])
+			}
+			if best == 0 || longest+2 < best {
+				best = longest + 2
+			}
+		}
+		if best != d {
+			t.Fatalf("pawn %d wk %d bk %d: dtz %d, best king move gives %d", psq, wk, bk, d, best)
+		}
+	}
+}
+
+// TestTablesUsePairs makes sure the test tables exercise the full decoder:
+// pair symbols expanding to several values and codes of several lengths.
+func TestTablesUsePairs(t *testing.T) {
+	for _, name := range []string{"KPvK.rtbw", "KRvK.rtbw", "KRvK.rtbz", "KPvK.rtbz"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		table, err := parseTable(newEntry(name[:4]), data, name[5:] == "rtbz")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		pairs, lengths := false, false
+		for i := range table.pairs {
+			for _, d := range table.pairs[i] {
+				if d == nil || d.flags&flagSingleValue != 0 {
+					continue
+				}
+				lengths = lengths || d.maxSymLen > d.minSymLen
+				for _, l := range d.symlen {
+					pairs = pairs || l > 0
+				}
+			}
+		}
+		if !pairs || !lengths {
+			t.Errorf("%s: pair symbols %v, several code lengths %v", name, pairs, lengths)
+		}
+	}
+}
+
+func TestSingleValueTable(t *testing.T) {
+	tb := openTestdata(t)
+	pos := place(0, map[string]Piece{"e1": WKing, "b1": WKnight, "e8": BKing})
+	if got, err := tb.ProbeWDLTable(pos); err != nil || got != Draw {
+		t.Fatalf("KNvK: got %d, %v", got, err)
+	}
+}
+
+func TestMissingAndCorruptTables(t *testing.T) {
+	tb := openTestdata(t)
+	kqk := place(0, map[string]Piece{"e1": WKing, "d1": WQueen, "e8": BKing})
+	if _, err := tb.ProbeWDLTable(kqk); !errors.Is(err, ErrMissing) {
+		t.Fatalf("KQvK: err %v, want ErrMissing", err)
+	}
+	knk := place(0, map[string]Piece{"e1": WKing, "b1": WKnight, "e8": BKing})
+	if _, _, err := tb.ProbeDTZTable(knk, Win); !errors.Is(err, ErrMissing) {
+		t.Fatalf("KNvK DTZ: err %v, want ErrMissing", err)
+	}
+	kk := place(0, map[string]Piece{"e1": WKing, "e8": BKing})
+	if got, err := tb.ProbeWDLTable(kk); err != nil || got != Draw {
+		t.Fatalf("KvK: got %d, %v", got, err)
+	}
+
+	dir := t.TempDir()
+	data, err := os.ReadFile(filepath.Join("testdata", "KRvK.rtbw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "KRvK.rtbw"), data[:100], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.txt"), []byte("not a table"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bad, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	krk := place(0, map[string]Piece{"e1": WKing, "a1": WRook, "e8": BKing})
+	if _, err := bad.ProbeWDLTable(krk); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("truncated table: err %v, want ErrCorrupt", err)
+	}
+}
+
+func TestEncodingTables(t *testing.T) {
+	// 462 king pairs with the first king in the a1-d1-d4 triangle.
+	maxKK := 0
+	for i := range mapKK {
+		for _, v := range mapKK[i] {
+			maxKK = max(maxKK, v)
+		}
+	}
+	if maxKK != 461 {
+		t.Fatalf("max MapKK = %d, want 461", maxKK)
+	}
+	if binomial[2][5] != 10 || binomial[3][48] != 17296 {
+		t.Fatal("binomial table is wrong")
+	}
+	if mapPawns[8] != 47 || mapPawns[15] != 46 {
+		t.Fatalf("MapPawns a2/h2 = %d/%d", mapPawns[8], mapPawns[15])
+	}
+}
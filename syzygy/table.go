@@ -0,0 +1,426 @@
+// --- syzygy/table.go ---
+
+package syzygy
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// tbPieces is the largest piece count the format supports.
+const tbPieces = 7
+
+// Per-table flags.
+const (
+	flagSTM         = 1
+	flagMapped      = 2
+	flagWinPlies    = 4
+	flagLossPlies   = 8
+	flagWide        = 16
+	flagSingleValue = 128
+)
+
+// entry describes one material signature and its two table files.
+type entry struct {
+	key, key2       string // stronger side as White, and colours swapped
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	pawnCount       [2]int // leading colour first
+	wdl, dtz        slot
+}
+
+type slot struct {
+	path  string
+	table *table
+	err   error
+}
+
+// newEntry parses a file name such as "KRPvKR", or returns nil.
+func newEntry(name string) *entry {
+	white, black, ok := strings.Cut(name, "v")
+	if !ok || !validSide(white) || !validSide(black) {
+		return nil
+	}
+	e := &entry{
+		key:        name,
+		key2:       black + "v" + white,
+		pieceCount: len(white) + len(black),
+	}
+	if e.pieceCount > tbPieces {
+		return nil
+	}
+	wp, bp := strings.Count(white, "P"), strings.Count(black, "P")
+	e.hasPawns = wp+bp > 0
+	for _, side := range []string{white, black} {
+		for _, c := range "QRBNP" {
+			if strings.Count(side, string(c)) == 1 {
+				e.hasUniquePieces = true
+			}
+		}
+	}
+	// The leading colour is the one with fewer (but some) pawns.
+	if bp == 0 || (wp > 0 && bp >= wp) {
+		e.pawnCount = [2]int{wp, bp}
+	} else {
+		e.pawnCount = [2]int{bp, wp}
+	}
+	return e
+}
+
+func validSide(s string) bool {
+	if len(s) == 0 || s[0] != 'K' || strings.Count(s, "K") != 1 {
+		return false
+	}
+	return strings.Trim(s, "KQRBNP") == ""
+}
+
+// table is a parsed WDL or DTZ file.
+type table struct {
+	dtz      bool
+	hasPawns bool
+	sides    int
+	pairs    [2][4]*pairsData // by side to move and pawn file
+	dtzMap   []byte
+}
+
+// pairsData is one compressed sub-table.
+type pairsData struct {
+	flags       uint8
+	pieces      [tbPieces]Piece
+	groupLen    [tbPieces + 1]int
+	groupIdx    [tbPieces + 1]uint64
+	sizeofBlock uint64
+	span        uint64
+	numBlocks   int
+	maxSymLen   int
+	minSymLen   int // the stored value for single-value tables
+	lowestSym   []byte
+	base64      []uint64
+	symlen      []int
+	btree       []byte
+	sparseIndex []byte
+	blockLength []byte
+	data        []byte
+	mapIdx      [4]int
+
+	sparseIndexSize int
+	blockLengthSize int
+}
+
+func (t *table) get(stm, file int) *pairsData {
+	if !t.hasPawns {
+		file = 0
+	}
+	return t.pairs[stm%t.sides][file]
+}
+
+// storesSide reports whether a one-sided DTZ table holds the given side to move.
+func (t *table) storesSide(e *entry, stm, file int) bool {
+	return int(t.get(stm, file).flags&flagSTM) == stm || (e.key == e.key2 && !e.hasPawns)
+}
+
+func le16(b []byte, off int) int    { return int(binary.LittleEndian.Uint16(b[off:])) }
+func le32(b []byte, off int) uint64 { return uint64(binary.LittleEndian.Uint32(b[off:])) }
+
+// parseTable lays out the sub-tables of a file. Any out-of-range access while
+// parsing means the file is truncated or not a table.
+func parseTable(e *entry, data []byte, dtz bool) (t *table, err error) {
+	defer func() {
+		if recover() != nil {
+			t, err = nil, ErrCorrupt
+		}
+	}()
+
+	magic := wdlMagic
+	if dtz {
+		magic = dtzMagic
+	}
+	if len(data) < 5 || [4]byte(data[:4]) != magic {
+		return nil, ErrCorrupt
+	}
+	split := e.key != e.key2
+	if (data[4]&2 != 0) != e.hasPawns || (!dtz && (data[4]&1 != 0) != split) {
+		return nil, ErrCorrupt
+	}
+
+	t = &table{dtz: dtz, hasPawns: e.hasPawns, sides: 1}
+	if !dtz && split {
+		t.sides = 2
+	}
+	files := 1
+	if e.hasPawns {
+		files = 4
+	}
+	pp := e.hasPawns && e.pawnCount[1] > 0
+
+	pos := 5
+	for f := 0; f < files; f++ {
+		for i := 0; i < t.sides; i++ {
+			t.pairs[i][f] = &pairsData{}
+		}
+		order := [2][2]int{{int(data[pos] & 0xF), 0xF}, {int(data[pos] >> 4), 0xF}}
+		if pp {
+			order[0][1], order[1][1] = int(data[pos+1]&0xF), int(data[pos+1]>>4)
+			pos++
+		}
+		pos++
+		for k := 0; k < e.pieceCount; k++ {
+			for i := 0; i < t.sides; i++ {
+				p := data[pos] & 0xF
+				if i == 1 {
+					p = data[pos] >> 4
+				}
+				t.pairs[i][f].pieces[k] = Piece(p)
+			}
+			pos++
+		}
+		for i := 0; i < t.sides; i++ {
+			setGroups(e, t.pairs[i][f], order[i], f)
+		}
+	}
+	pos += pos & 1
+
+	for f := 0; f < files; f++ {
+		for i := 0; i < t.sides; i++ {
+			pos = t.pairs[i][f].setSizes(data, pos)
+		}
+	}
+	if dtz {
+		pos = t.setDTZMap(data, pos, files)
+	}
+	for f := 0; f < files; f++ {
+		for i := 0; i < t.sides; i++ {
+			d := t.pairs[i][f]
+			d.sparseIndex = data[pos : pos+d.sparseIndexSize*6]
+			pos += d.sparseIndexSize * 6
+		}
+	}
+	for f := 0; f < files; f++ {
+		for i := 0; i < t.sides; i++ {
+			d := t.pairs[i][f]
+			d.blockLength = data[pos : pos+d.blockLengthSize*2]
+			pos += d.blockLengthSize * 2
+		}
+	}
+	for f := 0; f < files; f++ {
+		for i := 0; i < t.sides; i++ {
+			d := t.pairs[i][f]
+			pos = (pos + 63) &^ 63
+			n := d.numBlocks * int(d.sizeofBlock)
+			d.data = data[pos : pos+n]
+			pos += n
+		}
+	}
+	return t, nil
+}
+
+// setSizes reads a sub-table header and its symbol dictionary.
+func (d *pairsData) setSizes(data []byte, pos int) int {
+	d.flags = data[pos]
+	pos++
+	if d.flags&flagSingleValue != 0 {
+		d.minSymLen = int(data[pos])
+		return pos + 1
+	}
+
+	tbSize := d.size()
+	d.sizeofBlock = 1 << data[pos]
+	d.span = 1 << data[pos+1]
+	d.sparseIndexSize = int((tbSize + d.span - 1) / d.span)
+	padding := int(data[pos+2])
+	d.numBlocks = int(le32(data, pos+3))
+	d.blockLengthSize = d.numBlocks + padding
+	d.maxSymLen = int(data[pos+7])
+	d.minSymLen = int(data[pos+8])
+	pos += 9
+
+	// Canonical Huffman codes: longer codes have lower numeric values. base64
+	// holds, per code length, the lowest code left-aligned in 64 bits.
+	n := d.maxSymLen - d.minSymLen + 1
+	d.lowestSym = data[pos : pos+2*n]
+	d.base64 = make([]uint64, n)
+	for i := n - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(le16(d.lowestSym, 2*i)) - uint64(le16(d.lowestSym, 2*(i+1)))) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= 64 - i - d.minSymLen
+	}
+	pos += 2 * n
+
+	numSyms := le16(data, pos)
+	pos += 2
+	d.btree = data[pos : pos+3*numSyms]
+	d.symlen = make([]int, numSyms)
+	visited := make([]bool, numSyms)
+	for s := range d.symlen {
+		if !visited[s] {
+			d.symlen[s] = d.setSymlen(s, visited)
+		}
+	}
+	return pos + 3*numSyms + numSyms&1
+}
+
+// Each dictionary symbol is either a leaf holding a value (right = 0xFFF) or
+// a pair of two earlier symbols; symlen is the expanded length minus one.
+func (d *pairsData) left(s int) int {
+	return int(d.btree[3*s+1]&0xF)<<8 | int(d.btree[3*s])
+}
+
+func (d *pairsData) right(s int) int {
+	return int(d.btree[3*s+2])<<4 | int(d.btree[3*s+1]>>4)
+}
+
+func (d *pairsData) setSymlen(s int, visited []bool) int {
+	visited[s] = true
+	sr := d.right(s)
+	if sr == 0xFFF {
+		return 0
+	}
+	sl := d.left(s)
+	if !visited[sl] {
+		d.symlen[sl] = d.setSymlen(sl, visited)
+	}
+	if !visited[sr] {
+		d.symlen[sr] = d.setSymlen(sr, visited)
+	}
+	return d.symlen[sl] + d.symlen[sr] + 1
+}
+
+// setDTZMap records where the value maps of each pawn file start. Mapped DTZ
+// tables store small indices that are translated through these maps.
+func (t *table) setDTZMap(data []byte, pos, files int) int {
+	base := pos
+	t.dtzMap = data[base:]
+	for f := 0; f < files; f++ {
+		d := t.pairs[0][f]
+		if d.flags&flagMapped == 0 {
+			continue
+		}
+		if d.flags&flagWide != 0 {
+			pos += pos & 1
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = (pos-base)/2 + 1
+				pos += 2*le16(data, pos) + 2
+			}
+		} else {
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = pos - base + 1
+				pos += int(data[pos]) + 1
+			}
+		}
+	}
+	return pos + pos&1
+}
+
+// decompress returns the value stored at idx.
+func (d *pairsData) decompress(idx uint64) int {
+	if d.flags&flagSingleValue != 0 {
+		return d.minSymLen
+	}
+
+	// The sparse index points at the block holding the value at the middle of
+	// each span; walk to the right block from there.
+	k := idx / d.span
+	block := int(le32(d.sparseIndex, int(k)*6))
+	offset := le16(d.sparseIndex, int(k)*6+4)
+	offset += int(idx%d.span) - int(d.span/2)
+	for offset < 0 {
+		block--
+		offset += le16(d.blockLength, 2*block) + 1
+	}
+	for offset > le16(d.blockLength, 2*block) {
+		offset -= le16(d.blockLength, 2*block) + 1
+		block++
+	}
+
+	ptr := block * int(d.sizeofBlock)
+	buf := d.word64(ptr)
+	ptr += 8
+	bufSize := 64
+	var sym int
+	for {
+		l := 0
+		for buf < d.base64[l] {
+			l++
+		}
+		sym = int((buf-d.base64[l])>>(64-l-d.minSymLen)) + le16(d.lowestSym, 2*l)
+		if offset < d.symlen[sym]+1 {
+			break
+		}
+		offset -= d.symlen[sym] + 1
+		l += d.minSymLen
+		buf <<= l
+		bufSize -= l
+		if bufSize <= 32 {
+			bufSize += 32
+			buf |= uint64(d.word32(ptr)) << (64 - bufSize)
+			ptr += 4
+		}
+	}
+
+	// Expand the pair symbol down to the leaf holding our value.
+	for d.symlen[sym] != 0 {
+		left := d.left(sym)
+		if offset < d.symlen[left]+1 {
+			sym = left
+		} else {
+			offset -= d.symlen[left] + 1
+			sym = d.right(sym)
+		}
+	}
+	return d.left(sym)
+}
+
+// word32 and word64 read big-endian words; the decoder may look a few bytes
+// past the last block, which reads as zeros.
+func (d *pairsData) word32(off int) uint32 {
+	var b [4]byte
+	if off < len(d.data) {
+		copy(b[:], d.data[off:])
+	}
+	return binary.BigEndian.Uint32(b[:])
+}
+
+func (d *pairsData) word64(off int) uint64 {
+	return uint64(d.word32(off))<<32 | uint64(d.word32(off+4))
+}
+
+// probe decodes the value of a position: a WDL score, or DTZ in plies for a
+// position whose WDL is wdl. stored is false if a DTZ table lacks the side.
+func (t *table) probe(e *entry, pos *Position, wdl WDL) (value int, stored bool, err error) {
+	defer func() {
+		if recover() != nil {
+			value, stored, err = 0, false, ErrCorrupt
+		}
+	}()
+	d, idx, file, ok := t.encode(e, pos)
+	if !ok {
+		return 0, false, nil
+	}
+	v := d.decompress(idx)
+	if !t.dtz {
+		return v - 2, true, nil
+	}
+	return t.mapScore(d, file, v, wdl), true, nil
+}
+
+// mapScore turns a stored DTZ value into plies.
+func (t *table) mapScore(d *pairsData, file, value int, wdl WDL) int {
+	wdlMap := [5]int{1, 3, 0, 2, 0}
+	d = t.get(0, file)
+	if d.flags&flagMapped != 0 {
+		i := d.mapIdx[wdlMap[wdl+2]] + value
+		if d.flags&flagWide != 0 {
+			value = le16(t.dtzMap, 2*i)
+		} else {
+			value = int(t.dtzMap[i])
+		}
+	}
+	if (wdl == Win && d.flags&flagWinPlies == 0) ||
+		(wdl == Loss && d.flags&flagLossPlies == 0) ||
+		wdl == CursedWin || wdl == BlessedLoss {
+		value *= 2
+	}
+	return value + 1
+}
@@ -0,0 +1,879 @@
+// --- syzygy/writer_test.go ---
+
+package syzygy
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/mesb/mchess/endgame"
+)
+
+// The tables in testdata are not downloaded Syzygy files: they are written by
+// the encoder below from independently computed values (the KPK bitbase and
+// retrograde KRvK and KPvK DTZ solvers), compressed the way the generator
+// does it, with recursive pairing and canonical Huffman codes. The reference
+// positions and cross-checks in syzygy_test.go make no use of the encoder,
+// so they apply equally to the official KPvK, KRvK and KNvK files. Run
+// `go test ./syzygy -run Testdata -update` to rebuild.
+
+var update = flag.Bool("update", false, "rewrite testdata tables")
+
+type tableSpec struct {
+	name   string
+	dtz    bool
+	flags  uint8   // sub-table flags (DTZ)
+	pieces []Piece // compression order, leading pawns first
+	// value returns the stored value of a position, or false if the
+	// position is illegal or not stored.
+	value func(pos *Position) (int, bool)
+	// class picks the value map of a mapped DTZ position: 0 win, 1 loss,
+	// 2 cursed win, 3 blessed loss.
+	class func(pos *Position) int
+}
+
+func testdataSpecs() []tableSpec {
+	krk := solveKRvK()
+	kpk := solveKPvK(krk)
+	return []tableSpec{
+		{name: "KPvK", pieces: []Piece{WPawn, WKing, BKing}, value: kpkValue},
+		{name: "KRvK", pieces: []Piece{WKing, WRook, BKing}, value: func(pos *Position) (int, bool) {
+			r, ok := krk.lookup(pos)
+			return int(r.wdl) + 2, ok
+		}},
+		{name: "KRvK", dtz: true, flags: flagWinPlies | flagLossPlies, pieces: []Piece{WKing, WRook, BKing},
+			value: func(pos *Position) (int, bool) {
+				r, ok := krk.lookup(pos)
+				if !ok || pos.Turn != 0 {
+					return 0, false
+				}
+				return r.plies - 1, true
+			}},
+		{name: "KPvK", dtz: true, flags: flagMapped | flagWinPlies | flagLossPlies, pieces: []Piece{WPawn, WKing, BKing},
+			value: func(pos *Position) (int, bool) {
+				plies, ok := kpk.lookup(pos)
+				if !ok || pos.Turn != 0 || plies == 0 {
+					return 0, false
+				}
+				return plies - 1, true
+			},
+			class: func(*Position) int { return 0 }},
+		{name: "KNvK", pieces: []Piece{WKing, WKnight, BKing}, value: func(pos *Position) (int, bool) {
+			return int(Draw) + 2, true
+		}},
+	}
+}
+
+func TestTestdataUpToDate(t *testing.T) {
+	if !*update {
+		t.Skip("run with -update to regenerate testdata tables")
+	}
+	for _, spec := range testdataSpecs() {
+		data, err := buildTable(spec)
+		if err != nil {
+			t.Fatalf("%s: %v", spec.name, err)
+		}
+		suffix := wdlSuffix
+		if spec.dtz {
+			suffix = dtzSuffix
+		}
+		path := filepath.Join("testdata", spec.name+suffix)
+		if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+			continue
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("wrote %s (%d bytes)", path, len(data))
+	}
+}
+
+// buildTable enumerates every placement of the pieces, maps it through the
+// prober's own index encoding and compresses the resulting value arrays.
+// Two placements with the same index must agree, which checks that the
+// encoding only merges positions that are true symmetries of each other.
+func buildTable(spec tableSpec) ([]byte, error) {
+	e := newEntry(spec.name)
+	t := &table{dtz: spec.dtz, hasPawns: e.hasPawns, sides: 1}
+	if !spec.dtz && e.key != e.key2 {
+		t.sides = 2
+	}
+	files := 1
+	if e.hasPawns {
+		files = 4
+	}
+	values := make(map[*pairsData][]int)
+	classes := make(map[*pairsData][]int)
+	for f := 0; f < files; f++ {
+		for i := 0; i < t.sides; i++ {
+			d := &pairsData{flags: spec.flags}
+			copy(d.pieces[:], spec.pieces)
+			setGroups(e, d, [2]int{0, 0xF}, f)
+			t.pairs[i][f] = d
+			values[d] = make([]int, d.size())
+			classes[d] = make([]int, d.size())
+			for j := range values[d] {
+				values[d][j] = -1
+			}
+		}
+	}
+
+	var conflict error
+	var place func(pos *Position, k int)
+	place = func(pos *Position, k int) {
+		if k == len(spec.pieces) {
+			for turn := 0; turn < 2; turn++ {
+				pos.Turn = turn
+				v, ok := spec.value(pos)
+				if !ok {
+					continue
+				}
+				d, idx, _, stored := t.encode(e, pos)
+				if !stored {
+					continue
+				}
+				vals := values[d]
+				if vals[idx] >= 0 && vals[idx] != v && conflict == nil {
+					conflict = fmt.Errorf("index %d holds %d and %d", idx, vals[idx], v)
+				}
+				vals[idx] = v
+				if spec.class != nil {
+					classes[d][idx] = spec.class(pos)
+				}
+			}
+			return
+		}
+		for sq := 0; sq < 64; sq++ {
+			if pos.Board[sq] == NoPiece {
+				pos.Board[sq] = spec.pieces[k]
+				place(pos, k+1)
+				pos.Board[sq] = NoPiece
+			}
+		}
+	}
+	place(&Position{}, 0)
+	if conflict != nil {
+		return nil, conflict
+	}
+
+	magic := wdlMagic
+	if spec.dtz {
+		magic = dtzMagic
+	}
+	out := append([]byte{}, magic[:]...)
+	var flags byte
+	if t.sides == 2 {
+		flags |= 1
+	}
+	if e.hasPawns {
+		flags |= 2
+	}
+	out = append(out, flags)
+	for f := 0; f < files; f++ {
+		out = append(out, 0) // both sides encode the groups in natural order
+		for k := range spec.pieces {
+			b := byte(t.pairs[0][f].pieces[k])
+			if t.sides == 2 {
+				b |= byte(t.pairs[1][f].pieces[k]) << 4
+			}
+			out = append(out, b)
+		}
+	}
+	out = append(out, make([]byte, len(out)&1)...)
+
+	// Mapped DTZ tables store indices into a sorted map of the values
+	// found in each class, four maps per pawn file.
+	var maps []byte
+	if spec.flags&flagMapped != 0 {
+		for f := 0; f < files; f++ {
+			d := t.pairs[0][f]
+			var byClass [4][]int
+			for idx, v := range values[d] {
+				if v >= 0 {
+					byClass[classes[d][idx]] = append(byClass[classes[d][idx]], v)
+				}
+			}
+			var index [4]map[int]int
+			for c := range byClass {
+				sort.Ints(byClass[c])
+				byClass[c] = slices.Compact(byClass[c])
+				index[c] = make(map[int]int)
+				maps = append(maps, byte(len(byClass[c])))
+				for i, v := range byClass[c] {
+					if v > 0xFF {
+						return nil, fmt.Errorf("value %d needs a wide map", v)
+					}
+					index[c][v] = i
+					maps = append(maps, byte(v))
+				}
+			}
+			for idx, v := range values[d] {
+				if v >= 0 {
+					values[d][idx] = index[classes[d][idx]][v]
+				}
+			}
+		}
+	}
+
+	var parts []compressed
+	for f := 0; f < files; f++ {
+		for i := 0; i < t.sides; i++ {
+			d := t.pairs[i][f]
+			c, err := compress(values[d], d.flags)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, c)
+		}
+	}
+	for _, p := range parts {
+		out = append(out, p.header...)
+	}
+	if spec.dtz {
+		out = append(out, maps...)
+		out = append(out, make([]byte, len(out)&1)...)
+	}
+	for _, p := range parts {
+		out = append(out, p.sparse...)
+	}
+	for _, p := range parts {
+		out = append(out, p.blockLength...)
+	}
+	for _, p := range parts {
+		out = append(out, make([]byte, (64-len(out)%64)%64)...)
+		out = append(out, p.data...)
+	}
+	return out, nil
+}
+
+type compressed struct {
+	header, sparse, blockLength, data []byte
+}
+
+// Compression parameters: 32-byte blocks, a sparse index entry per 1024
+// values, and pair symbols expanding to at most 128 values so that a block
+// never holds more values than its 16-bit length can count.
+const (
+	blockExp      = 5
+	spanExp       = 10
+	maxSymbols    = 4095
+	maxExpansion  = 128
+	minPairCount  = 4
+	maxCodeLength = 32
+)
+
+// symbol is a dictionary entry: a leaf holding a value, or a pair of two
+// earlier symbols.
+type symbol struct {
+	value, left, right int // right < 0 for a leaf
+	length             int // expanded length
+}
+
+// compress stores values the way the Syzygy generator does: recursive
+// pairing (the most frequent adjacent pair becomes a new symbol, over and
+// over) followed by a canonical Huffman code over the symbols. Unset entries
+// are "don't care" and repeat their neighbour, which lets them join runs.
+func compress(values []int, flags uint8) (compressed, error) {
+	seq := make([]int, len(values))
+	fill := -1
+	for _, v := range values {
+		if v >= 0 {
+			fill = v
+			break
+		}
+	}
+	var leaves []int
+	seen := make(map[int]bool)
+	for i, v := range values {
+		if v < 0 {
+			v = fill
+		}
+		fill = v
+		seq[i] = v
+		if !seen[v] {
+			seen[v] = true
+			leaves = append(leaves, v)
+		}
+	}
+	if len(leaves) <= 1 {
+		return compressed{header: []byte{flags | flagSingleValue, byte(max(fill, 0))}}, nil
+	}
+	sort.Ints(leaves)
+	var syms []symbol
+	leafSym := make(map[int]int)
+	for _, v := range leaves {
+		leafSym[v] = len(syms)
+		syms = append(syms, symbol{value: v, right: -1, length: 1})
+	}
+	for i, v := range seq {
+		seq[i] = leafSym[v]
+	}
+	syms, seq = pairSymbols(syms, seq)
+
+	lengths, err := huffmanLengths(syms, seq)
+	if err != nil {
+		return compressed{}, err
+	}
+
+	// Canonical order: longest codes first, so the prober can number the
+	// symbols of each length from lowestSym.
+	order := make([]int, len(syms))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return lengths[order[a]] > lengths[order[b]] })
+	renum := make([]int, len(syms))
+	for i, s := range order {
+		renum[s] = i
+	}
+	minLen, maxLen := lengths[order[len(order)-1]], lengths[order[0]]
+	count := make([]int, maxLen+2)
+	for _, l := range lengths {
+		count[l]++
+	}
+	offset := make([]int, maxLen+2)
+	base := make([]uint64, maxLen+2)
+	for l := maxLen - 1; l >= minLen; l-- {
+		offset[l] = offset[l+1] + count[l+1]
+		if (base[l+1]+uint64(count[l+1]))%2 != 0 {
+			return compressed{}, fmt.Errorf("code lengths do not form a complete prefix code")
+		}
+		base[l] = (base[l+1] + uint64(count[l+1])) / 2
+	}
+	code := func(s int) (uint64, int) {
+		l := lengths[s]
+		return base[l] + uint64(renum[s]-offset[l]), l
+	}
+
+	// Pack whole symbols into blocks, remembering where each block starts.
+	const blockBits = 8 << blockExp
+	var c compressed
+	var starts []int
+	bit, pos := blockBits, 0
+	for _, s := range seq {
+		v, l := code(s)
+		if bit+l > blockBits {
+			starts = append(starts, pos)
+			c.data = append(c.data, make([]byte, blockBits/8)...)
+			bit = 0
+		}
+		at := (len(starts)-1)*blockBits + bit
+		for j := l - 1; j >= 0; j-- {
+			if v&(1<<j) != 0 {
+				c.data[at/8] |= 0x80 >> (at % 8)
+			}
+			at++
+		}
+		bit += l
+		pos += syms[s].length
+	}
+	numBlocks := len(starts)
+	starts = append(starts, pos)
+
+	// Each sparse entry locates the middle value of its span. Spans past the
+	// end point into padding blocks, which only serve to walk back from.
+	const span, padLength = 1 << spanExp, 1 << 16
+	blockLengthSize := numBlocks
+	for k := 0; k*span < len(values); k++ {
+		mid := k*span + span/2
+		var block, off int
+		if mid < pos {
+			block = sort.SearchInts(starts, mid+1) - 1
+			off = mid - starts[block]
+		} else {
+			block = numBlocks + (mid-pos)/padLength
+			off = (mid - pos) % padLength
+			blockLengthSize = max(blockLengthSize, block+1)
+		}
+		c.sparse = binary.LittleEndian.AppendUint32(c.sparse, uint32(block))
+		c.sparse = binary.LittleEndian.AppendUint16(c.sparse, uint16(off))
+	}
+	for b := 0; b < blockLengthSize; b++ {
+		n := padLength
+		if b < numBlocks {
+			n = starts[b+1] - starts[b]
+		}
+		c.blockLength = binary.LittleEndian.AppendUint16(c.blockLength, uint16(n-1))
+	}
+
+	h := []byte{flags, blockExp, spanExp, byte(blockLengthSize - numBlocks)}
+	h = binary.LittleEndian.AppendUint32(h, uint32(numBlocks))
+	h = append(h, byte(maxLen), byte(minLen))
+	for l := minLen; l <= maxLen; l++ {
+		h = binary.LittleEndian.AppendUint16(h, uint16(offset[l]))
+	}
+	h = binary.LittleEndian.AppendUint16(h, uint16(len(syms)))
+	for _, s := range order {
+		left, right := syms[s].value, 0xFFF
+		if syms[s].right >= 0 {
+			left, right = renum[syms[s].left], renum[syms[s].right]
+		}
+		h = append(h, byte(left), byte(left>>8)&0xF|byte(right<<4), byte(right>>4))
+	}
+	h = append(h, make([]byte, len(syms)&1)...)
+	c.header = h
+	return c, nil
+}
+
+// pairSymbols replaces the most frequent adjacent pair by a new symbol until
+// no pair is common enough or the dictionary is full.
+func pairSymbols(syms []symbol, seq []int) ([]symbol, []int) {
+	for len(syms) < maxSymbols {
+		counts := make(map[[2]int]int)
+		overlap := false
+		for i := 1; i < len(seq); i++ {
+			// Runs like "aaa" hold one replaceable pair, not two.
+			if overlap && seq[i] == seq[i-1] && seq[i-1] == seq[i-2] {
+				overlap = false
+				continue
+			}
+			counts[[2]int{seq[i-1], seq[i]}]++
+			overlap = true
+		}
+		best, bestCount := [2]int{}, 0
+		for p, n := range counts {
+			if syms[p[0]].length+syms[p[1]].length > maxExpansion {
+				continue
+			}
+			if n > bestCount || (n == bestCount && (p[0] < best[0] || p[0] == best[0] && p[1] < best[1])) {
+				best, bestCount = p, n
+			}
+		}
+		if bestCount < minPairCount {
+			break
+		}
+		s := len(syms)
+		syms = append(syms, symbol{left: best[0], right: best[1], length: syms[best[0]].length + syms[best[1]].length})
+		out := seq[:0]
+		for i := 0; i < len(seq); i++ {
+			if i+1 < len(seq) && seq[i] == best[0] && seq[i+1] == best[1] {
+				out = append(out, s)
+				i++
+				continue
+			}
+			out = append(out, seq[i])
+		}
+		seq = out
+	}
+	return syms, seq
+}
+
+// huffmanLengths returns a code length per symbol. Symbols that only occur
+// inside pairs still need a code, so every weight is one more than the
+// symbol's count in the stream.
+func huffmanLengths(syms []symbol, seq []int) ([]int, error) {
+	weight := make([]int, len(syms))
+	for i := range weight {
+		weight[i] = 1
+	}
+	for _, s := range seq {
+		weight[s]++
+	}
+	h := &huffmanHeap{weight: weight}
+	for i := range syms {
+		h.nodes = append(h.nodes, i)
+	}
+	heap.Init(h)
+	var children [][2]int
+	for h.Len() > 1 {
+		a, b := heap.Pop(h).(int), heap.Pop(h).(int)
+		h.weight = append(h.weight, h.weight[a]+h.weight[b])
+		children = append(children, [2]int{a, b})
+		heap.Push(h, len(h.weight)-1)
+	}
+
+	lengths := make([]int, len(syms))
+	var walk func(n, depth int)
+	walk = func(n, depth int) {
+		if n < len(syms) {
+			lengths[n] = depth
+			return
+		}
+		walk(children[n-len(syms)][0], depth+1)
+		walk(children[n-len(syms)][1], depth+1)
+	}
+	walk(h.nodes[0], 0)
+	for _, l := range lengths {
+		if l > maxCodeLength {
+			return nil, fmt.Errorf("code length %d exceeds %d bits", l, maxCodeLength)
+		}
+	}
+	return lengths, nil
+}
+
+// huffmanHeap orders tree nodes by weight, then by id for a stable result.
+type huffmanHeap struct {
+	nodes  []int
+	weight []int
+}
+
+func (h *huffmanHeap) Len() int { return len(h.nodes) }
+func (h *huffmanHeap) Less(i, j int) bool {
+	a, b := h.nodes[i], h.nodes[j]
+	return h.weight[a] < h.weight[b] || h.weight[a] == h.weight[b] && a < b
+}
+func (h *huffmanHeap) Swap(i, j int) { h.nodes[i], h.nodes[j] = h.nodes[j], h.nodes[i] }
+func (h *huffmanHeap) Push(x any)    { h.nodes = append(h.nodes, x.(int)) }
+func (h *huffmanHeap) Pop() any {
+	n := h.nodes[len(h.nodes)-1]
+	h.nodes = h.nodes[:len(h.nodes)-1]
+	return n
+}
+
+// squaresOf returns the squares of the given pieces in order.
+func squaresOf(pos *Position, want ...Piece) []int {
+	out := make([]int, len(want))
+	for i, p := range want {
+		out[i] = -1
+		for sq, q := range pos.Board {
+			if q == p {
+				out[i] = sq
+			}
+		}
+	}
+	return out
+}
+
+func kpkValue(pos *Position) (int, bool) {
+	sq := squaresOf(pos, WPawn, WKing, BKing)
+	psq, wk, bk := sq[0], sq[1], sq[2]
+	if rankOf(psq) == 0 || rankOf(psq) == 7 || kingsTouch(wk, bk) {
+		return 0, false
+	}
+	if pos.Turn == 0 && rankOf(bk) == rankOf(psq)+1 && abs(fileOf(bk)-fileOf(psq)) == 1 {
+		return 0, false // Black in check with White to move
+	}
+	wdl := Draw
+	if endgame.ProbeKPK(0, wk, psq, bk, pos.Turn) {
+		wdl = Win
+		if pos.Turn == 1 {
+			wdl = Loss
+		}
+	}
+	return int(wdl) + 2, true
+}
+
+// kpkTable holds, per side to move, the distance in plies to the winning
+// pawn move of every decisive KPvK position, indexed by kpkIndex; zero
+// marks draws and illegal positions.
+type kpkTable [2][64 * 64 * 64]int
+
+func kpkIndex(psq, wk, bk int) int { return psq<<12 | wk<<6 | bk }
+
+func (k *kpkTable) lookup(pos *Position) (int, bool) {
+	if _, ok := kpkValue(pos); !ok {
+		return 0, false
+	}
+	sq := squaresOf(pos, WPawn, WKing, BKing)
+	return k[pos.Turn][kpkIndex(sq[0], sq[1], sq[2])], true
+}
+
+// queenSees reports whether a queen on from attacks to, with blocker in the way.
+func queenSees(from, to, blocker int) bool {
+	dr, df := rankOf(to)-rankOf(from), fileOf(to)-fileOf(from)
+	if from == to || (dr != 0 && df != 0 && abs(dr) != abs(df)) {
+		return false
+	}
+	step := sign(dr)*8 + sign(df)
+	for sq := from + step; sq != to; sq += step {
+		if sq == blocker {
+			return false
+		}
+	}
+	return true
+}
+
+// promotionWins reports whether promoting on sq wins with Black to move:
+// a queen wins unless it falls at once or Black is stalemated, and a rook
+// is looked up in the solved KRvK table. Minor pieces only draw.
+func promotionWins(sq, wk, bk int, krk *krkTable) bool {
+	if r := krk[1][krkIndex(wk, sq, bk)]; r.legal && r.wdl == Loss {
+		return true
+	}
+	moves := 0
+	for _, to := range kingTargets(bk) {
+		switch {
+		case kingsTouch(to, wk):
+		case to == sq:
+			return false
+		case !queenSees(sq, to, wk):
+			moves++
+		}
+	}
+	return moves > 0 || queenSees(sq, bk, wk)
+}
+
+// solveKPvK computes DTZ for every KPvK position. The win/draw split comes
+// from the KPK bitbase; distances grow outward from the positions where a
+// pawn move keeps the win, with White taking the shortest way there and
+// Black the longest.
+func solveKPvK(krk *krkTable) *kpkTable {
+	k := new(kpkTable)
+	type state struct{ psq, wk, bk int }
+	var white, black []state
+	for psq := 8; psq < 56; psq++ {
+		for wk := 0; wk < 64; wk++ {
+			for bk := 0; bk < 64; bk++ {
+				if wk == psq || bk == psq || wk == bk || kingsTouch(wk, bk) {
+					continue
+				}
+				s := state{psq, wk, bk}
+				checked := rankOf(bk) == rankOf(psq)+1 && abs(fileOf(bk)-fileOf(psq)) == 1
+				if !checked && endgame.ProbeKPK(0, wk, psq, bk, 0) {
+					white = append(white, s)
+				}
+				if endgame.ProbeKPK(0, wk, psq, bk, 1) {
+					black = append(black, s)
+				}
+			}
+		}
+	}
+
+	pawnWins := func(s state) bool {
+		for _, to := range []int{s.psq + 8, s.psq + 16} {
+			if to == s.wk || to == s.bk || (to == s.psq+16 && rankOf(s.psq) != 1) {
+				break
+			}
+			if rankOf(to) == 7 {
+				if promotionWins(to, s.wk, s.bk, krk) {
+					return true
+				}
+				break
+			}
+			if endgame.ProbeKPK(0, s.wk, to, s.bk, 1) {
+				return true
+			}
+		}
+		return false
+	}
+	blackMoves := func(s state) []state {
+		var moves []state
+		for _, to := range kingTargets(s.bk) {
+			attacked := rankOf(to) == rankOf(s.psq)+1 && abs(fileOf(to)-fileOf(s.psq)) == 1
+			if !kingsTouch(to, s.wk) && to != s.psq && !attacked {
+				moves = append(moves, state{s.psq, s.wk, to})
+			}
+		}
+		return moves
+	}
+
+	for _, s := range white {
+		if pawnWins(s) {
+			k[0][kpkIndex(s.psq, s.wk, s.bk)] = 1
+		}
+	}
+	for ply, idle := 2, 0; idle < 2; ply++ {
+		idle++
+		if ply%2 == 0 {
+			for _, s := range black {
+				idx := kpkIndex(s.psq, s.wk, s.bk)
+				if k[1][idx] != 0 {
+					continue
+				}
+				longest := 0
+				for _, m := range blackMoves(s) {
+					d := k[0][kpkIndex(m.psq, m.wk, m.bk)]
+					if d == 0 {
+						longest = -1
+						break
+					}
+					longest = max(longest, d)
+				}
+				if longest == ply-1 {
+					k[1][idx] = ply
+					idle = 0
+				}
+			}
+			continue
+		}
+		for _, s := range white {
+			idx := kpkIndex(s.psq, s.wk, s.bk)
+			if k[0][idx] != 0 {
+				continue
+			}
+			for _, to := range kingTargets(s.wk) {
+				if to != s.psq && !kingsTouch(to, s.bk) && k[1][kpkIndex(s.psq, to, s.bk)] == ply-1 {
+					k[0][idx] = ply
+					idle = 0
+					break
+				}
+			}
+		}
+	}
+	return k
+}
+
+// krkResult is the solved value of a KRvK position: its WDL for the side to
+// move and, for decisive positions, the distance to mate in plies.
+type krkResult struct {
+	wdl   WDL
+	plies int
+	legal bool
+}
+
+type krkTable [2][64 * 64 * 64]krkResult
+
+func krkIndex(wk, wr, bk int) int { return wk<<12 | wr<<6 | bk }
+
+func (k *krkTable) lookup(pos *Position) (krkResult, bool) {
+	sq := squaresOf(pos, WKing, WRook, BKing)
+	r := k[pos.Turn][krkIndex(sq[0], sq[1], sq[2])]
+	return r, r.legal
+}
+
+// rookSees reports whether a rook on from attacks to, with blocker in the way.
+func rookSees(from, to, blocker int) bool {
+	if from == to || (rankOf(from) != rankOf(to) && fileOf(from) != fileOf(to)) {
+		return false
+	}
+	dr, df := sign(rankOf(to)-rankOf(from)), sign(fileOf(to)-fileOf(from))
+	for sq := from + dr*8 + df; sq != to; sq += dr*8 + df {
+		if sq == blocker {
+			return false
+		}
+	}
+	return true
+}
+
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+func kingTargets(sq int) []int {
+	var out []int
+	for to := 0; to < 64; to++ {
+		if to != sq && kingsTouch(sq, to) {
+			out = append(out, to)
+		}
+	}
+	return out
+}
+
+// solveKRvK computes distance to mate for every KRvK position by iterating
+// ply by ply from the mates outward.
+func solveKRvK() *krkTable {
+	k := new(krkTable)
+	type state struct{ wk, wr, bk int }
+	var all []state
+	for wk := 0; wk < 64; wk++ {
+		for wr := 0; wr < 64; wr++ {
+			for bk := 0; bk < 64; bk++ {
+				if wk == wr || wr == bk || wk == bk || kingsTouch(wk, bk) {
+					continue
+				}
+				all = append(all, state{wk, wr, bk})
+				idx := krkIndex(wk, wr, bk)
+				k[1][idx].legal = true
+				k[1][idx].wdl = Draw
+				if !rookSees(wr, bk, wk) {
+					k[0][idx].legal = true
+					k[0][idx].wdl = Win
+				}
+			}
+		}
+	}
+
+	blackMoves := func(s state) (moves []state, capture bool) {
+		for _, to := range kingTargets(s.bk) {
+			if kingsTouch(to, s.wk) {
+				continue
+			}
+			if to == s.wr {
+				capture = true
+				continue
+			}
+			if !rookSees(s.wr, to, s.wk) {
+				moves = append(moves, state{s.wk, s.wr, to})
+			}
+		}
+		return moves, capture
+	}
+	whiteMoves := func(s state) []state {
+		var moves []state
+		for _, to := range kingTargets(s.wk) {
+			if to != s.wr && !kingsTouch(to, s.bk) {
+				moves = append(moves, state{to, s.wr, s.bk})
+			}
+		}
+		for to := 0; to < 64; to++ {
+			if to != s.wk && to != s.bk && rookSees(s.wr, to, s.wk) && rookSees(s.wr, to, s.bk) {
+				moves = append(moves, state{s.wk, to, s.bk})
+			}
+		}
+		return moves
+	}
+
+	// Black to move: mate is a loss in 0 plies.
+	resolved := make([]bool, 64*64*64)
+	for _, s := range all {
+		idx := krkIndex(s.wk, s.wr, s.bk)
+		moves, capture := blackMoves(s)
+		if len(moves) == 0 && !capture && rookSees(s.wr, s.bk, s.wk) {
+			k[1][idx] = krkResult{wdl: Loss, plies: 0, legal: true}
+			resolved[idx] = true
+		}
+	}
+	won := make([]bool, 64*64*64)
+	for ply := 1; ; ply++ {
+		changed := false
+		if ply%2 == 1 {
+			for _, s := range all {
+				idx := krkIndex(s.wk, s.wr, s.bk)
+				if !k[0][idx].legal || won[idx] {
+					continue
+				}
+				for _, m := range whiteMoves(s) {
+					if r := k[1][krkIndex(m.wk, m.wr, m.bk)]; resolved[krkIndex(m.wk, m.wr, m.bk)] && r.plies == ply-1 {
+						k[0][idx].plies = ply
+						won[idx] = true
+						changed = true
+						break
+					}
+				}
+			}
+		} else {
+			for _, s := range all {
+				idx := krkIndex(s.wk, s.wr, s.bk)
+				if resolved[idx] {
+					continue
+				}
+				moves, capture := blackMoves(s)
+				if capture || len(moves) == 0 {
+					continue // the rook falls, or stalemate
+				}
+				lost, longest := true, 0
+				for _, m := range moves {
+					mi := krkIndex(m.wk, m.wr, m.bk)
+					if !won[mi] {
+						lost = false
+						break
+					}
+					longest = max(longest, k[0][mi].plies)
+				}
+				if lost {
+					k[1][idx] = krkResult{wdl: Loss, plies: longest + 1, legal: true}
+					resolved[idx] = true
+					changed = true
+				}
+			}
+		}
+		if !changed && ply%2 == 0 {
+			break
+		}
+	}
+	return k
+}
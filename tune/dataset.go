@@ -0,0 +1,129 @@
+// --- tune/dataset.go ---
+
+package tune
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/pgn"
+	"github.com/mesb/mchess/pieces"
+	"github.com/mesb/mchess/socrates"
+)
+
+// Position is one labelled training sample.
+type Position struct {
+	Board  *board.Board
+	State  *board.GameState
+	Result float64 // 1 = White won, 0.5 = draw, 0 = Black won
+}
+
+// LoadFile reads a dataset, choosing the parser from the file extension
+// (.pgn for games, anything else is treated as EPD).
+func LoadFile(path string, opts PGNOptions) ([]Position, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".pgn") {
+		return ReadPGN(f, opts)
+	}
+	return ReadEPD(f)
+}
+
+// ReadEPD parses one position per line: at least the four FEN board fields,
+// followed anywhere on the line by a result marker such as c9 "1-0";,
+// "1/2-1/2", [1.0], [0.5] or [0.0].
+func ReadEPD(r io.Reader) ([]Position, error) {
+	var out []Position
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("line %d: expected FEN fields", lineNo)
+		}
+		result, ok := parseResult(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: no game result", lineNo)
+		}
+		fen := strings.Join(fields[:4], " ") + " 0 1"
+		b, state, err := board.FromFEN(fen)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		out = append(out, Position{Board: b, State: state, Result: result})
+	}
+	return out, scanner.Err()
+}
+
+func parseResult(line string) (float64, bool) {
+	switch {
+	case strings.Contains(line, "1/2-1/2"), strings.Contains(line, "[0.5]"):
+		return 0.5, true
+	case strings.Contains(line, "1-0"), strings.Contains(line, "[1.0]"), strings.Contains(line, "[1]"):
+		return 1, true
+	case strings.Contains(line, "0-1"), strings.Contains(line, "[0.0]"), strings.Contains(line, "[0]"):
+		return 0, true
+	}
+	return 0, false
+}
+
+// PGNOptions controls which positions are sampled from games.
+type PGNOptions struct {
+	SkipPlies int // ignore the opening plies of every game
+}
+
+// ReadPGN replays every finished game and samples its quiet positions,
+// labelling each with the game's result. Positions in check or reached by
+// a capture or promotion are skipped since their static score is unreliable.
+func ReadPGN(r io.Reader, opts PGNOptions) ([]Position, error) {
+	var out []Position
+	engine := socrates.New(board.InitStandard())
+	rd := pgn.NewReader(r)
+	for i := 1; ; i++ {
+		game, err := rd.Read()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("game %d: %v", i, err)
+		}
+		result, ok := parseResult(game.Result)
+		if !ok {
+			continue // unfinished games carry no label
+		}
+		if err := game.Play(engine); err != nil {
+			return nil, fmt.Errorf("game %d: %v", i, err)
+		}
+		moves := engine.Log.Moves()
+		for ply := len(moves); ply > 0; ply-- {
+			last := moves[ply-1]
+			quiet := last.Target == nil && !isPromotion(last) && !engine.IsInCheck(engine.Turn)
+			if ply > opts.SkipPlies && quiet {
+				b, state, err := board.FromFEN(engine.Board.ToFEN(engine.State))
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, Position{Board: b, State: state, Result: result})
+			}
+			engine.UndoMove()
+		}
+	}
+}
+
+func isPromotion(m socrates.Move) bool {
+	_, isPawn := m.Piece.(*pieces.Pawn)
+	return isPawn && (m.To.Rank == 0 || m.To.Rank == 7)
+}
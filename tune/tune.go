@@ -0,0 +1,123 @@
+// --- tune/tune.go ---
+
+// Package tune fits the handcrafted evaluation parameters to game results
+// using Texel's method: it minimises the mean squared error between each
+// position's game result and the logistic of its static evaluation.
+package tune
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/mesb/mchess/socrates"
+)
+
+// Sigmoid maps a White-relative centipawn score to an expected game result.
+func Sigmoid(score, k float64) float64 {
+	return 1 / (1 + math.Pow(10, -k*score/400))
+}
+
+// Error returns the mean squared prediction error of the active evaluation.
+func Error(positions []Position, k float64) float64 {
+	if len(positions) == 0 {
+		return 0
+	}
+	workers := runtime.GOMAXPROCS(0)
+	chunk := (len(positions) + workers - 1) / workers
+	sums := make([]float64, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		lo := w * chunk
+		hi := min(lo+chunk, len(positions))
+		if lo >= hi {
+			break
+		}
+		wg.Add(1)
+		go func(w int, batch []Position) {
+			defer wg.Done()
+			for _, p := range batch {
+				score := float64(socrates.EvaluatePosition(p.Board, p.State))
+				diff := p.Result - Sigmoid(score, k)
+				sums[w] += diff * diff
+			}
+		}(w, positions[lo:hi])
+	}
+	wg.Wait()
+
+	total := 0.0
+	for _, s := range sums {
+		total += s
+	}
+	return total / float64(len(positions))
+}
+
+// FindK returns the scaling constant that best fits the current parameters,
+// found by ternary search over [0, 3].
+func FindK(positions []Position) float64 {
+	lo, hi := 0.0, 3.0
+	for i := 0; i < 40; i++ {
+		m1 := lo + (hi-lo)/3
+		m2 := hi - (hi-lo)/3
+		if Error(positions, m1) < Error(positions, m2) {
+			hi = m2
+		} else {
+			lo = m1
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// Options configures a tuning run.
+type Options struct {
+	K         float64 // logistic scale; 0 means fit it with FindK
+	MaxPasses int     // stop after this many passes over all parameters (0 = until converged)
+	Step      int     // increment tried for each parameter (default 1)
+
+	// Progress, if set, is called after every pass with the best parameters so far.
+	Progress func(pass int, err float64, best socrates.EvalParams)
+}
+
+// Tune runs Texel local search from start and returns the best parameters
+// found with their error. The engine's active parameters are restored on return.
+func Tune(positions []Position, start socrates.EvalParams, opts Options) (socrates.EvalParams, float64) {
+	saved := socrates.CurrentEvalParams()
+	defer socrates.SetEvalParams(saved)
+
+	step := opts.Step
+	if step <= 0 {
+		step = 1
+	}
+
+	best := start
+	socrates.SetEvalParams(best)
+	k := opts.K
+	if k == 0 {
+		k = FindK(positions)
+	}
+	bestErr := Error(positions, k)
+
+	for pass := 1; opts.MaxPasses == 0 || pass <= opts.MaxPasses; pass++ {
+		improved := false
+		for i := range best.Fields() {
+			for _, delta := range []int{step, -step} {
+				trial := best
+				*trial.Fields()[i] += delta
+				socrates.SetEvalParams(trial)
+				if e := Error(positions, k); e < bestErr {
+					best, bestErr = trial, e
+					improved = true
+					break
+				}
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(pass, bestErr, best)
+		}
+		if !improved {
+			break
+		}
+	}
+	return best, bestErr
+}
@@ -0,0 +1,93 @@
+package tune
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mesb/mchess/socrates"
+)
+
+const sampleEPD = `
+# White is a knight up and won; Black is a rook up and won; level and drawn.
+4k3/pp6/8/8/8/8/PP6/1N2K3 w - - c9 "1-0";
+4k3/pp6/8/8/8/8/PP6/1N2K3 b - - [1.0]
+r3k3/pp6/8/8/8/8/PP6/4K3 w - - c9 "0-1";
+4k3/pp6/8/8/8/8/PP6/4K3 w - - c9 "1/2-1/2";
+`
+
+func TestReadEPD(t *testing.T) {
+	positions, err := ReadEPD(strings.NewReader(sampleEPD))
+	if err != nil {
+		t.Fatalf("ReadEPD: %v", err)
+	}
+	if len(positions) != 4 {
+		t.Fatalf("expected 4 positions, got %d", len(positions))
+	}
+	want := []float64{1, 1, 0, 0.5}
+	for i, p := range positions {
+		if p.Result != want[i] {
+			t.Fatalf("position %d: result %v, want %v", i, p.Result, want[i])
+		}
+	}
+	if _, err := ReadEPD(strings.NewReader("4k3/8/8/8/8/8/8/4K3 w - -\n")); err == nil {
+		t.Fatal("expected error for missing result")
+	}
+}
+
+func TestReadPGNSamplesQuietPositions(t *testing.T) {
+	games := `[Event "A"]
+[Result "1-0"]
+
+1. e2e4 e7e5 2. g1f3 b8c6 1-0
+
+[Event "B"]
+[Result "*"]
+
+1. d2d4 *
+`
+	positions, err := ReadPGN(strings.NewReader(games), PGNOptions{SkipPlies: 1})
+	if err != nil {
+		t.Fatalf("ReadPGN: %v", err)
+	}
+	// Plies 2..4 of the finished game; the unfinished game is ignored.
+	if len(positions) != 3 {
+		t.Fatalf("expected 3 positions, got %d", len(positions))
+	}
+	for _, p := range positions {
+		if p.Result != 1 {
+			t.Fatalf("expected White win label, got %v", p.Result)
+		}
+	}
+}
+
+func TestTuneReducesError(t *testing.T) {
+	positions, err := ReadEPD(strings.NewReader(sampleEPD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := socrates.DefaultEvalParams()
+	start.Knight = 50 // badly undervalued
+	socrates.SetEvalParams(start)
+	before := Error(positions, 1)
+	socrates.SetEvalParams(socrates.DefaultEvalParams())
+
+	best, after := Tune(positions, start, Options{K: 1, MaxPasses: 3, Step: 20})
+	if after >= before {
+		t.Fatalf("tuning did not improve error: %.6f -> %.6f", before, after)
+	}
+	if best.Knight <= start.Knight {
+		t.Fatalf("expected knight value to rise, got %d", best.Knight)
+	}
+	if socrates.CurrentEvalParams().Knight != socrates.ValueKnight {
+		t.Fatal("Tune must restore the active parameters")
+	}
+}
+
+func TestSigmoid(t *testing.T) {
+	if Sigmoid(0, 1) != 0.5 {
+		t.Fatal("sigmoid(0) should be 0.5")
+	}
+	if Sigmoid(400, 1) <= 0.9 || Sigmoid(-400, 1) >= 0.1 {
+		t.Fatal("sigmoid should saturate towards the winner")
+	}
+}
@@ -0,0 +1,417 @@
+// --- uci/client/client.go ---
+
+// Package client drives external UCI engines: it runs an engine binary as
+// a subprocess, talks the protocol on its pipes, and turns its replies
+// into typed values. Engines that hang are stopped and, if need be,
+// killed; engines that crash are reported as such.
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mesb/mchess/notation"
+	"github.com/mesb/mchess/socrates"
+)
+
+// DefaultTimeout bounds how long an engine may take to answer uci,
+// isready and quit, and how far it may overrun the time it was given.
+const DefaultTimeout = 10 * time.Second
+
+var (
+	// ErrTimeout reports an engine that did not answer in time.
+	ErrTimeout = errors.New("engine did not answer in time")
+	// ErrExited reports an engine that quit or crashed while in use.
+	ErrExited = errors.New("engine exited")
+)
+
+// Option is an option the engine advertised in reply to uci.
+type Option struct {
+	Name     string
+	Type     string // check, spin, combo, button or string
+	Default  string
+	Min, Max int
+	Vars     []string
+}
+
+// Engine is a running UCI engine.
+type Engine struct {
+	Name, Author string
+	Options      []Option
+	// Timeout replaces DefaultTimeout for the commands that follow.
+	Timeout time.Duration
+
+	path   string
+	cmd    *exec.Cmd
+	mu     sync.Mutex // serializes writes to stdin
+	stdin  io.WriteCloser
+	lines  chan string   // stdout, closed when it ends
+	exited chan struct{} // closed once the process is reaped
+	err    error         // why the process ended, once exited is closed
+	stderr tail
+}
+
+// Start runs the engine at path with args and completes the uci handshake.
+func Start(path string, args ...string) (*Engine, error) {
+	e := &Engine{
+		Timeout: DefaultTimeout,
+		path:    path,
+		cmd:     exec.Command(path, args...),
+		lines:   make(chan string, 256),
+		exited:  make(chan struct{}),
+	}
+	e.cmd.Stderr = &e.stderr
+	stdin, err := e.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := e.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	e.stdin = stdin
+	if err := e.cmd.Start(); err != nil {
+		return nil, err
+	}
+	go e.read(stdout)
+
+	if err := e.handshake(); err != nil {
+		e.kill()
+		return nil, err
+	}
+	return e, nil
+}
+
+// read forwards stdout line by line, then reaps the process.
+func (e *Engine) read(stdout io.Reader) {
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		e.lines <- sc.Text()
+	}
+	close(e.lines)
+	e.err = e.cmd.Wait()
+	close(e.exited)
+}
+
+func (e *Engine) handshake() error {
+	if err := e.send("uci"); err != nil {
+		return err
+	}
+	return e.await(time.Now().Add(e.Timeout), func(line string) bool {
+		switch cmd, rest, _ := strings.Cut(line, " "); cmd {
+		case "id":
+			switch key, value, _ := strings.Cut(rest, " "); key {
+			case "name":
+				e.Name = value
+			case "author":
+				e.Author = value
+			}
+		case "option":
+			e.Options = append(e.Options, parseOption(rest))
+		case "uciok":
+			return true
+		}
+		return false
+	})
+}
+
+// parseOption reads "name Hash type spin default 16 min 1 max 1024".
+func parseOption(line string) Option {
+	var o Option
+	fields := strings.Fields(line)
+	key, start := "", 0
+	flush := func(end int) {
+		value := strings.Join(fields[start:end], " ")
+		switch key {
+		case "name":
+			o.Name = value
+		case "type":
+			o.Type = value
+		case "default":
+			o.Default = value
+		case "min":
+			o.Min, _ = strconv.Atoi(value)
+		case "max":
+			o.Max, _ = strconv.Atoi(value)
+		case "var":
+			o.Vars = append(o.Vars, value)
+		}
+	}
+	for i, f := range fields {
+		switch f {
+		case "name", "type", "default", "min", "max", "var":
+			// "name" words may not be keywords, so a name runs to "type".
+			if key == "name" && f != "type" {
+				continue
+			}
+			if key != "" {
+				flush(i)
+			}
+			key, start = f, i+1
+		}
+	}
+	if key != "" {
+		flush(len(fields))
+	}
+	return o
+}
+
+// Option returns the advertised option called name, ignoring case.
+func (e *Engine) Option(name string) (Option, bool) {
+	for _, o := range e.Options {
+		if strings.EqualFold(o.Name, name) {
+			return o, true
+		}
+	}
+	return Option{}, false
+}
+
+// SetOption sets an advertised option and waits until the engine is ready.
+func (e *Engine) SetOption(name, value string) error {
+	o, ok := e.Option(name)
+	if !ok {
+		return fmt.Errorf("%s has no option %q", e, name)
+	}
+	cmd := "setoption name " + o.Name
+	if o.Type != "button" {
+		cmd += " value " + value
+	}
+	if err := e.send(cmd); err != nil {
+		return err
+	}
+	return e.IsReady()
+}
+
+// IsReady waits for the engine to answer isready.
+func (e *Engine) IsReady() error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+	return e.await(time.Now().Add(e.Timeout), func(line string) bool { return line == "readyok" })
+}
+
+// NewGame tells the engine that the next position is from a new game.
+func (e *Engine) NewGame() error {
+	if err := e.send("ucinewgame"); err != nil {
+		return err
+	}
+	return e.IsReady()
+}
+
+// Position sets up fen, or the start position when fen is empty, and
+// plays moves in coordinate notation from there.
+func (e *Engine) Position(fen string, moves ...string) error {
+	cmd := "position startpos"
+	if fen != "" {
+		cmd = "position fen " + fen
+	}
+	if len(moves) > 0 {
+		cmd += " moves " + strings.Join(moves, " ")
+	}
+	return e.send(cmd)
+}
+
+// PositionOf sets up the game on eng: its first position and the moves
+// played since, so the engine sees the repetitions. eng is rewound and
+// replayed, and must not be in use meanwhile.
+func (e *Engine) PositionOf(eng *socrates.RuleEngine) error {
+	fen, moves := History(eng)
+	return e.Position(fen, moves...)
+}
+
+// History returns the FEN of the position eng started from and the moves
+// played since in coordinate notation. eng is rewound and replayed.
+func History(eng *socrates.RuleEngine) (fen string, moves []string) {
+	fen, played := notation.Played(eng)
+	moves = make([]string, len(played))
+	for i, m := range played {
+		moves[i] = m.String()
+	}
+	return fen, moves
+}
+
+// Go searches the position within l, passing every info line to onInfo
+// (which may be nil), and returns the engine's move. Stop, or cancelling
+// ctx, ends the search early; ctx's error is then returned with whatever
+// move the engine gave. An engine overrunning its time by Timeout is
+// stopped and reported with ErrTimeout; one that still does not answer is
+// killed.
+func (e *Engine) Go(ctx context.Context, l Limits, onInfo func(Info)) (Result, error) {
+	if err := e.send(l.command()); err != nil {
+		return Result{}, err
+	}
+	var deadline <-chan time.Time
+	if bound := l.timeBound(); bound > 0 && !l.Infinite {
+		t := time.NewTimer(bound + e.Timeout)
+		defer t.Stop()
+		deadline = t.C
+	}
+
+	var res Result
+	var last Info
+	var failed error
+	var grace <-chan time.Time // runs once stop was sent
+	done := ctx.Done()
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return res, e.exitError()
+			}
+			switch cmd, _, _ := strings.Cut(line, " "); cmd {
+			case "info":
+				info, err := ParseInfo(line)
+				if err != nil {
+					continue // a garbled line does not spoil the search
+				}
+				if len(info.PV) > 0 && info.MultiPV <= 1 {
+					last = info
+				}
+				if onInfo != nil {
+					onInfo(info)
+				}
+			case "bestmove":
+				var err error
+				if res, err = parseBestMove(line); err != nil {
+					return res, err
+				}
+				res.Info = last
+				return res, failed
+			}
+		case <-done:
+			done = nil
+			failed = ctx.Err()
+			grace = e.stopWithin(grace)
+		case <-deadline:
+			deadline = nil
+			failed = fmt.Errorf("%s: %w", e, ErrTimeout)
+			grace = e.stopWithin(grace)
+		case <-grace:
+			e.kill()
+			return res, fmt.Errorf("%s: %w", e, ErrTimeout)
+		}
+	}
+}
+
+// stopWithin sends stop, giving the engine Timeout to answer unless it was
+// already given a grace period.
+func (e *Engine) stopWithin(grace <-chan time.Time) <-chan time.Time {
+	e.send("stop")
+	if grace != nil {
+		return grace
+	}
+	return time.After(e.Timeout)
+}
+
+// Stop asks the engine to end the running search and answer at once.
+func (e *Engine) Stop() error {
+	return e.send("stop")
+}
+
+// Close quits the engine, killing it if it does not exit within Timeout.
+func (e *Engine) Close() error {
+	e.send("quit")
+	e.stdin.Close()
+	select {
+	case <-e.exited:
+		return e.err
+	case <-time.After(e.Timeout):
+		e.kill()
+		return fmt.Errorf("%s: %w", e, ErrTimeout)
+	}
+}
+
+func (e *Engine) String() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.path
+}
+
+// send writes one command.
+func (e *Engine) send(cmd string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := io.WriteString(e.stdin, cmd+"\n"); err != nil {
+		return e.exitError()
+	}
+	return nil
+}
+
+// await reads lines until done accepts one, the engine exits or the
+// deadline passes.
+func (e *Engine) await(deadline time.Time, done func(line string) bool) error {
+	t := time.NewTimer(time.Until(deadline))
+	defer t.Stop()
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return e.exitError()
+			}
+			if done(line) {
+				return nil
+			}
+		case <-t.C:
+			return fmt.Errorf("%s: %w", e, ErrTimeout)
+		}
+	}
+}
+
+// exitError describes how the engine ended, waiting briefly for the
+// process to be reaped.
+func (e *Engine) exitError() error {
+	select {
+	case <-e.exited:
+	case <-time.After(e.Timeout):
+		return fmt.Errorf("%s: %w", e, ErrExited)
+	}
+	msg := "exit status 0"
+	if e.err != nil {
+		msg = e.err.Error()
+	}
+	if s := e.stderr.String(); s != "" {
+		msg += ": " + s
+	}
+	return fmt.Errorf("%s: %w (%s)", e, ErrExited, msg)
+}
+
+func (e *Engine) kill() {
+	if e.cmd.Process != nil {
+		e.cmd.Process.Kill()
+	}
+	e.stdin.Close()
+}
+
+// tail keeps the end of the engine's stderr for crash reports.
+type tail struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+const tailSize = 512
+
+func (t *tail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > tailSize {
+		t.buf = t.buf[len(t.buf)-tailSize:]
+	}
+	return len(p), nil
+}
+
+func (t *tail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.TrimSpace(string(t.buf))
+}
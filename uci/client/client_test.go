@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/internal/enginetest"
+	"github.com/mesb/mchess/socrates"
+)
+
+// enginePath is the project's own engine, built once for the tests.
+var enginePath string
+
+func TestMain(m *testing.M) {
+	enginetest.Main(m, &enginePath)
+}
+
+func startFake(t *testing.T, mode string) *Engine {
+	t.Helper()
+	e, err := Start(enginetest.Fake(t, mode))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Timeout = 200 * time.Millisecond
+	return e
+}
+
+func startEngine(t *testing.T) *Engine {
+	t.Helper()
+	e, err := Start(enginePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestHandshakeAndOptions(t *testing.T) {
+	e := startEngine(t)
+	if e.Name != "MCHESS Dragon" || e.Author == "" {
+		t.Fatalf("id name %q author %q", e.Name, e.Author)
+	}
+	hash, ok := e.Option("hash")
+	if !ok || hash.Type != "spin" || hash.Min != 1 || hash.Max <= hash.Min {
+		t.Fatalf("Hash option: %+v", hash)
+	}
+	if clear, ok := e.Option("Clear Hash"); !ok || clear.Type != "button" {
+		t.Fatalf("Clear Hash option: %+v", clear)
+	}
+	if err := e.SetOption("Hash", "4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetOption("Clear Hash", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetOption("No Such Option", "1"); err == nil {
+		t.Fatal("an unknown option was accepted")
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestGoReportsAndAnswers(t *testing.T) {
+	e := startEngine(t)
+	if err := e.SetOption("OwnBook", "false"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.NewGame(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Position("", "e2e4", "e7e5"); err != nil {
+		t.Fatal(err)
+	}
+	var depths []int
+	res, err := e.Go(context.Background(), Limits{Depth: 3}, func(i Info) {
+		if i.Score != nil {
+			depths = append(depths, i.Depth)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(depths) == 0 || depths[len(depths)-1] != 3 {
+		t.Fatalf("info depths %v", depths)
+	}
+	if res.Info.Depth != 3 || len(res.Info.PV) == 0 || res.Info.PV[0] != res.Move {
+		t.Fatalf("result %+v does not lead with the last principal variation", res)
+	}
+	if _, _, _, err := socrates.ParseMove(res.Move); err != nil {
+		t.Fatalf("bestmove %q: %v", res.Move, err)
+	}
+}
+
+func TestPositionOfKeepsTheGame(t *testing.T) {
+	b, s, err := board.FromFEN("8/P6k/8/8/8/8/8/K7 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eng := socrates.New(b)
+	eng.SetPosition(b, s)
+	for _, mv := range []string{"a7a8n", "h7g6"} {
+		from, to, promo, _ := socrates.ParseMove(mv)
+		if !eng.MakeMove(*from, *to, promo) {
+			t.Fatalf("%s is illegal", mv)
+		}
+	}
+	before := eng.Board.ToFEN(eng.State)
+	fen, moves := History(eng)
+	if fen != "8/P6k/8/8/8/8/8/K7 w - - 0 1" || strings.Join(moves, " ") != "a7a8n h7g6" {
+		t.Fatalf("History = %q %q", fen, moves)
+	}
+	if after := eng.Board.ToFEN(eng.State); after != before {
+		t.Fatalf("History left %q, want %q", after, before)
+	}
+
+	e := startEngine(t)
+	if err := e.PositionOf(eng); err != nil {
+		t.Fatal(err)
+	}
+	res, err := e.Go(context.Background(), Limits{Depth: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(res.Move, "a8") && !strings.HasPrefix(res.Move, "a1") {
+		t.Fatalf("%q is not a move of White's knight or king", res.Move)
+	}
+}
+
+func TestStopAndCancel(t *testing.T) {
+	e := startEngine(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	res, err := e.Go(ctx, Limits{Infinite: true}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) || res.Move == "" {
+		t.Fatalf("cancelled search gave %+v, %v", res, err)
+	}
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		e.Stop()
+	}()
+	if res, err := e.Go(context.Background(), Limits{}, nil); err != nil || res.Move == "" {
+		t.Fatalf("stopped search gave %+v, %v", res, err)
+	}
+}
+
+func TestHangingEngineIsKilled(t *testing.T) {
+	e := startFake(t, "hang")
+	start := time.Now()
+	_, err := e.Go(context.Background(), Limits{MoveTime: 50 * time.Millisecond}, nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("got %v, want a timeout", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("the hanging engine was not given up on")
+	}
+	if _, err := e.Go(context.Background(), Limits{Depth: 1}, nil); !errors.Is(err, ErrExited) {
+		t.Fatalf("the killed engine answered: %v", err)
+	}
+}
+
+func TestCrashIsReported(t *testing.T) {
+	e := startFake(t, "crash")
+	_, err := e.Go(context.Background(), Limits{Depth: 1}, nil)
+	if !errors.Is(err, ErrExited) || !strings.Contains(err.Error(), "segmentation fault") {
+		t.Fatalf("got %v, want a crash with its stderr", err)
+	}
+	if err := e.IsReady(); !errors.Is(err, ErrExited) {
+		t.Fatalf("IsReady after the crash: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+// --- uci/client/info.go ---
+
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits bound a search, mirroring the parameters of go. The zero value
+// searches without limit until Stop.
+type Limits struct {
+	Depth, Nodes, Mate int
+	MoveTime           time.Duration
+	WTime, BTime       time.Duration
+	WInc, BInc         time.Duration
+	MovesToGo          int
+	Infinite           bool
+}
+
+// command renders the limits as a go command.
+func (l Limits) command() string {
+	args := []string{"go"}
+	add := func(name string, v int64) {
+		if v > 0 {
+			args = append(args, name, strconv.FormatInt(v, 10))
+		}
+	}
+	add("depth", int64(l.Depth))
+	add("nodes", int64(l.Nodes))
+	add("mate", int64(l.Mate))
+	add("movetime", l.MoveTime.Milliseconds())
+	add("wtime", l.WTime.Milliseconds())
+	add("btime", l.BTime.Milliseconds())
+	add("winc", l.WInc.Milliseconds())
+	add("binc", l.BInc.Milliseconds())
+	add("movestogo", int64(l.MovesToGo))
+	if l.Infinite || len(args) == 1 {
+		args = append(args, "infinite")
+	}
+	return strings.Join(args, " ")
+}
+
+// timeBound is the longest the search may take by its own limits, or zero
+// when only depth, nodes or Stop end it.
+func (l Limits) timeBound() time.Duration {
+	if l.MoveTime > 0 {
+		return l.MoveTime
+	}
+	return max(l.WTime, l.BTime)
+}
+
+// Score is an evaluation from the side to move: centipawns, or moves to
+// mate when Mate is not zero (negative when getting mated).
+type Score struct {
+	CP    int
+	Mate  int
+	Lower bool // the score is a lower bound
+	Upper bool // the score is an upper bound
+}
+
+func (s Score) String() string {
+	var str string
+	if s.Mate != 0 {
+		str = fmt.Sprintf("mate %d", s.Mate)
+	} else {
+		str = fmt.Sprintf("cp %d", s.CP)
+	}
+	switch {
+	case s.Lower:
+		str += " lowerbound"
+	case s.Upper:
+		str += " upperbound"
+	}
+	return str
+}
+
+// Info is one info line. Fields the engine did not send are zero; Score
+// is nil without a score.
+type Info struct {
+	Depth, SelDepth int
+	MultiPV         int
+	Score           *Score
+	Nodes, NPS      int64
+	TBHits          int64
+	Hashfull        int // permille
+	Time            time.Duration
+	PV              []string
+	CurrMove        string
+	CurrMoveNumber  int
+	String          string // free text from "info string"
+}
+
+// infoFields maps the keywords taking one number to where they go.
+var infoFields = map[string]func(*Info, int64){
+	"depth":          func(i *Info, v int64) { i.Depth = int(v) },
+	"seldepth":       func(i *Info, v int64) { i.SelDepth = int(v) },
+	"multipv":        func(i *Info, v int64) { i.MultiPV = int(v) },
+	"nodes":          func(i *Info, v int64) { i.Nodes = v },
+	"nps":            func(i *Info, v int64) { i.NPS = v },
+	"tbhits":         func(i *Info, v int64) { i.TBHits = v },
+	"hashfull":       func(i *Info, v int64) { i.Hashfull = int(v) },
+	"time":           func(i *Info, v int64) { i.Time = time.Duration(v) * time.Millisecond },
+	"currmovenumber": func(i *Info, v int64) { i.CurrMoveNumber = int(v) },
+	"cpuload":        func(*Info, int64) {},
+	"sbhits":         func(*Info, int64) {},
+}
+
+// ParseInfo reads an info line. Unknown keywords are skipped, as the
+// protocol asks.
+func ParseInfo(line string) (Info, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "info" {
+		return Info{}, fmt.Errorf("not an info line: %q", line)
+	}
+	var info Info
+	for i := 1; i < len(fields); i++ {
+		key := fields[i]
+		switch key {
+		case "string":
+			info.String = strings.Join(fields[i+1:], " ")
+			return info, nil
+		case "pv":
+			info.PV = append([]string(nil), fields[i+1:]...)
+			return info, nil
+		case "currmove":
+			if i+1 < len(fields) {
+				i++
+				info.CurrMove = fields[i]
+			}
+		case "score":
+			s, n, err := parseScore(fields[i+1:])
+			if err != nil {
+				return info, fmt.Errorf("info %q: %v", line, err)
+			}
+			info.Score = &s
+			i += n
+		default:
+			set, ok := infoFields[key]
+			if !ok || i+1 >= len(fields) {
+				continue
+			}
+			v, err := strconv.ParseInt(fields[i+1], 10, 64)
+			if err != nil {
+				return info, fmt.Errorf("info %q: %s needs a number, got %q", line, key, fields[i+1])
+			}
+			set(&info, v)
+			i++
+		}
+	}
+	return info, nil
+}
+
+// parseScore reads "cp 35", "mate -3" and their bounds, returning how many
+// fields it used.
+func parseScore(fields []string) (Score, int, error) {
+	var s Score
+	if len(fields) < 2 {
+		return s, len(fields), fmt.Errorf("score needs cp or mate and a value")
+	}
+	v, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return s, 2, fmt.Errorf("score %s needs a number, got %q", fields[0], fields[1])
+	}
+	switch fields[0] {
+	case "cp":
+		s.CP = v
+	case "mate":
+		s.Mate = v
+	default:
+		return s, 2, fmt.Errorf("unknown score type %q", fields[0])
+	}
+	n := 2
+	for ; n < len(fields); n++ {
+		switch fields[n] {
+		case "lowerbound":
+			s.Lower = true
+		case "upperbound":
+			s.Upper = true
+		default:
+			return s, n, nil
+		}
+	}
+	return s, n, nil
+}
+
+// Result is the answer to go.
+type Result struct {
+	Move   string // "0000" or "(none)" when there is no legal move
+	Ponder string // the expected reply; empty when not given
+	Info   Info   // the last info line with a principal variation
+}
+
+// parseBestMove reads "bestmove e2e4 [ponder e7e5]".
+func parseBestMove(line string) (Result, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "bestmove" {
+		return Result{}, fmt.Errorf("bad bestmove line %q", line)
+	}
+	res := Result{Move: fields[1]}
+	if len(fields) >= 4 && fields[2] == "ponder" {
+		res.Ponder = fields[3]
+	}
+	return res, nil
+}
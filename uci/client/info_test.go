@@ -0,0 +1,80 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		line string
+		want Info
+	}{
+		{
+			"info depth 12 seldepth 18 multipv 1 score cp -35 upperbound nodes 123456 nps 900000 hashfull 12 tbhits 0 time 137 pv e2e4 e7e5 g1f3",
+			Info{Depth: 12, SelDepth: 18, MultiPV: 1, Score: &Score{CP: -35, Upper: true}, Nodes: 123456, NPS: 900000, Hashfull: 12, Time: 137 * time.Millisecond, PV: []string{"e2e4", "e7e5", "g1f3"}},
+		},
+		{
+			"info depth 7 score mate -3 pv h7h8",
+			Info{Depth: 7, Score: &Score{Mate: -3}, PV: []string{"h7h8"}},
+		},
+		{
+			"info depth 20 currmove d2d4 currmovenumber 3",
+			Info{Depth: 20, CurrMove: "d2d4", CurrMoveNumber: 3},
+		},
+		{
+			"info string book move e2e4 depth 3",
+			Info{String: "book move e2e4 depth 3"},
+		},
+		{
+			"info depth 3 refutation e2e4 d7d5 wdl 400 500 100 nodes 9",
+			Info{Depth: 3, Nodes: 9},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ParseInfo(tt.line)
+		if err != nil {
+			t.Errorf("ParseInfo(%q): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseInfo(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"info depth deep", "info score cp", "bestmove e2e4"} {
+		if _, err := ParseInfo(bad); err == nil {
+			t.Errorf("ParseInfo(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestParseOption(t *testing.T) {
+	got := parseOption("name Move Overhead type spin default 10 min 0 max 5000")
+	want := Option{Name: "Move Overhead", Type: "spin", Default: "10", Max: 5000}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	got = parseOption("name Personality type combo default Solid var Solid var Wild Attacker")
+	want = Option{Name: "Personality", Type: "combo", Default: "Solid", Vars: []string{"Solid", "Wild Attacker"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestLimitsCommand(t *testing.T) {
+	tests := []struct {
+		l    Limits
+		want string
+	}{
+		{Limits{}, "go infinite"},
+		{Limits{Depth: 8}, "go depth 8"},
+		{Limits{WTime: time.Minute, BTime: 50 * time.Second, WInc: time.Second, BInc: time.Second, MovesToGo: 20}, "go wtime 60000 btime 50000 winc 1000 binc 1000 movestogo 20"},
+		{Limits{MoveTime: 250 * time.Millisecond, Nodes: 1000}, "go nodes 1000 movetime 250"},
+	}
+	for _, tt := range tests {
+		if got := tt.l.command(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.l, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// --- uci/learn.go ---
+
+package uci
+
+import (
+	"fmt"
+
+	"github.com/mesb/mchess/pieces"
+	"github.com/mesb/mchess/polyglot"
+	"github.com/mesb/mchess/socrates"
+)
+
+// adjudicateScore is the last search score (centipawns, engine's view) at
+// which an unfinished game counts as won or lost. GUIs end games by
+// resignation or adjudication without telling the engine the result.
+const adjudicateScore = 400
+
+// bookLearner follows the book moves of the current game and credits them
+// with its result when the next game starts.
+type bookLearner struct {
+	learn *polyglot.Learning
+	path  string // sidecar file; empty disables learning
+
+	line      []polyglot.Step
+	lastScore int // last search score, from lastColor's side
+	lastColor int
+}
+
+// open starts learning into path, loading what earlier sessions learned.
+func (l *bookLearner) open(path string) error {
+	l.learn, l.path, l.line = nil, "", nil
+	if path == "" {
+		return nil
+	}
+	learn, err := polyglot.OpenLearning(path)
+	if err != nil {
+		return err
+	}
+	l.learn, l.path = learn, path
+	return nil
+}
+
+// played notes the engine's reply to the position on eng.
+func (l *bookLearner) played(eng *socrates.RuleEngine, res socrates.SearchResult) {
+	if l.learn == nil || res.From == res.To {
+		return
+	}
+	l.lastScore, l.lastColor = res.Score, eng.Turn
+	if res.Book {
+		l.line = append(l.line, eng.BookStep(socrates.SimpleMove{From: res.From, To: res.To, Promo: res.Promo}))
+	}
+}
+
+// finish records the game that ended in eng's position and saves the file.
+func (l *bookLearner) finish(eng *socrates.RuleEngine) error {
+	line := l.line
+	l.line = nil
+	if l.learn == nil || len(line) == 0 {
+		return nil
+	}
+	score, over := eng.GameResult()
+	if !over {
+		score = 0.5
+		switch {
+		case l.lastScore >= adjudicateScore:
+			score = 1
+		case l.lastScore <= -adjudicateScore:
+			score = 0
+		}
+		if l.lastColor == pieces.BLACK {
+			score = 1 - score
+		}
+	}
+	l.learn.Record(line, score)
+	if err := l.learn.Save(l.path); err != nil {
+		return fmt.Errorf("BookLearnFile %s: %v", l.path, err)
+	}
+	return nil
+}
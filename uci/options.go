@@ -0,0 +1,273 @@
+// --- uci/options.go ---
+
+package uci
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mesb/mchess/nnue"
+	"github.com/mesb/mchess/polyglot"
+	"github.com/mesb/mchess/socrates"
+	"github.com/mesb/mchess/syzygy"
+)
+
+// option is one entry of the table advertised in reply to "uci". The
+// constructors below validate setoption values against the option's type
+// before handing them to its setter.
+type option struct {
+	name string
+	kind string // spin, check, combo, button or string
+	def  string
+	min  int
+	max  int
+	vars []string
+
+	// set applies a value, returning a note worth passing on to the GUI.
+	set func(o *engineOptions, eng *socrates.RuleEngine, value string) (string, error)
+}
+
+// String renders the "option name ..." line.
+func (op option) String() string {
+	s := fmt.Sprintf("option name %s type %s", op.name, op.kind)
+	switch op.kind {
+	case "spin":
+		s += fmt.Sprintf(" default %s min %d max %d", op.def, op.min, op.max)
+	case "combo":
+		s += " default " + op.def
+		for _, v := range op.vars {
+			s += " var " + v
+		}
+	case "check":
+		s += " default " + op.def
+	case "string":
+		if op.def == "" {
+			s += " default <empty>"
+		} else {
+			s += " default " + op.def
+		}
+	}
+	return s
+}
+
+func spinOption(name string, def, lo, hi int, set func(*engineOptions, int)) option {
+	return option{name: name, kind: "spin", def: strconv.Itoa(def), min: lo, max: hi,
+		set: func(o *engineOptions, _ *socrates.RuleEngine, value string) (string, error) {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < lo || n > hi {
+				return "", fmt.Errorf("%s must be between %d and %d", name, lo, hi)
+			}
+			set(o, n)
+			return "", nil
+		}}
+}
+
+func checkOption(name string, def bool, set func(*engineOptions, bool) error) option {
+	return option{name: name, kind: "check", def: strconv.FormatBool(def),
+		set: func(o *engineOptions, _ *socrates.RuleEngine, value string) (string, error) {
+			switch strings.ToLower(value) {
+			case "true":
+				return "", set(o, true)
+			case "false":
+				return "", set(o, false)
+			}
+			return "", fmt.Errorf("%s must be true or false", name)
+		}}
+}
+
+func comboOption(name, def string, vars []string, set func(*engineOptions, string)) option {
+	return option{name: name, kind: "combo", def: def, vars: vars,
+		set: func(o *engineOptions, _ *socrates.RuleEngine, value string) (string, error) {
+			for _, v := range vars {
+				if strings.EqualFold(v, value) {
+					set(o, v)
+					return "", nil
+				}
+			}
+			return "", fmt.Errorf("%s must be one of %s", name, strings.Join(vars, ", "))
+		}}
+}
+
+func buttonOption(name string, press func(*socrates.RuleEngine)) option {
+	return option{name: name, kind: "button",
+		set: func(_ *engineOptions, eng *socrates.RuleEngine, _ string) (string, error) {
+			press(eng)
+			return "", nil
+		}}
+}
+
+// stringOption passes "<empty>" on as the empty string.
+func stringOption(name string, set func(*engineOptions, string) (string, error)) option {
+	return option{name: name, kind: "string",
+		set: func(o *engineOptions, _ *socrates.RuleEngine, value string) (string, error) {
+			if value == "<empty>" {
+				value = ""
+			}
+			return set(o, value)
+		}}
+}
+
+// Engine limits advertised for the options that have no socrates constant.
+const (
+	defaultMoveOverhead = 10 // ms
+	maxMoveOverhead     = 5000
+)
+
+var errChess960 = errors.New("UCI_Chess960 is not supported: castling follows standard chess rules")
+
+// options lists every option in the order it is advertised.
+var options = buildOptions()
+
+func buildOptions() []option {
+	opts := []option{
+		spinOption("Hash", socrates.DefaultHashMB, 1, socrates.MaxHashMB,
+			func(o *engineOptions, mb int) { o.hashMB = mb }),
+		// The search runs on one thread; the option is there for GUIs that
+		// insist on setting it.
+		spinOption("Threads", 1, 1, 1, func(*engineOptions, int) {}),
+		buttonOption("Clear Hash", (*socrates.RuleEngine).ClearHash),
+		checkOption("Ponder", false, func(o *engineOptions, on bool) error {
+			o.ponder = on
+			return nil
+		}),
+		spinOption("MultiPV", 1, 1, socrates.MaxMultiPV,
+			func(o *engineOptions, n int) { o.multiPV = n }),
+		spinOption("Move Overhead", defaultMoveOverhead, 0, maxMoveOverhead,
+			func(o *engineOptions, ms int) { o.moveOverhead = ms }),
+		checkOption("UCI_Chess960", false, func(_ *engineOptions, on bool) error {
+			if on {
+				return errChess960
+			}
+			return nil
+		}),
+		checkOption("UCI_AnalyseMode", false, func(o *engineOptions, on bool) error {
+			o.analyse = on
+			return nil
+		}),
+
+		stringOption("EvalFile", setEvalFile),
+		stringOption("SyzygyPath", setSyzygyPath),
+
+		checkOption("OwnBook", true, func(o *engineOptions, on bool) error {
+			o.ownBook = on
+			return nil
+		}),
+		stringOption("BookFile", setBookFile),
+		stringOption("BookLearnFile", setBookLearnFile),
+		comboOption("BookPolicy", socrates.BookWeighted.String(), socrates.BookPolicyNames(),
+			func(o *engineOptions, name string) { o.bookOpts.Policy, _ = socrates.BookPolicyByName(name) }),
+		spinOption("BookDepth", 0, 0, 200, func(o *engineOptions, n int) { o.bookOpts.MaxPly = n }),
+		spinOption("BookMinWeight", 0, 0, 100, func(o *engineOptions, n int) { o.bookOpts.MinWeight = n }),
+		spinOption("BookSeed", 0, 0, math.MaxInt32, func(o *engineOptions, n int) { o.bookOpts.Seed = int64(n) }),
+
+		spinOption("Skill Level", socrates.MaxSkillLevel, 0, socrates.MaxSkillLevel,
+			func(o *engineOptions, n int) { o.skillLevel = n }),
+		checkOption("UCI_LimitStrength", false, func(o *engineOptions, on bool) error {
+			o.limitStrength = on
+			return nil
+		}),
+		spinOption("UCI_Elo", defaultElo, socrates.MinElo, socrates.MaxElo,
+			func(o *engineOptions, n int) { o.elo = n }),
+		comboOption("Personality", socrates.Balanced.Name, socrates.PersonalityNames(),
+			func(o *engineOptions, name string) { o.personality, _ = socrates.PersonalityByName(name) }),
+		spinOption("Contempt", 0, -socrates.MaxContempt, socrates.MaxContempt,
+			func(o *engineOptions, n int) { o.contempt = n }),
+		checkOption("DynamicContempt", false, func(o *engineOptions, on bool) error {
+			o.dynamicContempt = on
+			return nil
+		}),
+		stringOption("UCI_Opponent", func(o *engineOptions, value string) (string, error) {
+			o.opponentElo = opponentElo(strings.Fields(value))
+			return "", nil
+		}),
+	}
+
+	// Search margins and reductions, exposed for SPSA-style tuning.
+	params := socrates.DefaultSearchParams()
+	for _, t := range params.Tunables() {
+		name := t.Name
+		opts = append(opts, spinOption(name, *t.Value, t.Min, t.Max, func(_ *engineOptions, n int) {
+			params := socrates.CurrentSearchParams()
+			if t, ok := params.Tunable(name); ok {
+				*t.Value = n
+				socrates.SetSearchParams(params)
+			}
+		}))
+	}
+	return opts
+}
+
+// findOption looks an option up by its case-insensitive name.
+func findOption(name string) (option, bool) {
+	for _, op := range options {
+		if strings.EqualFold(op.name, name) {
+			return op, true
+		}
+	}
+	return option{}, false
+}
+
+func setEvalFile(o *engineOptions, path string) (string, error) {
+	if path == "" {
+		o.network = nil
+		return "", nil
+	}
+	n, err := nnue.Load(path)
+	if err != nil {
+		return "", fmt.Errorf("EvalFile %s: %v", path, err)
+	}
+	o.network = n
+	return fmt.Sprintf("NNUE evaluation using %s", path), nil
+}
+
+func setSyzygyPath(o *engineOptions, path string) (string, error) {
+	if path == "" {
+		o.tablebase = nil
+		return "", nil
+	}
+	tb, err := syzygy.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("SyzygyPath %s: %v", path, err)
+	}
+	o.tablebase = tb
+	return fmt.Sprintf("Found %d tablebases (up to %d pieces)", tb.Count(), tb.MaxPieces()), nil
+}
+
+func setBookFile(o *engineOptions, path string) (string, error) {
+	if path == "" {
+		o.book = nil
+		return "", nil
+	}
+	b, err := polyglot.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("BookFile %s: %v", path, err)
+	}
+	o.book = b
+	return fmt.Sprintf("Book %s with %d entries", path, b.Len()), nil
+}
+
+func setBookLearnFile(o *engineOptions, path string) (string, error) {
+	if err := o.learning.open(path); err != nil {
+		return "", fmt.Errorf("BookLearnFile %s: %v", path, err)
+	}
+	if path == "" {
+		return "", nil
+	}
+	return fmt.Sprintf("Book learning in %s (%d moves known)", path, o.learning.learn.Len()), nil
+}
+
+// opponentElo reads the rating from a UCI_Opponent value of the form
+// "<title> <elo> <computer|human> <name>"; "none" or garbage give 0.
+func opponentElo(value []string) int {
+	if len(value) < 2 {
+		return 0
+	}
+	elo, err := strconv.Atoi(value[1])
+	if err != nil || elo < 0 {
+		return 0
+	}
+	return elo
+}
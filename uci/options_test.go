@@ -0,0 +1,74 @@
+package uci
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/socrates"
+)
+
+func setOption(t *testing.T, opts *engineOptions, eng *socrates.RuleEngine, name, value string) error {
+	t.Helper()
+	op, ok := findOption(name)
+	if !ok {
+		t.Fatalf("no option %s", name)
+	}
+	_, err := op.set(opts, eng, value)
+	return err
+}
+
+func TestOptionsAreValidated(t *testing.T) {
+	opts := newEngineOptions()
+	eng := socrates.New(board.InitStandard())
+	tests := []struct {
+		name, value string
+		ok          bool
+	}{
+		{"Hash", "16", true},
+		{"Hash", "0", false},
+		{"hash", "lots", false},
+		{"MultiPV", "4", true},
+		{"Threads", "2", false},
+		{"Ponder", "true", true},
+		{"Ponder", "yes", false},
+		{"UCI_Chess960", "true", false},
+		{"UCI_Chess960", "false", true},
+		{"BookPolicy", "BEST", true},
+		{"BookPolicy", "random", false},
+		{"Clear Hash", "", true},
+		{"EvalFile", "<empty>", true},
+	}
+	for _, tt := range tests {
+		if err := setOption(t, opts, eng, tt.name, tt.value); (err == nil) != tt.ok {
+			t.Errorf("setoption %s = %q: err %v", tt.name, tt.value, err)
+		}
+	}
+	if _, ok := findOption("NoSuchOption"); ok {
+		t.Error("found an option that does not exist")
+	}
+
+	opts.apply(eng)
+	if eng.HashSize() > 16 || eng.MultiPV() != 4 || !opts.ponder {
+		t.Fatalf("hash %d MB, multipv %d, ponder %v", eng.HashSize(), eng.MultiPV(), opts.ponder)
+	}
+	if opts.bookOpts.Policy != socrates.BookBest {
+		t.Fatalf("book policy %v", opts.bookOpts.Policy)
+	}
+}
+
+func TestOptionLines(t *testing.T) {
+	want := map[string]string{
+		"Hash":       fmt.Sprintf("option name Hash type spin default %d min 1 max %d", socrates.DefaultHashMB, socrates.MaxHashMB),
+		"Clear Hash": "option name Clear Hash type button",
+		"EvalFile":   "option name EvalFile type string default <empty>",
+		"BookPolicy": "option name BookPolicy type combo default weighted var weighted var best var uniform",
+		"Ponder":     "option name Ponder type check default false",
+	}
+	for name, line := range want {
+		op, ok := findOption(name)
+		if !ok || op.String() != line {
+			t.Errorf("%s: got %q", name, op)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// --- uci/search.go ---
+
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mesb/mchess/search"
+	"github.com/mesb/mchess/socrates"
+)
+
+// goKeywords are the parameter names of "go" in the UCI protocol.
+var goKeywords = map[string]bool{
+	"searchmoves": true, "ponder": true, "wtime": true, "btime": true, "winc": true, "binc": true,
+	"movestogo": true, "depth": true, "nodes": true, "mate": true, "movetime": true, "infinite": true,
+}
+
+// parseGo reads the limits of a "go" command. Unknown or malformed
+// parameters are returned as errors and otherwise ignored.
+func parseGo(args []string) (search.Limits, []error) {
+	var p search.Limits
+	var errs []error
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "infinite":
+			p.Infinite = true
+			continue
+		case "ponder":
+			p.Ponder = true
+			continue
+		case "depth", "nodes", "movetime", "wtime", "btime", "winc", "binc", "movestogo":
+		default:
+			errs = append(errs, fmt.Errorf("go: %s is not supported", args[i]))
+			// Skip its arguments: a count, or the moves of searchmoves.
+			for i+1 < len(args) && !goKeywords[args[i+1]] {
+				i++
+			}
+			continue
+		}
+		if i+1 >= len(args) {
+			errs = append(errs, fmt.Errorf("go: %s needs a value", args[i]))
+			break
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil || n < 0 {
+			errs = append(errs, fmt.Errorf("go: %s needs a number, got %q", args[i], args[i+1]))
+			i++
+			continue
+		}
+		ms := time.Duration(n) * time.Millisecond
+		switch args[i] {
+		case "depth":
+			p.Depth = n
+		case "nodes":
+			p.Nodes = n
+		case "movetime":
+			p.MoveTime = ms
+		case "wtime":
+			p.Time[0] = ms
+		case "btime":
+			p.Time[1] = ms
+		case "winc":
+			p.Inc[0] = ms
+		case "binc":
+			p.Inc[1] = ms
+		case "movestogo":
+			p.MovesToGo = n
+		}
+		i++
+	}
+	return p, errs
+}
+
+// reporter writes the progress of a search in UCI terms.
+type reporter struct {
+	out  *output
+	opts *engineOptions
+	eng  *socrates.RuleEngine
+}
+
+func (r reporter) Iteration(i search.Info) {
+	r.out.send("info depth %d seldepth %d multipv %d score %s nodes %d nps %d hashfull %d tbhits %d time %d pv %s",
+		i.Depth, i.SelDepth, i.MultiPV, scoreString(i.Score, i.Bound), i.Nodes, i.NPS(),
+		i.Hashfull, i.TBHits, i.Time.Milliseconds(), socrates.PVString(i.PV))
+}
+
+func (r reporter) CurrMove(depth int, m socrates.SimpleMove, number int) {
+	r.out.send("info depth %d currmove %s currmovenumber %d", depth, m.String(), number)
+}
+
+func (r reporter) BookMove(m socrates.SimpleMove) {
+	r.out.send("info string book move %s", m.String())
+}
+
+func (r reporter) BestMove(res search.Result) {
+	m, ok := res.Move()
+	if !ok {
+		r.out.send("bestmove 0000")
+		return
+	}
+	if res.Ponder.From != res.Ponder.To {
+		r.out.send("bestmove %s ponder %s", m.String(), res.Ponder.String())
+	} else {
+		r.out.send("bestmove %s", m.String())
+	}
+	r.opts.learning.played(r.eng, res.SearchResult)
+}
+
+// scoreString formats a score as "cp <centipawns>" or "mate <moves>",
+// negative when the engine is being mated, followed by its bound.
+func scoreString(score int, bound socrates.Bound) string {
+	s := fmt.Sprintf("cp %d", score)
+	if moves, ok := socrates.MateIn(score); ok {
+		s = fmt.Sprintf("mate %d", moves)
+	}
+	switch bound {
+	case socrates.BoundLower:
+		s += " lowerbound"
+	case socrates.BoundUpper:
+		s += " upperbound"
+	}
+	return s
+}
@@ -0,0 +1,44 @@
+package uci
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mesb/mchess/socrates"
+)
+
+func TestParseGo(t *testing.T) {
+	p, errs := parseGo([]string{"go", "wtime", "60000", "btime", "3010", "winc", "0", "binc", "0", "movestogo", "1", "ponder"})
+	if len(errs) != 0 || p.Time[1] != 3010*time.Millisecond || p.MovesToGo != 1 || !p.Ponder {
+		t.Fatalf("parsed %+v, errors %v", p, errs)
+	}
+}
+
+func TestParseGoReportsBadParameters(t *testing.T) {
+	p, errs := parseGo([]string{"go", "wtime", "soon", "mate", "3", "nodes", "100", "depth", "4", "movetime"})
+	if len(errs) != 3 {
+		t.Fatalf("errors %v, want three", errs)
+	}
+	if p.Depth != 4 || p.Nodes != 100 || p.Time[0] != 0 {
+		t.Fatalf("parsed %+v", p)
+	}
+}
+
+func TestScoreString(t *testing.T) {
+	tests := []struct {
+		score int
+		bound socrates.Bound
+		want  string
+	}{
+		{35, socrates.BoundExact, "cp 35"},
+		{-120, socrates.BoundUpper, "cp -120 upperbound"},
+		{socrates.MateScore - 1, socrates.BoundExact, "mate 1"},
+		{socrates.MateScore - 3, socrates.BoundLower, "mate 2 lowerbound"},
+		{-socrates.MateScore + 2, socrates.BoundExact, "mate -1"},
+	}
+	for _, tt := range tests {
+		if got := scoreString(tt.score, tt.bound); got != tt.want {
+			t.Errorf("scoreString(%d, %d) = %q, want %q", tt.score, tt.bound, got, tt.want)
+		}
+	}
+}
@@ -5,120 +5,323 @@ package uci
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/mesb/mchess/address"
 	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/nnue"
+	"github.com/mesb/mchess/polyglot"
+	"github.com/mesb/mchess/search"
 	"github.com/mesb/mchess/socrates"
+	"github.com/mesb/mchess/syzygy"
 )
 
-// Run starts the UCI loop, listening to Stdin and writing to Stdout.
-func Run() {
-	scanner := bufio.NewScanner(os.Stdin)
-	// Initialize with standard start position
-	eng := socrates.New(board.InitStandard())
+// engineOptions holds setoption values that must survive engine resets.
+type engineOptions struct {
+	network   *nnue.Network
+	tablebase *syzygy.Tablebase
+	book      *polyglot.Book
+	bookOpts  socrates.BookOptions
+	ownBook   bool
+	analyse   bool
+	learning  bookLearner
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		cmd := strings.Fields(line)
-		if len(cmd) == 0 {
-			continue
-		}
+	skillLevel    int
+	limitStrength bool
+	elo           int
+	personality   socrates.Personality
 
-		switch cmd[0] {
-		case "uci":
-			fmt.Println("id name MCHESS Dragon")
-			fmt.Println("id author Hexa")
-			fmt.Println("uciok")
+	contempt        int
+	dynamicContempt bool
+	opponentElo     int
 
-		case "isready":
-			fmt.Println("readyok")
+	hashMB       int
+	multiPV      int
+	ponder       bool
+	moveOverhead int // ms kept back from the clock for GUI and network lag
+}
 
-		case "ucinewgame":
-			eng = socrates.New(board.InitStandard())
+// UCI_Elo default.
+const defaultElo = 1350
 
-		case "position":
-			handlePosition(eng, cmd)
+func newEngineOptions() *engineOptions {
+	return &engineOptions{
+		skillLevel:  socrates.MaxSkillLevel,
+		elo:         defaultElo,
+		personality: socrates.Balanced,
+		ownBook:     true,
 
-		case "go":
-			handleGo(eng, cmd)
+		hashMB:       socrates.DefaultHashMB,
+		multiPV:      1,
+		moveOverhead: defaultMoveOverhead,
+	}
+}
 
-		case "quit":
-			return
-		}
+// strength resolves the effective skill level; UCI_LimitStrength makes
+// UCI_Elo take precedence over Skill Level.
+func (o *engineOptions) strength() int {
+	if o.limitStrength {
+		return socrates.EloToSkillLevel(o.elo)
 	}
+	return o.skillLevel
 }
 
-// handlePosition parses "position startpos moves e2e4..." or "position fen ... moves ..."
-func handlePosition(eng *socrates.RuleEngine, args []string) {
-	if len(args) < 2 {
-		return
+// apply re-installs the configured options on a (possibly fresh) engine.
+func (o *engineOptions) apply(eng *socrates.RuleEngine) {
+	eng.SetHashSize(o.hashMB)
+	eng.SetMultiPV(o.multiPV)
+	if eng.Network() != o.network {
+		eng.UseNetwork(o.network)
+	}
+	if eng.Tablebase() != o.tablebase {
+		eng.UseTablebase(o.tablebase)
+	}
+	if eng.Book() != o.book {
+		eng.UseBook(o.book)
+	}
+	bo := o.bookOpts
+	bo.Disabled = !o.ownBook || o.analyse
+	eng.SetBookOptions(bo)
+	if eng.Learning() != o.learning.learn {
+		eng.UseLearning(o.learning.learn)
+	}
+	if eng.SkillLevel() != o.strength() {
+		eng.SetSkillLevel(o.strength())
 	}
+	eng.SetPersonality(o.personality)
+	eng.SetContempt(o.contempt)
+	eng.SetDynamicContempt(o.dynamicContempt)
+	eng.SetOpponentElo(o.opponentElo)
+}
 
-	moveIdx := -1
-	// 1. Reset Board
-	if args[1] == "startpos" {
-		*eng = *socrates.New(board.InitStandard())
-		moveIdx = 2
-	} else if args[1] == "fen" {
-		// Join tokens until "moves" keyword
-		var fenParts []string
-		for i := 2; i < len(args); i++ {
-			if args[i] == "moves" {
-				moveIdx = i
-				break
-			}
-			fenParts = append(fenParts, args[i])
-		}
-		fen := strings.Join(fenParts, " ")
-		b, s, err := board.FromFEN(fen)
-		if err == nil {
-			eng.Board = b
-			eng.State = s
-			eng.Turn = s.Turn
-			eng.ResetHashHistory()
+// output serializes the lines of the search goroutine and the input loop.
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// send writes one line to the GUI.
+func (o *output) send(format string, args ...any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fmt.Fprintf(o.w, format+"\n", args...)
+}
+
+// Write passes text through unchanged, for reports such as bench.
+func (o *output) Write(b []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.w.Write(b)
+}
+
+// Protocol speaks UCI to a GUI: it reads commands from one stream and
+// answers on another. Searches run in the background, so isready, stop and
+// ponderhit are answered while the engine thinks; any other command first
+// stops the search. Malformed commands are reported as info strings.
+type Protocol struct {
+	in       io.Reader
+	out      *output
+	eng      *socrates.RuleEngine
+	opts     *engineOptions
+	searcher search.Searcher
+}
+
+// New returns a protocol reading commands from in and writing to out,
+// set up on the standard start position.
+func New(in io.Reader, out io.Writer) *Protocol {
+	p := &Protocol{
+		in:   in,
+		out:  &output{w: out},
+		eng:  socrates.New(board.InitStandard()),
+		opts: newEngineOptions(),
+	}
+	return p
+}
+
+// Run starts the UCI loop, listening to Stdin and writing to Stdout.
+func Run() {
+	if err := New(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "uci:", err)
+	}
+}
+
+// Run processes commands until quit or the end of the input, returning
+// any read error.
+func (p *Protocol) Run() error {
+	scanner := bufio.NewScanner(p.in)
+	for scanner.Scan() {
+		if !p.handle(strings.Fields(scanner.Text())) {
+			return nil
 		}
 	}
+	p.quit()
+	return scanner.Err()
+}
 
-	// 2. Apply Moves (if any)
-	if moveIdx != -1 && moveIdx < len(args) && args[moveIdx] == "moves" {
-		for i := moveIdx + 1; i < len(args); i++ {
-			mvStr := args[i]
-			from, to, promo, err := socrates.ParseMove(mvStr)
-			if err == nil {
-				eng.MakeMove(*from, *to, promo)
+// handle executes one command, reporting false after quit.
+func (p *Protocol) handle(cmd []string) bool {
+	if len(cmd) == 0 {
+		return true
+	}
+	switch cmd[0] {
+	case "isready":
+		p.out.send("readyok")
+		return true
+	case "ponderhit":
+		p.searcher.PonderHit()
+		return true
+	}
+	p.searcher.Stop()
+
+	switch cmd[0] {
+	case "uci":
+		p.out.send("id name MCHESS Dragon")
+		p.out.send("id author Hexa")
+		for _, op := range options {
+			p.out.send("%s", op)
+		}
+		p.out.send("uciok")
+
+	case "debug", "register", "stop":
+		// Nothing to do: there is no debug output, no registration, and
+		// the search has already stopped.
+
+	case "setoption":
+		p.setOption(cmd)
+		p.opts.apply(p.eng)
+
+	case "ucinewgame":
+		p.finishGame()
+		p.eng = socrates.New(board.InitStandard())
+		p.opts.bookOpts.Game++ // a different line through the book each game
+		p.opts.apply(p.eng)
+
+	case "position":
+		p.position(cmd)
+		p.opts.apply(p.eng)
+
+	case "go":
+		p.goSearch(cmd)
+
+	case "bench":
+		// Not part of UCI: searches a fixed set of positions and reports
+		// the node count signature and speed.
+		depth := search.BenchDepth
+		if len(cmd) > 1 {
+			n, err := strconv.Atoi(cmd[1])
+			if err != nil || n <= 0 || n > search.MaxDepth {
+				p.out.send("info string bench: depth must be between 1 and %d", search.MaxDepth)
+				return true
 			}
+			depth = n
 		}
+		search.Bench(p.out, depth)
+
+	case "quit":
+		p.quit()
+		return false
+
+	default:
+		p.out.send("info string Unknown command %q", strings.Join(cmd, " "))
 	}
+	return true
 }
 
-// handleGo starts the search. Currently supports fixed depth or simple time management.
-func handleGo(eng *socrates.RuleEngine, args []string) {
-	// Default search parameters
-	depth := 5
+// goSearch starts a search under the limits of a "go" command.
+func (p *Protocol) goSearch(args []string) {
+	l, errs := parseGo(args)
+	for _, err := range errs {
+		p.out.send("info string %v", err)
+	}
+	l.Overhead = time.Duration(p.opts.moveOverhead) * time.Millisecond
+	p.searcher.Start(p.eng, l, reporter{out: p.out, opts: p.opts, eng: p.eng})
+}
 
-	// TODO: Implement iterative deepening and time management parsing here.
-	_ = args
+func (p *Protocol) quit() {
+	p.searcher.Stop()
+	p.finishGame()
+}
 
-	start := time.Now()
-	res := eng.Search(depth)
-	elapsed := time.Since(start)
+// finishGame credits the book moves of the game just played.
+func (p *Protocol) finishGame() {
+	if err := p.opts.learning.finish(p.eng); err != nil {
+		p.out.send("info string %v", err)
+	}
+}
 
-	// Output info string for the GUI
-	// cp = centipawns score
-	fmt.Printf("info depth %d score cp %d nodes %d time %d\n",
-		depth, res.Score, res.Nodes, elapsed.Milliseconds())
+// setOption parses "setoption name <id> [value <x>]", reporting unknown
+// options and invalid values.
+func (p *Protocol) setOption(args []string) {
+	var name, value []string
+	target := &name
+	for _, tok := range args[1:] {
+		switch tok {
+		case "name":
+			target = &name
+		case "value":
+			target = &value
+		default:
+			*target = append(*target, tok)
+		}
+	}
 
-	// Output the best move found
-	moveStr := squareString(res.From) + squareString(res.To)
-	if res.Promo != 0 {
-		moveStr += string(res.Promo)
+	op, ok := findOption(strings.Join(name, " "))
+	if !ok {
+		p.out.send("info string Unknown option %q", strings.Join(name, " "))
+		return
+	}
+	note, err := op.set(p.opts, p.eng, strings.Join(value, " "))
+	switch {
+	case err != nil:
+		p.out.send("info string %v", err)
+	case note != "":
+		p.out.send("info string %s", note)
 	}
-	fmt.Printf("bestmove %s\n", moveStr)
 }
 
-func squareString(a address.Addr) string {
-	return fmt.Sprintf("%c%d", a.File.Char(), int(a.Rank)+1)
+// position parses "position startpos moves e2e4..." or "position fen ...
+// moves ...". A bad FEN leaves the position unchanged; the moves stop at
+// the first illegal one.
+func (p *Protocol) position(args []string) {
+	if len(args) < 2 {
+		p.out.send("info string position needs startpos or fen")
+		return
+	}
+
+	moveIdx := len(args)
+	for i, arg := range args {
+		if arg == "moves" {
+			moveIdx = i
+			break
+		}
+	}
+	// 1. Reset Board, keeping the transposition table between moves
+	switch args[1] {
+	case "startpos":
+		p.eng.SetPosition(board.InitStandard(), board.NewGameState())
+	case "fen":
+		fen := strings.Join(args[2:moveIdx], " ")
+		b, s, err := board.FromFEN(fen)
+		if err != nil {
+			p.out.send("info string Invalid FEN %q: %v", fen, err)
+			return
+		}
+		p.eng.SetPosition(b, s)
+	default:
+		p.out.send("info string position needs startpos or fen, got %q", args[1])
+		return
+	}
+
+	// 2. Apply Moves (if any)
+	for _, mv := range args[min(moveIdx+1, len(args)):] {
+		from, to, promo, err := socrates.ParseMove(mv)
+		if err != nil || !p.eng.MakeMove(*from, *to, promo) {
+			p.out.send("info string Illegal move %s in position", mv)
+			return
+		}
+	}
 }
@@ -0,0 +1,147 @@
+package uci
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesb/mchess/internal/protocoltest"
+)
+
+// converse drives a Protocol the way a GUI does.
+func converse(t *testing.T) *protocoltest.Conversation {
+	t.Helper()
+	return protocoltest.Converse(t, func(in io.Reader, out io.Writer) error { return New(in, out).Run() })
+}
+
+func TestHandshake(t *testing.T) {
+	c := converse(t)
+	c.Send("uci")
+	_, lines := c.Expect("uciok")
+	if len(lines) < 2 || lines[0] != "id name MCHESS Dragon" || !strings.HasPrefix(lines[1], "id author") {
+		t.Fatalf("no id lines: %q", lines)
+	}
+	want := []string{"Hash", "Threads", "MultiPV", "OwnBook", "Ponder", "Clear Hash", "UCI_Chess960", "Move Overhead", "Skill Level", "UCI_Elo"}
+	for _, name := range want {
+		found := false
+		for _, l := range lines {
+			found = found || strings.HasPrefix(l, "option name "+name+" type ")
+		}
+		if !found {
+			t.Errorf("option %s is not advertised", name)
+		}
+	}
+	c.Send("isready")
+	c.Expect("readyok")
+	c.Quit()
+}
+
+func TestSearchReportsAndAnswers(t *testing.T) {
+	c := converse(t)
+	c.Send("setoption name OwnBook value false")
+	c.Send("setoption name MultiPV value 2")
+	c.Send("position startpos moves e2e4 e7e5")
+	c.Send("go depth 3")
+	best, info := c.Expect("bestmove ")
+	last := info[len(info)-1]
+	for _, field := range []string{"depth 3 ", "seldepth ", "multipv 2 ", "score cp ", "nodes ", "nps ", "hashfull ", "tbhits ", "time ", "pv "} {
+		if !strings.Contains(last, field) {
+			t.Errorf("%q lacks %q", last, field)
+		}
+	}
+	if len(strings.Fields(best)) < 2 {
+		t.Fatalf("%q names no move", best)
+	}
+	c.Quit()
+}
+
+func TestMalformedCommandsAreReported(t *testing.T) {
+	c := converse(t)
+	tests := []struct{ cmd, reply string }{
+		{"position fen not/a/fen w - - 0 1", "info string Invalid FEN"},
+		{"position startpos moves e2e4 e2e4", "info string Illegal move e2e4"},
+		{"position", "info string position needs startpos or fen"},
+		{"setoption name Hash value huge", "info string Hash must be between"},
+		{"setoption name Nonsense value 1", `info string Unknown option "Nonsense"`},
+		{"frobnicate now", `info string Unknown command "frobnicate now"`},
+		{"go mate 3 depth 1", "info string go: mate is not supported"},
+	}
+	for _, tt := range tests {
+		c.Send(tt.cmd)
+		if line, _ := c.Expect("info string"); !strings.HasPrefix(line, tt.reply) {
+			t.Errorf("%s: got %q, want %q", tt.cmd, line, tt.reply)
+		}
+	}
+	c.Expect("bestmove")
+
+	// The bad FEN left the position alone: after 1. e4 Black is to move.
+	c.Send("setoption name OwnBook value false")
+	c.Send("position startpos moves e2e4")
+	c.Send("position fen 8/8/8 w - - 0 1")
+	c.Expect("info string Invalid FEN")
+	c.Send("go depth 1")
+	best, _ := c.Expect("bestmove ")
+	if from := strings.Fields(best)[1]; from[1] != '7' && from[1] != '8' {
+		t.Fatalf("%q is not a black move", best)
+	}
+	c.Quit()
+}
+
+func TestStopAndIsReadyDuringSearch(t *testing.T) {
+	c := converse(t)
+	c.Send("setoption name OwnBook value false")
+	c.Send("position startpos")
+	c.Send("go infinite")
+	c.Expect("info depth 1 ")
+	c.Send("isready")
+	if _, before := c.Expect("readyok"); strings.Contains(strings.Join(before, "\n"), "bestmove") {
+		t.Fatal("an infinite search answered before stop")
+	}
+	c.Send("stop")
+	c.Expect("bestmove ")
+	c.Quit()
+}
+
+func TestPonderhit(t *testing.T) {
+	c := converse(t)
+	c.Send("setoption name OwnBook value false")
+	c.Send("setoption name Ponder value true")
+	c.Send("position startpos moves e2e4 e7e5")
+	c.Send("go ponder depth 2 wtime 10000 btime 10000")
+	c.Expect("info depth 2 ")
+	c.Quiet(100 * time.Millisecond)
+	c.Send("ponderhit")
+	c.Expect("bestmove ")
+
+	// stop ends pondering just as well, for a ponder miss.
+	c.Send("go ponder wtime 10000 btime 10000")
+	c.Expect("info depth 1 ")
+	c.Send("stop")
+	c.Expect("bestmove ")
+	c.Quit()
+}
+
+func TestInputEndStopsTheSearch(t *testing.T) {
+	c := converse(t)
+	c.Send("setoption name OwnBook value false")
+	c.Send("go infinite")
+	c.Expect("info depth 1 ")
+	c.Close()
+	c.Expect("bestmove ")
+	if err := c.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBench(t *testing.T) {
+	c := converse(t)
+	c.Send("bench 0")
+	c.Expect("info string bench: depth must be between 1 and")
+	c.Send("bench 1")
+	c.Expect("Nodes searched  : ")
+	c.Expect("Nodes/second    : ")
+	c.Send("isready")
+	c.Expect("readyok")
+	c.Quit()
+}
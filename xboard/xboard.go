@@ -0,0 +1,406 @@
+// --- xboard/xboard.go ---
+
+// Package xboard speaks the Chess Engine Communication Protocol (CECP)
+// used by XBoard, WinBoard and the bots bridging them to chess servers.
+// It runs on the same background searcher and time manager as the UCI
+// front end.
+package xboard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mesb/mchess/board"
+	"github.com/mesb/mchess/pieces"
+	"github.com/mesb/mchess/search"
+	"github.com/mesb/mchess/socrates"
+)
+
+// features answers "protover 2". The GUI sends moves as "usermove e2e4"
+// and positions with setboard; the clocks arrive with time and otim.
+var features = []string{
+	"ping=1", "setboard=1", "playother=1", "san=0", "usermove=1", "time=1",
+	"draw=0", "sigint=0", "sigterm=0", "reuse=1", "analyze=1", "colors=0",
+	"memory=1", `myname="MCHESS Dragon"`, `variants="normal"`,
+}
+
+// mateScore is how CECP shows a mate in N moves: 100000 + N.
+const mateScore = 100000
+
+// output serializes the lines of the search goroutine and the input loop.
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (o *output) send(format string, args ...any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fmt.Fprintf(o.w, format+"\n", args...)
+}
+
+// Protocol is an engine speaking CECP on a pair of streams.
+type Protocol struct {
+	in       io.Reader
+	out      *output
+	eng      *socrates.RuleEngine
+	searcher search.Searcher
+	thinking *thought // the running search; nil when idle
+
+	engineColor int  // the side the engine plays
+	force       bool // play neither side
+	analyzing   bool
+	post        atomic.Bool // show thinking output
+
+	// Time control from level, st and sd; the clocks from time and otim.
+	movesPerControl int
+	base, inc       time.Duration
+	moveTime        time.Duration
+	depth           int
+	clock, otim     time.Duration
+}
+
+// New returns a protocol reading commands from in and writing to out,
+// set up for a new game with the engine playing Black.
+func New(in io.Reader, out io.Writer) *Protocol {
+	p := &Protocol{in: in, out: &output{w: out}}
+	p.newGame()
+	return p
+}
+
+// Run processes commands until quit or the end of the input, returning
+// any read error.
+func (p *Protocol) Run() error {
+	scanner := bufio.NewScanner(p.in)
+	for scanner.Scan() {
+		if !p.handle(strings.Fields(scanner.Text())) {
+			return nil
+		}
+	}
+	p.abandon()
+	return scanner.Err()
+}
+
+// handle executes one command, reporting false after quit.
+func (p *Protocol) handle(cmd []string) bool {
+	if len(cmd) == 0 {
+		return true
+	}
+	args := cmd[1:]
+	switch cmd[0] {
+	// Commands that leave a running search alone.
+	case "xboard", "accepted", "rejected", "random", "hard", "easy", "computer",
+		"name", "rating", "ics", "cores", "draw", "hint", "bk", ".", "white", "black":
+	case "protover":
+		p.out.send("feature done=0")
+		p.out.send("feature %s", strings.Join(features, " "))
+		p.out.send("feature done=1")
+	case "ping":
+		p.out.send("pong %s", strings.Join(args, " "))
+	case "post":
+		p.post.Store(true)
+	case "nopost":
+		p.post.Store(false)
+	case "time":
+		p.clock = centiseconds(args)
+	case "otim":
+		p.otim = centiseconds(args)
+	case "level":
+		p.level(args)
+	case "st":
+		if secs, err := strconv.ParseFloat(first(args), 64); err == nil && secs > 0 {
+			p.moveTime = time.Duration(secs * float64(time.Second))
+		} else {
+			p.errorf("bad st", cmd)
+		}
+	case "sd":
+		if n, err := strconv.Atoi(first(args)); err == nil && n > 0 {
+			p.depth = n
+		} else {
+			p.errorf("bad sd", cmd)
+		}
+	case "memory":
+		if mb, err := strconv.Atoi(first(args)); err == nil && mb > 0 {
+			p.abandon()
+			p.eng.SetHashSize(mb)
+			p.resume()
+		} else {
+			p.errorf("bad memory", cmd)
+		}
+	case "?":
+		p.searcher.Stop() // move now
+
+	// Commands that change the game stop the search first.
+	case "quit":
+		p.abandon()
+		return false
+	case "new":
+		p.abandon()
+		p.newGame()
+		p.resume()
+	case "variant":
+		if first(args) != "normal" {
+			p.out.send("Error (unsupported variant): %s", first(args))
+		}
+	case "force":
+		p.abandon()
+		p.force = true
+	case "go":
+		p.abandon()
+		p.force = false
+		p.engineColor = p.eng.Turn
+		p.think()
+	case "playother":
+		p.abandon()
+		p.force = false
+		p.engineColor = 1 - p.eng.Turn
+	case "usermove":
+		p.userMove(first(args))
+	case "setboard":
+		p.abandon()
+		b, s, err := board.FromFEN(strings.Join(args, " "))
+		if err != nil {
+			p.out.send("tellusererror Illegal position: %v", err)
+		} else {
+			p.eng.SetPosition(b, s)
+		}
+		p.resume()
+	case "undo":
+		p.abandon()
+		p.eng.UndoMove()
+		p.resume()
+	case "remove":
+		p.abandon()
+		p.eng.UndoMove()
+		p.eng.UndoMove()
+		p.resume()
+	case "result":
+		p.abandon()
+		p.force = true
+	case "analyze":
+		p.abandon()
+		p.analyzing = true
+		p.resume()
+	case "exit":
+		p.abandon()
+		p.analyzing = false
+	default:
+		// Protocol 1 GUIs send bare moves.
+		if _, _, _, err := socrates.ParseMove(cmd[0]); err == nil && len(cmd) == 1 {
+			p.userMove(cmd[0])
+			return true
+		}
+		p.out.send("Error (unknown command): %s", cmd[0])
+	}
+	return true
+}
+
+// newGame sets up the start position with the engine playing Black.
+func (p *Protocol) newGame() {
+	if p.eng == nil {
+		p.eng = socrates.New(board.InitStandard())
+	} else {
+		p.eng.SetPosition(board.InitStandard(), board.NewGameState())
+	}
+	p.engineColor = pieces.BLACK
+	p.force = false
+	p.depth = 0
+	p.moveTime = 0
+}
+
+// userMove plays the opponent's move and answers it when it is the
+// engine's turn.
+func (p *Protocol) userMove(mv string) {
+	p.abandon()
+	from, to, promo, err := socrates.ParseMove(mv)
+	if err != nil || !p.eng.MakeMove(*from, *to, promo) {
+		p.out.send("Illegal move: %s", mv)
+		p.resume()
+		return
+	}
+	if p.announceResult() {
+		return
+	}
+	if p.analyzing || (!p.force && p.eng.Turn == p.engineColor) {
+		p.think()
+	}
+}
+
+// resume restarts the analysis after the position changed.
+func (p *Protocol) resume() {
+	if p.analyzing {
+		p.think()
+	}
+}
+
+// abandon stops the running search without playing its move.
+func (p *Protocol) abandon() {
+	if p.thinking != nil {
+		p.thinking.discard.Store(true)
+	}
+	p.searcher.Stop()
+	p.thinking = nil
+}
+
+// think starts searching the position: to play a move under the time
+// control, or without limit while analyzing.
+func (p *Protocol) think() {
+	if _, over := p.eng.GameResult(); over {
+		return
+	}
+	l := search.Limits{Depth: p.depth, Infinite: p.analyzing}
+	if !p.analyzing {
+		l.MoveTime = p.moveTime
+		clock := p.clock
+		if clock <= 0 {
+			clock = p.base
+		}
+		l.Time[p.eng.Turn], l.Time[1-p.eng.Turn] = clock, p.otim
+		l.Inc[p.eng.Turn], l.Inc[1-p.eng.Turn] = p.inc, p.inc
+		if p.movesPerControl > 0 {
+			played := (p.eng.State.FullmoveNumber - 1) % p.movesPerControl
+			l.MovesToGo = p.movesPerControl - played
+		}
+	}
+	p.thinking = &thought{p: p, analyzing: p.analyzing}
+	p.searcher.Start(p.eng, l, p.thinking)
+}
+
+// level reads "level MPS BASE INC": moves per control (0 for the whole
+// game), base time in minutes or minutes:seconds, and increment in
+// seconds.
+func (p *Protocol) level(args []string) {
+	if len(args) != 3 {
+		p.errorf("bad level", append([]string{"level"}, args...))
+		return
+	}
+	mps, err1 := strconv.Atoi(args[0])
+	base, err2 := parseBase(args[1])
+	inc, err3 := strconv.ParseFloat(args[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil || mps < 0 {
+		p.errorf("bad level", append([]string{"level"}, args...))
+		return
+	}
+	p.movesPerControl, p.base = mps, base
+	p.inc = time.Duration(inc * float64(time.Second))
+	p.moveTime = 0
+	p.clock, p.otim = base, base
+}
+
+// parseBase reads "5" or "0:30".
+func parseBase(s string) (time.Duration, error) {
+	minutes, seconds, found := strings.Cut(s, ":")
+	m, err := strconv.Atoi(minutes)
+	if err != nil {
+		return 0, err
+	}
+	d := time.Duration(m) * time.Minute
+	if found {
+		sec, err := strconv.Atoi(seconds)
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(sec) * time.Second
+	}
+	return d, nil
+}
+
+func (p *Protocol) errorf(reason string, cmd []string) {
+	p.out.send("Error (%s): %s", reason, strings.Join(cmd, " "))
+}
+
+// announceResult tells the GUI when the game just ended on the board.
+func (p *Protocol) announceResult() bool {
+	score, over := p.eng.GameResult()
+	if !over {
+		return false
+	}
+	var reason string
+	switch {
+	case p.eng.IsCheckmate() && score == 1:
+		reason = "White mates"
+	case p.eng.IsCheckmate():
+		reason = "Black mates"
+	case p.eng.IsStalemate():
+		reason = "Stalemate"
+	case p.eng.IsFiftyMoveRule():
+		reason = "Draw by fifty move rule"
+	case p.eng.IsInsufficientMaterial():
+		reason = "Draw by insufficient material"
+	default:
+		reason = "Draw by repetition"
+	}
+	result := "1/2-1/2"
+	switch score {
+	case 1:
+		result = "1-0"
+	case 0:
+		result = "0-1"
+	}
+	p.out.send("%s {%s}", result, reason)
+	return true
+}
+
+// thought reports one search; a thought abandoned by a new command neither
+// plays its move nor reports anything more.
+type thought struct {
+	p         *Protocol
+	analyzing bool
+	discard   atomic.Bool
+}
+
+// Iteration prints thinking output: ply, score, time in centiseconds,
+// nodes and the principal variation.
+func (t *thought) Iteration(i search.Info) {
+	if t.discard.Load() || i.Bound != socrates.BoundExact || i.MultiPV != 1 || !(t.analyzing || t.p.post.Load()) {
+		return
+	}
+	t.p.out.send("%d %d %d %d %s", i.Depth, cecpScore(i.Score), i.Time.Milliseconds()/10, i.Nodes, socrates.PVString(i.PV))
+}
+
+func (t *thought) CurrMove(int, socrates.SimpleMove, int) {}
+func (t *thought) BookMove(socrates.SimpleMove)           {}
+
+// BestMove plays the engine's move; it runs before any new command is
+// handled, since those stop the search first.
+func (t *thought) BestMove(res search.Result) {
+	m, ok := res.Move()
+	if t.discard.Load() || t.analyzing || !ok {
+		return
+	}
+	t.p.eng.MakeMove(m.From, m.To, m.Promo)
+	t.p.out.send("move %s", m.String())
+	t.p.announceResult()
+}
+
+// cecpScore converts a search score into centipawns, with mates shown as
+// 100000 + N for mate in N moves.
+func cecpScore(score int) int {
+	moves, ok := socrates.MateIn(score)
+	switch {
+	case !ok:
+		return score
+	case score > 0:
+		return mateScore + moves
+	}
+	return -mateScore + moves
+}
+
+// centiseconds reads the clock value of time and otim.
+func centiseconds(args []string) time.Duration {
+	n, _ := strconv.Atoi(first(args))
+	return time.Duration(n) * 10 * time.Millisecond
+}
+
+func first(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
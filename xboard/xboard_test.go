@@ -0,0 +1,179 @@
+package xboard
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesb/mchess/internal/protocoltest"
+)
+
+// conversation drives a Protocol the way XBoard does.
+type conversation struct {
+	*protocoltest.Conversation
+	t *testing.T
+}
+
+func converse(t *testing.T) *conversation {
+	t.Helper()
+	run := func(in io.Reader, out io.Writer) error { return New(in, out).Run() }
+	return &conversation{protocoltest.Converse(t, run), t}
+}
+
+// sync waits until the engine has handled every command sent so far.
+func (c *conversation) sync(n int) []string {
+	c.t.Helper()
+	c.Send(fmt.Sprintf("ping %d", n))
+	_, before := c.Expect(fmt.Sprintf("pong %d", n))
+	return before
+}
+
+// handshake opens protocol version 2 and waits for the features.
+func (c *conversation) handshake() {
+	c.t.Helper()
+	c.Send("xboard", "protover 2")
+	c.Expect("feature done=1")
+}
+
+func TestFeatures(t *testing.T) {
+	c := converse(t)
+	c.Send("xboard", "protover 2")
+	line, _ := c.Expect("feature ping=1")
+	for _, f := range []string{"setboard=1", "usermove=1", "analyze=1", "myname="} {
+		if !strings.Contains(line, f) {
+			t.Errorf("%q lacks %s", line, f)
+		}
+	}
+	c.Expect("feature done=1")
+	c.Quit()
+}
+
+func TestEngineAnswersMoves(t *testing.T) {
+	c := converse(t)
+	c.handshake()
+	c.Send("new", "post", "sd 3")
+	c.Send("setboard r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	c.Send("usermove f1c4")
+	move, thinking := c.Expect("move ")
+	if len(thinking) == 0 || !strings.HasPrefix(thinking[len(thinking)-1], "3 ") {
+		t.Fatalf("no thinking output to ply 3 before %q: %q", move, thinking)
+	}
+	if from := strings.Fields(move)[1]; from[1] != '7' && from[1] != '8' {
+		t.Fatalf("%q is not a black move", move)
+	}
+
+	// In force mode the engine only follows; go makes it play the side to move.
+	c.Send("force", "usermove d2d3")
+	if lines := c.sync(1); len(lines) != 0 {
+		t.Fatalf("force mode answered %q", lines)
+	}
+	c.Send("go")
+	move, _ = c.Expect("move ")
+	if from := strings.Fields(move)[1]; from[1] != '7' && from[1] != '8' {
+		t.Fatalf("%q is not a black move", move)
+	}
+	c.Quit()
+}
+
+func TestErrorsAndResults(t *testing.T) {
+	c := converse(t)
+	c.handshake()
+	c.Send("new", "force")
+	tests := []struct{ cmd, reply string }{
+		{"usermove e2e5", "Illegal move: e2e5"},
+		{"setboard 8/8/8 w - - 0 1", "tellusererror Illegal position"},
+		{"frobnicate", "Error (unknown command): frobnicate"},
+		{"variant crazyhouse", "Error (unsupported variant): crazyhouse"},
+		{"level 40 five 0", "Error (bad level)"},
+	}
+	for _, tt := range tests {
+		c.Send(tt.cmd)
+		if lines := c.sync(1); len(lines) != 1 || !strings.HasPrefix(lines[0], tt.reply) {
+			t.Errorf("%s: got %q, want %q", tt.cmd, lines, tt.reply)
+		}
+	}
+
+	// The engine mates and says so.
+	c.Send("setboard 7k/5Q2/6K1/8/8/8/8/8 w - - 0 1", "sd 2", "go")
+	if move, _ := c.Expect("move "); move != "move f7f8" {
+		t.Fatalf("got %q, want the mate", move)
+	}
+	if line, _ := c.Expect("1-0"); line != "1-0 {White mates}" {
+		t.Fatalf("got %q", line)
+	}
+
+	// So does a user move ending the game.
+	c.Send("setboard 7k/5Q2/6K1/8/8/8/8/8 w - - 0 1", "force", "usermove f7e6")
+	if line, _ := c.Expect("1/2-1/2"); line != "1/2-1/2 {Stalemate}" {
+		t.Fatalf("got %q", line)
+	}
+	c.Quit()
+}
+
+func TestUndoAndRemove(t *testing.T) {
+	c := converse(t)
+	c.handshake()
+	c.Send("new", "force")
+	c.Send("usermove e2e4", "usermove e7e5", "remove", "remove")
+	// Back at the start: a black move would be illegal.
+	c.Send("usermove e7e5")
+	if lines := c.sync(1); len(lines) != 1 || lines[0] != "Illegal move: e7e5" {
+		t.Fatalf("remove did not take back both moves: %q", lines)
+	}
+	c.Send("usermove e2e4", "undo", "usermove d2d4")
+	if lines := c.sync(2); len(lines) != 0 {
+		t.Fatalf("undo did not take back the move: %q", lines)
+	}
+	c.Quit()
+}
+
+func TestAnalyze(t *testing.T) {
+	c := converse(t)
+	c.handshake()
+	c.Send("new", "force", "analyze")
+	c.Expect("1 ")
+	c.Send("usermove e2e4")
+	// The analysis restarts on the new position, and never plays a move.
+	line, _ := c.Expect("1 ")
+	if pv := strings.Fields(line)[4]; pv[1] != '7' && pv[1] != '8' {
+		t.Fatalf("%q does not analyze for Black", line)
+	}
+	c.Send("exit")
+	if lines := c.sync(2); strings.Contains(strings.Join(lines, "\n"), "move ") {
+		t.Fatalf("analysis played a move: %q", lines)
+	}
+	c.Quit()
+}
+
+func TestLevel(t *testing.T) {
+	p := New(strings.NewReader(""), io.Discard)
+	p.handle(strings.Fields("level 40 0:30 2"))
+	if p.movesPerControl != 40 || p.base != 30*time.Second || p.inc != 2*time.Second {
+		t.Fatalf("level 40 0:30 2 gave %d %v %v", p.movesPerControl, p.base, p.inc)
+	}
+	p.handle(strings.Fields("level 0 5 0"))
+	if p.movesPerControl != 0 || p.base != 5*time.Minute {
+		t.Fatalf("level 0 5 0 gave %d %v", p.movesPerControl, p.base)
+	}
+	p.handle(strings.Fields("time 1234"))
+	if p.clock != 12340*time.Millisecond {
+		t.Fatalf("time 1234 gave %v", p.clock)
+	}
+}
+
+func TestCECPScore(t *testing.T) {
+	tests := []struct{ score, want int }{
+		{35, 35},
+		{-120, -120},
+		{30000 - 1, 100001},
+		{30000 - 3, 100002},
+		{-(30000 - 2), -100001},
+	}
+	for _, tt := range tests {
+		if got := cecpScore(tt.score); got != tt.want {
+			t.Errorf("cecpScore(%d) = %d, want %d", tt.score, got, tt.want)
+		}
+	}
+}